@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/node_exporter/log"
+)
+
+var accessLog = flag.Bool("web.access-log", false, "Log each request to the metrics endpoint with the client address, user, path, status, duration and bytes sent, at the info level.")
+
+// accessLogWriter counts the bytes and keeps the status of a response.
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// CloseNotify passes on the notifications of the wrapped writer, which
+// scrapes cancel their collectors on.
+func (w *accessLogWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
+
+// accessLogHandler logs the requests served by the wrapped handler once
+// they are done. Wrapping the compression and auth handlers, it logs the
+// bytes sent on the wire and the rejected requests.
+type accessLogHandler struct {
+	handler http.Handler
+}
+
+func (h accessLogHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	begin := time.Now()
+	aw := &accessLogWriter{ResponseWriter: w}
+	h.handler.ServeHTTP(aw, r)
+	if aw.status == 0 {
+		aw.status = http.StatusOK
+	}
+	user, _, _ := r.BasicAuth()
+	log.With(
+		"client", clientIP(r),
+		"user", user,
+		"method", r.Method,
+		"path", r.URL.RequestURI(),
+		"status", aw.status,
+		"duration_seconds", time.Since(begin).Seconds(),
+		"bytes", aw.bytes,
+	).Infof("Request served")
+}
+
+// clientIP returns the address of the client without the port, or the
+// whole remote address if it has none, as on Unix sockets.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/node_exporter/log"
+)
+
+func TestAccessLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	h := accessLogHandler{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("collect[]") == "" {
+			http.Error(w, "no", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("node_load1 0.5\n"))
+	})}
+
+	for _, c := range []struct {
+		path, want string
+	}{
+		{"/metrics?collect[]=loadavg", `path="/metrics?collect[]=loadavg" status=200 `},
+		{"/metrics", `path=/metrics status=400 `},
+	} {
+		buf.Reset()
+		r, _ := http.NewRequest("GET", c.path, nil)
+		r.RemoteAddr = "192.0.2.1:51234"
+		r.SetBasicAuth("prometheus", "secret")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		line := buf.String()
+		for _, want := range []string{
+			` msg="Request served" client=192.0.2.1 user=prometheus method=GET `,
+			c.want,
+		} {
+			if !strings.Contains(line, want) {
+				t.Errorf("%s: want %q in %q", c.path, want, line)
+			}
+		}
+	}
+	if !strings.HasSuffix(buf.String(), " bytes=3\n") {
+		t.Errorf("want the bytes of the error sent, got %q", buf.String())
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/node_exporter/log"
+)
+
+const adminAPIPrefix = "/api/v1/collectors"
+
+var enableAdminAPI = flag.Bool("web.enable-admin-api", false, "Serve an API under "+adminAPIPrefix+" to turn collectors on and off at runtime. Requires basic auth or client certificates.")
+
+// checkAdminAPI returns an error if the admin API is served without basic
+// auth or client certificates.
+func checkAdminAPI() error {
+	if *authUser == "" && *authFile == "" && *tlsClientCAFile == "" {
+		return fmt.Errorf("The admin API requires basic auth or client certificates to be set up")
+	}
+	return nil
+}
+
+// setDisabled turns the named collector off or back on.
+func (n *NodeCollector) setDisabled(name string, disabled bool) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.node.Collectors()[name]; !ok {
+		return fmt.Errorf("collector %q not enabled", name)
+	}
+	if disabled {
+		n.disabled[name] = true
+	} else {
+		delete(n.disabled, name)
+	}
+	return nil
+}
+
+// states returns whether each of the collectors is on.
+func (n *NodeCollector) states() map[string]bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	states := map[string]bool{}
+	for _, name := range n.node.Names() {
+		states[name] = !n.disabled[name]
+	}
+	return states
+}
+
+// adminHandler serves GET /api/v1/collectors, listing the collectors and
+// whether they are on, and POST /api/v1/collectors/<name>/enable|disable.
+type adminHandler struct {
+	collectors *NodeCollector
+}
+
+func (h adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, adminAPIPrefix), "/")
+	if path == "" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(h.collectors.states()); err != nil {
+			log.Errorf("Couldn't encode collector states: %s", err)
+		}
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || (parts[1] != "enable" && parts[1] != "disable") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name, disable := parts[0], parts[1] == "disable"
+	if err := h.collectors.setDisabled(name, disable); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	log.Infof("Collector %s %sd through the admin API by %s", name, parts[1], r.RemoteAddr)
+	w.Write([]byte(fmt.Sprintf("Collector %s %sd.\n", name, parts[1])))
+}
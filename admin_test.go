@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/node_exporter/collector"
+)
+
+func TestAdminHandler(t *testing.T) {
+	n := &NodeCollector{
+		node:     collector.NewNodeCollectorWith(map[string]collector.Collector{"stat": nil, "meminfo": nil}, nil),
+		disabled: map[string]bool{},
+	}
+	h := adminHandler{collectors: n}
+
+	for _, c := range []struct {
+		method, path string
+		code         int
+		names        string
+	}{
+		{"POST", "/api/v1/collectors/stat/disable", http.StatusOK, "meminfo"},
+		{"GET", "/api/v1/collectors/stat/enable", http.StatusMethodNotAllowed, "meminfo"},
+		{"POST", "/api/v1/collectors/hwmon/disable", http.StatusNotFound, "meminfo"},
+		{"POST", "/api/v1/collectors/stat/restart", http.StatusNotFound, "meminfo"},
+		{"POST", "/api/v1/collectors/stat/enable", http.StatusOK, "meminfo,stat"},
+	} {
+		r, _ := http.NewRequest(c.method, c.path, nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != c.code {
+			t.Errorf("%s %s: want status %d, got %d", c.method, c.path, c.code, w.Code)
+		}
+		if got := strings.Join(n.names(), ","); got != c.names {
+			t.Errorf("%s %s: want collectors %s, got %s", c.method, c.path, c.names, got)
+		}
+	}
+
+	r, _ := http.NewRequest("GET", "/api/v1/collectors", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if want, got := `{"meminfo":true,"stat":true}`+"\n", w.Body.String(); want != got {
+		t.Errorf("want states %s, got %s", want, got)
+	}
+}
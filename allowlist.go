@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/node_exporter/log"
+)
+
+var allowedNetworks = flag.String("web.allowed-networks", "", "Comma-separated list of CIDRs, like 10.0.0.0/8, of the clients allowed to connect. Others get 403 Forbidden. Empty allows all clients.")
+
+// ipAllowlistHandler rejects requests from TCP clients outside the allowed
+// networks. Clients of the Unix socket, which has no client address, are
+// let through, as the socket permissions already control them.
+type ipAllowlistHandler struct {
+	handler  http.Handler
+	networks []*net.IPNet
+}
+
+// newIPAllowlistHandler parses the comma-separated CIDRs. Addresses without
+// a prefix length allow that single address.
+func newIPAllowlistHandler(handler http.Handler, cidrs string) (*ipAllowlistHandler, error) {
+	h := &ipAllowlistHandler{handler: handler}
+	for _, cidr := range strings.Split(cidrs, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %s", cidr, err)
+		}
+		h.networks = append(h.networks, network)
+	}
+	if len(h.networks) == 0 {
+		return nil, fmt.Errorf("no networks in %q", cidrs)
+	}
+	return h, nil
+}
+
+func (h *ipAllowlistHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.allows(r) {
+		log.With("client", clientIP(r), "path", r.URL.Path).Debugf("Client not allowed")
+		http.Error(w, "Client not allowed", http.StatusForbidden)
+		return
+	}
+	h.handler.ServeHTTP(w, r)
+}
+
+func (h *ipAllowlistHandler) allows(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// Unix socket clients.
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range h.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
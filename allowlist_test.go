@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPAllowlistHandler(t *testing.T) {
+	h, err := newIPAllowlistHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), "10.0.0.0/8, 192.0.2.7,2001:db8::/32")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		remoteAddr string
+		code       int
+	}{
+		{"10.1.2.3:40000", http.StatusOK},
+		{"192.0.2.7:40000", http.StatusOK},
+		{"192.0.2.8:40000", http.StatusForbidden},
+		{"[2001:db8::1]:40000", http.StatusOK},
+		{"[2001:db9::1]:40000", http.StatusForbidden},
+		{"@", http.StatusOK},
+	} {
+		r, _ := http.NewRequest("GET", "/metrics", nil)
+		r.RemoteAddr = c.remoteAddr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != c.code {
+			t.Errorf("%s: want status %d, got %d", c.remoteAddr, c.code, w.Code)
+		}
+	}
+
+	for _, cidrs := range []string{"10.0.0.0/33", "localhost", " , "} {
+		if _, err := newIPAllowlistHandler(nil, cidrs); err == nil {
+			t.Errorf("want error for %q", cidrs)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type basicAuthHandler struct {
+	handler  http.HandlerFunc
+	user     string
+	password string
+	// users maps user names to bcrypt hashes of their passwords.
+	users map[string][]byte
+}
+
+func (h *basicAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, password, ok := r.BasicAuth()
+	if !ok || !h.authenticate(user, password) {
+		w.Header().Set("WWW-Authenticate", "Basic realm=\"metrics\"")
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+	h.handler(w, r)
+	return
+}
+
+// basicAuth returns a function adding the basic auth configured by the
+// -auth.* flags to handlers.
+func basicAuth() (func(http.Handler) http.Handler, error) {
+	if *authUser == "" && *authPass == "" && *authFile == "" {
+		return func(h http.Handler) http.Handler { return h }, nil
+	}
+	if (*authUser == "") != (*authPass == "") {
+		return nil, fmt.Errorf("You need to specify -auth.user and -auth.pass to enable basic auth")
+	}
+	var users map[string][]byte
+	if *authFile != "" {
+		var err error
+		users, err = readAuthFile(*authFile)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't read auth file: %s", err)
+		}
+	}
+	return func(h http.Handler) http.Handler {
+		return &basicAuthHandler{
+			handler:  h.ServeHTTP,
+			user:     *authUser,
+			password: *authPass,
+			users:    users,
+		}
+	}, nil
+}
+
+func (h *basicAuthHandler) authenticate(user, password string) bool {
+	if h.user != "" && user == h.user && password == h.password {
+		return true
+	}
+	hash, ok := h.users[user]
+	if !ok {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+func readAuthFile(path string) (map[string][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseAuthFile(file)
+}
+
+// parseAuthFile parses htpasswd style user:hash lines. Empty lines and lines
+// starting with # are ignored, only bcrypt hashes are accepted.
+func parseAuthFile(r io.Reader) (map[string][]byte, error) {
+	users := map[string][]byte{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("line %d: expected user:hash", n)
+		}
+		if _, err := bcrypt.Cost([]byte(parts[1])); err != nil {
+			return nil, fmt.Errorf("line %d: invalid bcrypt hash for user %s: %s", n, parts[0], err)
+		}
+		users[parts[0]] = []byte(parts[1])
+	}
+	return users, scanner.Err()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Hash of "secret" with cost 4.
+const testAuthFile = `# prometheus scrapers
+prometheus:$2a$04$wU.caVmPc.w2jM2OTBSZ/OQuuvmdVC3229hKvWaViA25ZVlrZL8i.
+`
+
+func TestParseAuthFile(t *testing.T) {
+	users, err := parseAuthFile(strings.NewReader(testAuthFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1, len(users); want != got {
+		t.Fatalf("want %d users, got %d", want, got)
+	}
+
+	if _, err := parseAuthFile(strings.NewReader("prometheus:secret\n")); err == nil {
+		t.Error("expected error for plaintext password")
+	}
+}
+
+func TestBasicAuthHandler(t *testing.T) {
+	users, err := parseAuthFile(strings.NewReader(testAuthFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &basicAuthHandler{
+		handler: func(w http.ResponseWriter, r *http.Request) {},
+		users:   users,
+	}
+
+	for _, c := range []struct {
+		user, password string
+		code           int
+	}{
+		{"prometheus", "secret", http.StatusOK},
+		{"prometheus", "wrong", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	} {
+		r, _ := http.NewRequest("GET", "/metrics", nil)
+		r.SetBasicAuth(c.user, c.password)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != c.code {
+			t.Errorf("%s/%s: want status %d, got %d", c.user, c.password, c.code, w.Code)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
+	"golang.org/x/net/context"
+)
+
+var backgroundInterval = flag.Duration("collectors.background-interval", 0, "If set, run the collectors on this interval in the background and serve scrapes from the result of their last run, so that no collector runs during a scrape. 0 runs the collectors on every scrape.")
+
+func newBackgroundUpdatedDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(collector.Namespace, "scrape", "collector_last_update_timestamp_seconds"),
+		"node_exporter: Time the last background run of a collector finished, in seconds since the epoch.",
+		[]string{"collector"}, nil,
+	)
+}
+
+// backgroundCollector runs the wrapped collector on an interval and replays
+// the metrics and error of its last run on Update.
+type backgroundCollector struct {
+	name      string
+	collector collector.Collector
+	timeout   time.Duration
+	updated   *prometheus.Desc
+
+	stop  chan struct{}
+	ready chan struct{} // closed after the first run
+
+	mu      sync.Mutex
+	metrics []prometheus.Metric
+	err     error
+	last    time.Time
+}
+
+// newBackgroundCollector starts running c every interval. A run is bounded by
+// timeout, by the interval if it is 0.
+func newBackgroundCollector(name string, c collector.Collector, interval, timeout time.Duration) *backgroundCollector {
+	if timeout <= 0 {
+		timeout = interval
+	}
+	b := &backgroundCollector{
+		name:      name,
+		collector: c,
+		timeout:   timeout,
+		updated:   newBackgroundUpdatedDesc(),
+		stop:      make(chan struct{}),
+		ready:     make(chan struct{}),
+	}
+	go b.loop(interval)
+	return b
+}
+
+func (b *backgroundCollector) loop(interval time.Duration) {
+	// Closing stops a running update too, which holds the flags.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-b.stop
+		cancel()
+	}()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	b.run(ctx)
+	close(b.ready)
+	for {
+		select {
+		case <-ticker.C:
+			b.run(ctx)
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+func (b *backgroundCollector) run(ctx context.Context) {
+	var metrics []prometheus.Metric
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		close(done)
+	}()
+	flagsMu.RLock()
+	err := collector.Update(collector.WithScrapeCache(ctx), b.collector, ch, b.timeout)
+	flagsMu.RUnlock()
+	close(ch)
+	<-done
+
+	b.mu.Lock()
+	b.metrics, b.err, b.last = metrics, err, time.Now()
+	b.mu.Unlock()
+}
+
+// Update waits for the first run to finish, or ctx to be done, and replays
+// the last run with its finishing time.
+func (b *backgroundCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	select {
+	case <-b.ready:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	b.mu.Lock()
+	metrics, err, last := b.metrics, b.err, b.last
+	b.mu.Unlock()
+	for _, m := range metrics {
+		ch <- m
+	}
+	ch <- prometheus.MustNewConstMetric(b.updated, prometheus.GaugeValue, float64(last.UnixNano())/1e9, b.name)
+	return err
+}
+
+// Close stops the background runs and closes the wrapped collector if it
+// holds resources.
+func (b *backgroundCollector) Close() error {
+	close(b.stop)
+	if closer, ok := b.collector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
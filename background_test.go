@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+type signallingCollector struct {
+	metric  prometheus.Gauge
+	err     error
+	updates chan struct{}
+}
+
+func (c *signallingCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	ch <- c.metric
+	c.updates <- struct{}{}
+	return c.err
+}
+
+func TestBackgroundCollector(t *testing.T) {
+	signalling := &signallingCollector{
+		metric:  prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"}),
+		updates: make(chan struct{}, 10),
+	}
+	c := newBackgroundCollector("test", signalling, time.Hour, 0)
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		ch := make(chan prometheus.Metric, 2)
+		if err := c.Update(context.Background(), ch); err != nil {
+			t.Fatal(err)
+		}
+		if len(ch) != 2 {
+			t.Fatalf("want 2 metrics, got %d", len(ch))
+		}
+		if m := <-ch; m != signalling.metric {
+			t.Errorf("want collector metric first, got %s", m.Desc())
+		}
+		if want, got := newBackgroundUpdatedDesc().String(), (<-ch).Desc().String(); want != got {
+			t.Errorf("want %s, got %s", want, got)
+		}
+	}
+	if want, got := 1, len(signalling.updates); want != got {
+		t.Errorf("want %d updates, got %d", want, got)
+	}
+}
+
+func TestBackgroundCollectorError(t *testing.T) {
+	signalling := &signallingCollector{
+		metric:  prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"}),
+		err:     errors.New("failed"),
+		updates: make(chan struct{}, 10),
+	}
+	c := newBackgroundCollector("test", signalling, time.Hour, 0)
+	defer c.Close()
+
+	if err := c.Update(context.Background(), make(chan prometheus.Metric, 2)); err != signalling.err {
+		t.Errorf("want error %v, got %v", signalling.err, err)
+	}
+}
+
+type blockingCollector struct{}
+
+func (blockingCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestBackgroundCollectorFirstRun(t *testing.T) {
+	c := newBackgroundCollector("test", blockingCollector{}, time.Hour, time.Hour)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.Update(ctx, make(chan prometheus.Metric, 1)); err != context.DeadlineExceeded {
+		t.Errorf("want %v waiting for the first run, got %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
+	"golang.org/x/net/context"
+)
+
+var cacheTTL = flag.Duration("collector.cache-ttl", 0, "Serve scrapes arriving within this duration of the last successful collection from its result, e.g. for HA pairs of Prometheus servers. 0 disables the cache.")
+
+// cachingCollector replays the metrics of the last successful Update of the
+// wrapped collector while they are younger than ttl.
+type cachingCollector struct {
+	collector collector.Collector
+	ttl       time.Duration
+
+	mu      sync.Mutex
+	metrics []prometheus.Metric
+	updated time.Time
+}
+
+func newCachingCollector(c collector.Collector, ttl time.Duration) *cachingCollector {
+	return &cachingCollector{collector: c, ttl: ttl}
+}
+
+func (c *cachingCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	c.mu.Lock()
+	if time.Since(c.updated) < c.ttl {
+		metrics := c.metrics
+		c.mu.Unlock()
+		for _, m := range metrics {
+			ch <- m
+		}
+		return nil
+	}
+	c.mu.Unlock()
+
+	var metrics []prometheus.Metric
+	record := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for m := range record {
+			metrics = append(metrics, m)
+			ch <- m
+		}
+		close(done)
+	}()
+	err := c.collector.Update(ctx, record)
+	close(record)
+	<-done
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.metrics, c.updated = metrics, time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+// Close closes the wrapped collector if it holds resources.
+func (c *cachingCollector) Close() error {
+	if closer, ok := c.collector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+type countingCollector struct {
+	metric  prometheus.Gauge
+	updates int
+}
+
+func (c *countingCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	c.updates++
+	ch <- c.metric
+	return nil
+}
+
+func TestCachingCollector(t *testing.T) {
+	counting := &countingCollector{metric: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"})}
+	c := newCachingCollector(counting, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		ch := make(chan prometheus.Metric, 1)
+		if err := c.Update(context.Background(), ch); err != nil {
+			t.Fatal(err)
+		}
+		if len(ch) != 1 {
+			t.Errorf("want 1 metric, got %d", len(ch))
+		}
+	}
+	if want, got := 1, counting.updates; want != got {
+		t.Errorf("want %d updates, got %d", want, got)
+	}
+
+	c.ttl = 0
+	c.Update(context.Background(), make(chan prometheus.Metric, 1))
+	if want, got := 2, counting.updates; want != got {
+		t.Errorf("want %d updates after expiry, got %d", want, got)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// runCheckConfig runs checkConfig for the check-config subcommand, writing
+// the problems found to errOut, and returns the exit status.
+func runCheckConfig(out, errOut io.Writer) int {
+	errs := checkConfig()
+	for _, err := range errs {
+		fmt.Fprintln(errOut, err)
+	}
+	if len(errs) > 0 {
+		fmt.Fprintf(errOut, "Found %d problems\n", len(errs))
+		return 1
+	}
+	fmt.Fprintln(out, "Config OK")
+	return 0
+}
+
+// checkConfig goes through the setup of the exporter without serving or
+// collecting: it reads the config file, sets up the collectors, which checks
+// their names and options and compiles their regexes, and checks the web,
+// auth and push flags, reading the certificates and credentials they name.
+// The collectors are neither run for -collectors.check-names nor started in
+// the background. It returns all problems found.
+func checkConfig() []error {
+	config, err := applyConfig(*configFile, false)
+	if err != nil {
+		return []error{fmt.Errorf("Couldn't read config %s: %s", *configFile, err)}
+	}
+
+	var errs []error
+	if collectors, _, err := newCollectors(config); err != nil {
+		errs = append(errs, fmt.Errorf("Couldn't load collectors: %s", err))
+	} else {
+		closeCollectors(collectors)
+	}
+
+	if _, err := basicAuth(); err != nil {
+		errs = append(errs, err)
+	}
+	if *enableAdminAPI {
+		if err := checkAdminAPI(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if _, err := serverTLSConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	if *allowedNetworks != "" {
+		if _, err := newIPAllowlistHandler(nil, *allowedNetworks); err != nil {
+			errs = append(errs, fmt.Errorf("Couldn't parse -web.allowed-networks: %s", err))
+		}
+	}
+
+	for _, address := range listenAddresses.addresses {
+		if _, err := net.ResolveTCPAddr("tcp", address); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid -web.listen-address %q: %s", address, err))
+		}
+	}
+	if *listenSocket != "" {
+		if _, err := strconv.ParseUint(*listenSocketMode, 8, 32); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid -web.listen-socket-mode %q: %s", *listenSocketMode, err))
+		}
+	}
+
+	pushing := false
+	if *pushGateway != "" {
+		if _, err := newPusher(*pushGateway, *pushJob, *pushInstance); err != nil {
+			errs = append(errs, fmt.Errorf("Couldn't set up Pushgateway push: %s", err))
+		}
+		pushing = true
+	}
+	if *remoteWriteURL != "" {
+		if _, err := newRemoteWriter(nil); err != nil {
+			errs = append(errs, fmt.Errorf("Couldn't set up remote_write: %s", err))
+		}
+		pushing = true
+	}
+	if *graphiteAddress != "" {
+		pushing = true
+	}
+	if *influxDBURL != "" {
+		if _, err := newInfluxDBSender(*influxDBURL, *influxDBInterval); err != nil {
+			errs = append(errs, fmt.Errorf("Couldn't set up InfluxDB output: %s", err))
+		}
+		pushing = true
+	}
+	if *statsdAddress != "" {
+		if _, err := newStatsdSender(*statsdAddress, *statsdPrefix, *statsdLabelMapping, *statsdInterval); err != nil {
+			errs = append(errs, fmt.Errorf("Couldn't set up StatsD output: %s", err))
+		}
+		pushing = true
+	}
+	if len(listenAddresses.addresses) == 0 && *listenSocket == "" && !*systemdSocket && !pushing {
+		errs = append(errs, fmt.Errorf("Neither -web.listen-address, -web.listen-socket nor a push mode set"))
+	}
+	return errs
+}
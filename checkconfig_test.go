@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
+)
+
+func TestCheckConfig(t *testing.T) {
+	defer func(c, e, cert, n string) {
+		*configFile, *enabledCollectors, *tlsCertFile, *allowedNetworks = c, e, cert, n
+	}(*configFile, *enabledCollectors, *tlsCertFile, *allowedNetworks)
+	*configFile = ""
+
+	*enabledCollectors = "time,nosuch"
+	*tlsCertFile = "server.crt"
+	*allowedNetworks = "10.0.0.0/33"
+	var out, errOut bytes.Buffer
+	if status := runCheckConfig(&out, &errOut); status != 1 {
+		t.Errorf("want status 1, got %d", status)
+	}
+	for _, want := range []string{
+		"collector 'nosuch' not available",
+		"-web.tls-key-file",
+		"invalid network \"10.0.0.0/33\"",
+		"Found 3 problems\n",
+	} {
+		if !strings.Contains(errOut.String(), want) {
+			t.Errorf("want %q in %q", want, errOut.String())
+		}
+	}
+
+	*enabledCollectors = "time"
+	*tlsCertFile = ""
+	*allowedNetworks = "10.0.0.0/8"
+	out.Reset()
+	errOut.Reset()
+	if status := runCheckConfig(&out, &errOut); status != 0 {
+		t.Errorf("want status 0, got %d: %s", status, errOut.String())
+	}
+	if want, got := "Config OK\n", out.String(); want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestCheckConfigDoesNotUpdate(t *testing.T) {
+	defer func(c, e string, check bool, i time.Duration) {
+		*configFile, *enabledCollectors, *checkNames, *backgroundInterval = c, e, check, i
+		delete(collector.Factories, "counting")
+	}(*configFile, *enabledCollectors, *checkNames, *backgroundInterval)
+	counting := &countingCollector{metric: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"})}
+	collector.Factories["counting"] = func(collector.Config) (collector.Collector, error) {
+		return counting, nil
+	}
+	*configFile = ""
+	*enabledCollectors = "counting"
+	*checkNames = true
+	*backgroundInterval = time.Millisecond
+
+	if errs := checkConfig(); len(errs) != 0 {
+		t.Fatalf("want no problems, got %v", errs)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if counting.updates != 0 {
+		t.Errorf("want no updates, got %d", counting.updates)
+	}
+}
@@ -3,44 +3,141 @@
 package collector
 
 import (
-	"github.com/golang/glog"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	attributesFile = flag.String("collector.attributes.file", "", "JSON or YAML file with key/value pairs to export as labels of node_attributes.")
+
+	labelNameRE = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
 )
 
 type attributesCollector struct {
 	config Config
-	metric *prometheus.GaugeVec
+	file   string
+
+	mtx        sync.Mutex
+	attributes map[string]string
+	mtime      time.Time
 }
 
 func init() {
-	Factories["attributes"] = NewAttributesCollector
+	registerCollector("attributes", defaultEnabled, NewAttributesCollector)
 }
 
 // Takes a config struct and prometheus registry and returns a new Collector exposing
-// labels from the config.
+// labels from the config and from the file given by --collector.attributes.file or
+// the attributes_file config entry. The file is read again whenever it changes.
 func NewAttributesCollector(config Config) (Collector, error) {
-	labelNames := []string{}
-	for l := range config.Attributes {
-		labelNames = append(labelNames, l)
+	c := &attributesCollector{
+		config: config,
+		file:   *attributesFile,
+	}
+	if config.Config["attributes_file"] != "" {
+		c.file = config.Config["attributes_file"]
 	}
+	if _, err := c.getAttributes(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
 
-	return &attributesCollector{
-		config: config,
-		metric: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Namespace: Namespace,
-				Name:      "attributes",
-				Help:      "The node_exporter attributes.",
-			},
-			labelNames,
-		),
-	}, nil
+func (c *attributesCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	attributes, err := c.getAttributes()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = attributes[name]
+	}
+
+	log.Debugf("Set node_attributes{%v}: 1", attributes)
+	// The label names change with the file, so the metric is built anew.
+	desc := prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "", "attributes"),
+		"The node_exporter attributes.",
+		names, nil,
+	)
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, values...)
+	return nil
 }
 
-func (c *attributesCollector) Update(ch chan<- prometheus.Metric) (err error) {
-	glog.V(1).Info("Set node_attributes{%v}: 1", c.config.Attributes)
-	c.metric.Reset()
-	c.metric.With(c.config.Attributes).Set(1)
-	c.metric.Collect(ch)
-	return err
+// getAttributes returns the attributes from the config merged with those
+// from the attributes file, reading the latter again if it was modified.
+func (c *attributesCollector) getAttributes() (map[string]string, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.file == "" {
+		return c.config.Attributes, nil
+	}
+	info, err := os.Stat(c.file)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read attributes: %s", err)
+	}
+	if c.attributes != nil && info.ModTime().Equal(c.mtime) {
+		return c.attributes, nil
+	}
+
+	data, err := ioutil.ReadFile(c.file)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read attributes: %s", err)
+	}
+	fileAttributes, err := parseAttributes(data)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse attributes in %s: %s", c.file, err)
+	}
+	attributes := map[string]string{}
+	for k, v := range c.config.Attributes {
+		attributes[k] = v
+	}
+	for k, v := range fileAttributes {
+		attributes[k] = v
+	}
+	log.Infof("Read %d attributes from %s", len(fileAttributes), c.file)
+	c.attributes, c.mtime = attributes, info.ModTime()
+	return attributes, nil
+}
+
+// parseAttributes parses a flat map of attributes. As YAML is a superset of
+// JSON, this handles both formats.
+func parseAttributes(data []byte) (map[string]string, error) {
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	attributes := map[string]string{}
+	for k, v := range raw {
+		if !labelNameRE.MatchString(k) {
+			return nil, fmt.Errorf("invalid attribute name %q", k)
+		}
+		switch v.(type) {
+		case nil:
+			// An empty label value is the same as no label in Prometheus.
+			attributes[k] = ""
+			continue
+		case map[interface{}]interface{}, []interface{}:
+			return nil, fmt.Errorf("attribute %s is not a scalar", k)
+		}
+		attributes[k] = fmt.Sprint(v)
+	}
+	return attributes, nil
 }
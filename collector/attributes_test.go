@@ -0,0 +1,38 @@
+package collector
+
+import "testing"
+
+func TestAttributes(t *testing.T) {
+	for _, data := range []string{
+		`{"role": "web_server", "rack": 12}`,
+		"role: web_server\nrack: 12\n",
+	} {
+		attributes, err := parseAttributes([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, got := "web_server", attributes["role"]; want != got {
+			t.Errorf("want role %s, got %s", want, got)
+		}
+		if want, got := "12", attributes["rack"]; want != got {
+			t.Errorf("want rack %s, got %s", want, got)
+		}
+	}
+
+	for _, data := range []string{`{"role": null}`, "role:\n", "role: ~\n"} {
+		attributes, err := parseAttributes([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := attributes["role"]; !ok || got != "" {
+			t.Errorf("want empty role for %q, got %q", data, got)
+		}
+	}
+
+	if _, err := parseAttributes([]byte("data-center: dc1\n")); err == nil {
+		t.Error("want error for invalid label name")
+	}
+	if _, err := parseAttributes([]byte("rack:\n  row: 3\n")); err == nil {
+		t.Error("want error for nested attributes")
+	}
+}
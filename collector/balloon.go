@@ -0,0 +1,114 @@
+// +build !noballoon
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const (
+	balloonSubsystem = "balloon"
+)
+
+type balloonCollector struct {
+	config   Config
+	size     prometheus.Gauge
+	counters map[string]prometheus.Counter
+}
+
+func init() {
+	registerCollector("balloon", defaultDisabled, NewBalloonCollector)
+}
+
+// NewBalloonCollector returns a new Collector exposing how much memory the
+// host reclaimed from this guest through the virtio balloon. The balloon
+// target is only known to the host, the guest sees the actual balloon size:
+// the driver reads the target from the device but doesn't show it to user
+// space. The memory statistics the driver reports to the host, like swap-ins
+// and major faults, free and available memory and the caches, come from the
+// guest's /proc/vmstat and /proc/meminfo rather than from the balloon, so
+// they aren't exported here: the meminfo collector exports the latter.
+func NewBalloonCollector(config Config) (Collector, error) {
+	if _, err := os.Stat(sysFilePath("bus/virtio/drivers/virtio_balloon")); err != nil {
+		return nil, NotApplicable("no virtio balloon driver loaded: %s", err)
+	}
+
+	counters := map[string]prometheus.Counter{}
+	for key, help := range map[string]string{
+		"balloon_inflate": "Pages taken from the guest by inflating the balloon.",
+		"balloon_deflate": "Pages given back to the guest by deflating the balloon.",
+		"balloon_migrate": "Balloon pages migrated by memory compaction.",
+	} {
+		counters[key] = prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: balloonSubsystem,
+			Name:      strings.TrimPrefix(key, "balloon_") + "_pages",
+			Help:      help,
+		})
+	}
+
+	return &balloonCollector{
+		config: config,
+		size: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: balloonSubsystem,
+			Name:      "size_bytes",
+			Help:      "Memory currently held by the balloon, i.e. unavailable to the guest, in bytes.",
+		}),
+		counters: counters,
+	}, nil
+}
+
+func (c *balloonCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	data, err := readProcFile(ctx, "vmstat")
+	if err != nil {
+		return err
+	}
+	stats, err := parseBalloonVmstat(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("couldn't get balloon stats: %s", err)
+	}
+	for key, counter := range c.counters {
+		counter.Set(stats[key])
+		counter.Collect(ch)
+	}
+
+	// Older kernels lack nr_balloon_pages, derive it from the events.
+	pages, ok := stats["nr_balloon_pages"]
+	if !ok {
+		pages = stats["balloon_inflate"] - stats["balloon_deflate"]
+	}
+	c.size.Set(pages * float64(os.Getpagesize()))
+	c.size.Collect(ch)
+	return nil
+}
+
+// parseBalloonVmstat returns the balloon related lines of /proc/vmstat.
+func parseBalloonVmstat(r io.Reader) (map[string]float64, error) {
+	stats := map[string]float64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 || !strings.Contains(parts[0], "balloon") {
+			continue
+		}
+		v, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %s in vmstat: %s", parts[1], err)
+		}
+		stats[parts[0]] = v
+	}
+	if _, ok := stats["balloon_inflate"]; !ok {
+		return nil, fmt.Errorf("kernel lacks balloon statistics, CONFIG_MEMORY_BALLOON not set")
+	}
+	return stats, scanner.Err()
+}
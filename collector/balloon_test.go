@@ -0,0 +1,34 @@
+package collector
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBalloonVmstat(t *testing.T) {
+	file, err := os.Open("fixtures/proc/vmstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	stats, err := parseBalloonVmstat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 262144.0, stats["nr_balloon_pages"]; want != got {
+		t.Errorf("want balloon pages %f, got %f", want, got)
+	}
+	if want, got := 65536.0, stats["balloon_deflate"]; want != got {
+		t.Errorf("want balloon deflations %f, got %f", want, got)
+	}
+	if _, ok := stats["pgpgin"]; ok {
+		t.Error("want unrelated vmstat lines to be skipped")
+	}
+
+	if _, err := parseBalloonVmstat(strings.NewReader("pgpgin 1\n")); err == nil {
+		t.Error("want error without balloon statistics")
+	}
+}
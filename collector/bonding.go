@@ -10,55 +10,39 @@ import (
 	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
-)
-
-const (
-	sysfsNet = "/sys/class/net"
+	"golang.org/x/net/context"
 )
 
 type bondingCollector struct {
-	slaves, active *prometheus.GaugeVec
+	slaves, active typedDesc
 }
 
 func init() {
-	Factories["bonding"] = NewBondingCollector
+	registerCollector("bonding", defaultDisabled, NewBondingCollector)
 }
 
 // NewBondingCollector returns a newly allocated bondingCollector.
 // It exposes the number of configured and active slave of linux bonding interfaces.
 func NewBondingCollector(config Config) (Collector, error) {
+	if _, err := os.Stat(sysFilePath("class/net/bonding_masters")); err != nil {
+		return nil, NotApplicable("bonding driver not loaded: %s", err)
+	}
 	return &bondingCollector{
-		slaves: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Namespace: Namespace,
-				Name:      "net_bonding_slaves",
-				Help:      "Number of configured slaves per bonding interface.",
-			},
-			[]string{"master"},
-		),
-		active: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Namespace: Namespace,
-				Name:      "net_bonding_slaves_active",
-				Help:      "Number of active slaves per bonding interface.",
-			},
-			[]string{"master"},
-		),
+		slaves: newTypedDesc("", "net_bonding_slaves", "Number of configured slaves per bonding interface.", prometheus.GaugeValue, "master"),
+		active: newTypedDesc("", "net_bonding_slaves_active", "Number of active slaves per bonding interface.", prometheus.GaugeValue, "master"),
 	}, nil
 }
 
 // Update reads and exposes bonding states, implements Collector interface. Caution: This works only on linux.
-func (c *bondingCollector) Update(ch chan<- prometheus.Metric) (err error) {
-	bondingStats, err := readBondingStats(sysfsNet)
+func (c *bondingCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	bondingStats, err := readBondingStats(sysFilePath("class/net"))
 	if err != nil {
 		return err
 	}
 	for master, status := range bondingStats {
-		c.slaves.WithLabelValues(master).Set(float64(status[0]))
-		c.active.WithLabelValues(master).Set(float64(status[1]))
+		ch <- c.slaves.mustNewConstMetric(float64(status[0]), master)
+		ch <- c.active.mustNewConstMetric(float64(status[1]), master)
 	}
-	c.slaves.Collect(ch)
-	c.active.Collect(ch)
 	return nil
 }
 
@@ -0,0 +1,483 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// The BSDs return structs and arrays of C types from sysctl, which the
+// decoders below read from the raw bytes. They take the size of a C long and
+// the byte order, so that they are tested on any platform.
+
+// parseSysctlLongs returns the C longs of an array like kern.cp_times.
+func parseSysctlLongs(data []byte, longSize int, order binary.ByteOrder) ([]uint64, error) {
+	if len(data)%longSize != 0 {
+		return nil, fmt.Errorf("invalid length %d of array of %d byte longs", len(data), longSize)
+	}
+	values := make([]uint64, 0, len(data)/longSize)
+	for i := 0; i < len(data); i += longSize {
+		if longSize == 4 {
+			values = append(values, uint64(order.Uint32(data[i:])))
+		} else {
+			values = append(values, order.Uint64(data[i:]))
+		}
+	}
+	return values, nil
+}
+
+// parseSysctlTimeval returns the seconds of a struct timeval like
+// kern.boottime. Its time_t is 32 or 64 bits depending on the platform.
+func parseSysctlTimeval(data []byte, order binary.ByteOrder) (float64, error) {
+	var sec, usec int64
+	switch len(data) {
+	case 8:
+		sec, usec = int64(int32(order.Uint32(data))), int64(int32(order.Uint32(data[4:])))
+	case 12:
+		sec, usec = int64(order.Uint64(data)), int64(int32(order.Uint32(data[8:])))
+	case 16:
+		sec, usec = int64(order.Uint64(data)), int64(order.Uint64(data[8:]))
+	default:
+		return 0, fmt.Errorf("invalid length %d of timeval", len(data))
+	}
+	return float64(sec) + float64(usec)/1e6, nil
+}
+
+// parseSysctlLoadavg returns the 1m load of a struct loadavg like vm.loadavg,
+// three fixed point averages followed by their scale as a long.
+func parseSysctlLoadavg(data []byte, longSize int, order binary.ByteOrder) (float64, error) {
+	if len(data) < 3*4+longSize {
+		return 0, fmt.Errorf("invalid length %d of loadavg", len(data))
+	}
+	scale, err := parseSysctlLongs(data[len(data)-longSize:], longSize, order)
+	if err != nil {
+		return 0, err
+	}
+	if scale[0] == 0 {
+		return 0, fmt.Errorf("loadavg has scale 0")
+	}
+	return float64(order.Uint32(data)) / float64(scale[0]), nil
+}
+
+// cString returns the NUL-terminated string of a C char array like the mount
+// point of a struct statfs.
+func cString(b []int8) string {
+	s := make([]byte, 0, len(b))
+	for _, c := range b {
+		if c == 0 {
+			break
+		}
+		s = append(s, byte(c))
+	}
+	return string(s)
+}
+
+// sysctlInterface holds the counters of a network interface by direction,
+// named like the columns of /proc/net/dev.
+type sysctlInterface struct {
+	name              string
+	receive, transmit map[string]uint64
+}
+
+// Message type and struct sizes of the NET_RT_IFLIST2 routing sysctl of
+// Darwin, see net/if.h and net/if_var.h.
+const (
+	rtmIfInfo2       = 0x12
+	sizeofIfMsghdr2  = 160
+	ifMsghdr2DataOff = 32
+)
+
+// ifData64Counters are the offsets of the 64 bit counters in struct
+// if_data64, by direction and /proc/net/dev column.
+var ifData64Counters = map[string]map[string]int{
+	"receive": {
+		"packets":   24,
+		"errs":      32,
+		"bytes":     64,
+		"multicast": 80,
+		"drop":      96,
+	},
+	"transmit": {
+		"packets":   40,
+		"errs":      48,
+		"colls":     56,
+		"bytes":     72,
+		"multicast": 88,
+	},
+}
+
+// parseSysctlIfList2 returns the interfaces of the RTM_IFINFO2 messages of a
+// NET_RT_IFLIST2 routing sysctl, skipping the messages of their addresses.
+// Each header is followed by the struct sockaddr_dl naming the interface.
+func parseSysctlIfList2(data []byte, order binary.ByteOrder) ([]sysctlInterface, error) {
+	var ifaces []sysctlInterface
+	for len(data) >= 4 {
+		msglen := int(order.Uint16(data))
+		if msglen < 4 || msglen > len(data) {
+			return nil, fmt.Errorf("invalid routing message length %d", msglen)
+		}
+		msg := data[:msglen]
+		data = data[msglen:]
+		if msg[3] != rtmIfInfo2 {
+			continue
+		}
+		// sockaddr_dl: length, family, index, type, then the lengths of
+		// the name, address and selector, and the name at offset 8.
+		if len(msg) < sizeofIfMsghdr2+8 {
+			return nil, fmt.Errorf("truncated interface message of %d bytes", len(msg))
+		}
+		sdl := msg[sizeofIfMsghdr2:]
+		nlen := int(sdl[5])
+		if len(sdl) < 8+nlen {
+			return nil, fmt.Errorf("truncated interface name of %d bytes", nlen)
+		}
+		iface := sysctlInterface{
+			name:     string(sdl[8 : 8+nlen]),
+			receive:  map[string]uint64{},
+			transmit: map[string]uint64{},
+		}
+		counters := msg[ifMsghdr2DataOff:]
+		for name, off := range ifData64Counters["receive"] {
+			iface.receive[name] = order.Uint64(counters[off:])
+		}
+		for name, off := range ifData64Counters["transmit"] {
+			iface.transmit[name] = order.Uint64(counters[off:])
+		}
+		ifaces = append(ifaces, iface)
+	}
+	return ifaces, nil
+}
+
+// parseSysctlClockinfo returns the stathz of a struct clockinfo like
+// kern.clockrate. It is the last int but profhz on FreeBSD, NetBSD and all
+// versions of OpenBSD, with or without tickadj.
+func parseSysctlClockinfo(data []byte, order binary.ByteOrder) (float64, error) {
+	if len(data) < 16 || len(data)%4 != 0 {
+		return 0, fmt.Errorf("invalid length %d of clockinfo", len(data))
+	}
+	hz := order.Uint32(data[len(data)-8:])
+	if hz == 0 {
+		return 0, fmt.Errorf("clockinfo has stathz 0")
+	}
+	return float64(hz), nil
+}
+
+// cpTimeModes returns the names of the CPUSTATES modes of a cp_time array,
+// named like those of Linux. OpenBSD 6.4 added spin between sys and intr.
+func cpTimeModes(states int) ([]string, error) {
+	switch states {
+	case 5:
+		return []string{"user", "nice", "system", "irq", "idle"}, nil
+	case 6:
+		return []string{"user", "nice", "system", "spin", "irq", "idle"}, nil
+	}
+	return nil, fmt.Errorf("unknown cp_time with %d states", states)
+}
+
+// uvmexp holds the fields of struct uvmexp of OpenBSD exported, in pages
+// where not counting events.
+type uvmexp struct {
+	pageSize, pages, free, active, inactive, wired uint32
+	swapPages, swapInUse                           uint32
+	interrupts, contextSwitches                    uint32
+}
+
+// Indexes of the fields of uvmexp in struct uvmexp, an array of ints up to
+// them, see uvm/uvmexp.h.
+const (
+	uvmexpPageSize        = 0
+	uvmexpPages           = 3
+	uvmexpFree            = 4
+	uvmexpActive          = 5
+	uvmexpInactive        = 6
+	uvmexpWired           = 8
+	uvmexpSwapPages       = 26
+	uvmexpSwapInUse       = 27
+	uvmexpInterrupts      = 35
+	uvmexpContextSwitches = 36
+)
+
+// parseSysctlUvmexp returns the uvmexp of vm.uvmexp.
+func parseSysctlUvmexp(data []byte, order binary.ByteOrder) (uvmexp, error) {
+	if len(data) < 4*(uvmexpContextSwitches+1) {
+		return uvmexp{}, fmt.Errorf("invalid length %d of uvmexp", len(data))
+	}
+	field := func(i int) uint32 { return order.Uint32(data[4*i:]) }
+	return uvmexp{
+		pageSize:        field(uvmexpPageSize),
+		pages:           field(uvmexpPages),
+		free:            field(uvmexpFree),
+		active:          field(uvmexpActive),
+		inactive:        field(uvmexpInactive),
+		wired:           field(uvmexpWired),
+		swapPages:       field(uvmexpSwapPages),
+		swapInUse:       field(uvmexpSwapInUse),
+		interrupts:      field(uvmexpInterrupts),
+		contextSwitches: field(uvmexpContextSwitches),
+	}, nil
+}
+
+// sysctlDisk holds the counters of a disk of hw.diskstats.
+type sysctlDisk struct {
+	name                  string
+	busy                  uint64
+	reads, writes         uint64
+	readBytes, writeBytes uint64
+	// busyTime is the time the disk was busy in seconds.
+	busyTime float64
+	// readTime and writeTime are the seconds spent by all reads and
+	// writes, where known.
+	readTime, writeTime float64
+}
+
+// diskNameLen is DS_DISKNAMELEN of OpenBSD, IOSTATNAMELEN of NetBSD and
+// DEVSTAT_NAME_LEN of DragonFly and FreeBSD.
+const diskNameLen = 16
+
+// parseSysctlDiskstats returns the disks of an array of struct diskstats
+// like hw.diskstats of OpenBSD: the name, an int busy count, the uint64
+// transfer, seek and byte counters, then the attach, last unbusy and busy
+// timevals. 64 bit platforms pad the int and have 16 byte timevals, 32 bit
+// platforms 12 byte timevals.
+func parseSysctlDiskstats(data []byte, count int, order binary.ByteOrder) ([]sysctlDisk, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	size := len(data) / count
+	var counters, timeval int
+	switch size {
+	case 112:
+		counters, timeval = diskNameLen+8, 16
+	case 96:
+		counters, timeval = diskNameLen+4, 12
+	default:
+		return nil, fmt.Errorf("unknown diskstats of %d bytes", size)
+	}
+	disks := make([]sysctlDisk, 0, count)
+	for i := 0; i < count; i++ {
+		ds := data[i*size : (i+1)*size]
+		busyTime, err := parseSysctlTimeval(ds[counters+5*8+2*timeval:counters+5*8+3*timeval], order)
+		if err != nil {
+			return nil, err
+		}
+		name := ds[:diskNameLen]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		disks = append(disks, sysctlDisk{
+			name:       string(name),
+			busy:       uint64(order.Uint32(ds[diskNameLen:])),
+			reads:      order.Uint64(ds[counters:]),
+			writes:     order.Uint64(ds[counters+8:]),
+			readBytes:  order.Uint64(ds[counters+3*8:]),
+			writeBytes: order.Uint64(ds[counters+4*8:]),
+			busyTime:   busyTime,
+		})
+	}
+	return disks, nil
+}
+
+// uvmexpSysctl holds the fields of struct uvmexp_sysctl of NetBSD exported,
+// in pages where not counting events.
+type uvmexpSysctl struct {
+	pageSize, pages, free, active, inactive, wired, filePages int64
+	swapPages, swapInUse                                      int64
+	interrupts, contextSwitches, forks                        int64
+}
+
+// Indexes of the fields of uvmexpSysctl in struct uvmexp_sysctl, an array
+// of int64 up to them, see uvm/uvm_extern.h.
+const (
+	uvmexp2PageSize        = 0
+	uvmexp2Pages           = 3
+	uvmexp2Free            = 4
+	uvmexp2Active          = 5
+	uvmexp2Inactive        = 6
+	uvmexp2Wired           = 8
+	uvmexp2FilePages       = 13
+	uvmexp2SwapPages       = 20
+	uvmexp2SwapInUse       = 21
+	uvmexp2Interrupts      = 29
+	uvmexp2ContextSwitches = 30
+	uvmexp2Forks           = 38
+)
+
+// parseSysctlUvmexp2 returns the uvmexpSysctl of vm.uvmexp2.
+func parseSysctlUvmexp2(data []byte, order binary.ByteOrder) (uvmexpSysctl, error) {
+	if len(data) < 8*(uvmexp2Forks+1) {
+		return uvmexpSysctl{}, fmt.Errorf("invalid length %d of uvmexp_sysctl", len(data))
+	}
+	field := func(i int) int64 { return int64(order.Uint64(data[8*i:])) }
+	return uvmexpSysctl{
+		pageSize:        field(uvmexp2PageSize),
+		pages:           field(uvmexp2Pages),
+		free:            field(uvmexp2Free),
+		active:          field(uvmexp2Active),
+		inactive:        field(uvmexp2Inactive),
+		wired:           field(uvmexp2Wired),
+		filePages:       field(uvmexp2FilePages),
+		swapPages:       field(uvmexp2SwapPages),
+		swapInUse:       field(uvmexp2SwapInUse),
+		interrupts:      field(uvmexp2Interrupts),
+		contextSwitches: field(uvmexp2ContextSwitches),
+		forks:           field(uvmexp2Forks),
+	}, nil
+}
+
+// sizeofIoSysctl is the size of struct io_sysctl of NetBSD up to the
+// separate read and write counters, which hw.iostats returns its entries
+// truncated to when asked for it.
+const sizeofIoSysctl = 104
+
+// ioSysctlDisk is IOSTAT_DISK, the type of disks in struct io_sysctl.
+const ioSysctlDisk = 0
+
+// parseSysctlIostats returns the disks of an array of struct io_sysctl like
+// hw.iostats of NetBSD: the name, int32 busy count and type, the uint64
+// transfer, seek and byte counters, the uint32 seconds and microseconds of
+// the attach, last unbusy and busy times, then the uint64 read and write
+// transfers and bytes. Tapes and NFS mounts are left out.
+func parseSysctlIostats(data []byte, order binary.ByteOrder) ([]sysctlDisk, error) {
+	if len(data)%sizeofIoSysctl != 0 {
+		return nil, fmt.Errorf("invalid length %d of iostats", len(data))
+	}
+	var disks []sysctlDisk
+	for ; len(data) > 0; data = data[sizeofIoSysctl:] {
+		if order.Uint32(data[diskNameLen+4:]) != ioSysctlDisk {
+			continue
+		}
+		name := data[:diskNameLen]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		disks = append(disks, sysctlDisk{
+			name:       string(name),
+			busy:       uint64(order.Uint32(data[diskNameLen:])),
+			reads:      order.Uint64(data[72:]),
+			writes:     order.Uint64(data[80:]),
+			readBytes:  order.Uint64(data[88:]),
+			writeBytes: order.Uint64(data[96:]),
+			busyTime:   float64(order.Uint32(data[64:])) + float64(order.Uint32(data[68:]))/1e6,
+		})
+	}
+	return disks, nil
+}
+
+// parseSysctlCputime returns the user, nice, sys, intr and idle times in
+// microseconds of each of the ncpu struct kinfo_cputime of kern.cputime of
+// DragonFly, which start with them as uint64.
+func parseSysctlCputime(data []byte, ncpu int, order binary.ByteOrder) ([][]uint64, error) {
+	if ncpu <= 0 || len(data)%ncpu != 0 || len(data)/ncpu < 5*8 {
+		return nil, fmt.Errorf("invalid length %d of cputime of %d cpus", len(data), ncpu)
+	}
+	size := len(data) / ncpu
+	times := make([][]uint64, 0, ncpu)
+	for ; len(data) > 0; data = data[size:] {
+		cpu := make([]uint64, 5)
+		for i := range cpu {
+			cpu[i] = order.Uint64(data[8*i:])
+		}
+		times = append(times, cpu)
+	}
+	return times, nil
+}
+
+// sizeofDevstatDragonFly is the size of struct devstat of DragonFly on
+// amd64, its only platform.
+const sizeofDevstatDragonFly = 200
+
+// devstatTypePass is DEVSTAT_TYPE_PASS, set in the device type of the
+// pass(4) instances shadowing the disks, next to the type of the disk like
+// DEVSTAT_TYPE_DIRECT and of its interface like DEVSTAT_TYPE_IF_IDE (0x20).
+const devstatTypePass = 0x100
+
+// parseSysctlDevstatDragonFly returns the disks of kern.devstat.all of
+// DragonFly: the long generation, then an array of struct devstat with the
+// list link, the uint32 device number, the name and int unit, the uint64
+// byte and operation counters, the int32 busy count, the uint32 block size,
+// the tagged counters, the creation, busy, start and last completion
+// timevals and the int flags, type and priority.
+func parseSysctlDevstatDragonFly(data []byte, order binary.ByteOrder) ([]sysctlDisk, error) {
+	if len(data) < 8 || (len(data)-8)%sizeofDevstatDragonFly != 0 {
+		return nil, fmt.Errorf("invalid length %d of devstat", len(data))
+	}
+	var disks []sysctlDisk
+	for data = data[8:]; len(data) > 0; data = data[sizeofDevstatDragonFly:] {
+		if order.Uint32(data[188:])&devstatTypePass != 0 {
+			continue
+		}
+		busyTime, err := parseSysctlTimeval(data[136:152], order)
+		if err != nil {
+			return nil, err
+		}
+		name := data[12 : 12+diskNameLen]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		disks = append(disks, sysctlDisk{
+			name:       fmt.Sprintf("%s%d", name, int32(order.Uint32(data[28:]))),
+			busy:       uint64(order.Uint32(data[88:])),
+			reads:      order.Uint64(data[56:]),
+			writes:     order.Uint64(data[64:]),
+			readBytes:  order.Uint64(data[32:]),
+			writeBytes: order.Uint64(data[40:]),
+			busyTime:   busyTime,
+		})
+	}
+	return disks, nil
+}
+
+// sizeofDevstatFreeBSD is the size of struct devstat of FreeBSD on 64 bit
+// platforms, version 6 of the devstat interface.
+const sizeofDevstatFreeBSD = 288
+
+// Indexes of the bytes, operations and durations of struct devstat of
+// FreeBSD by devstat_trans_flags.
+const (
+	devstatRead  = 1
+	devstatWrite = 2
+)
+
+// parseBintime returns the seconds of a struct bintime of 64 bit seconds and
+// a 64 bit binary fraction.
+func parseBintime(data []byte, order binary.ByteOrder) float64 {
+	return float64(int64(order.Uint64(data))) + math.Ldexp(float64(order.Uint64(data[8:])), -64)
+}
+
+// parseSysctlDevstatFreeBSD returns the disks of kern.devstat.all of
+// FreeBSD, which devstat(3) reads: the long generation, then an array of
+// struct devstat with the uint start and end counts of the I/Os in
+// progress at 8, the name and int unit at 44, the uint64 bytes and
+// operations and the bintime durations of the no data, read, write and
+// free transactions at 64, the busy bintime at 192 and the type at 260.
+func parseSysctlDevstatFreeBSD(data []byte, order binary.ByteOrder) ([]sysctlDisk, error) {
+	if len(data) < 8 || (len(data)-8)%sizeofDevstatFreeBSD != 0 {
+		return nil, fmt.Errorf("invalid length %d of devstat", len(data))
+	}
+	var disks []sysctlDisk
+	for data = data[8:]; len(data) > 0; data = data[sizeofDevstatFreeBSD:] {
+		if order.Uint32(data[260:])&devstatTypePass != 0 {
+			continue
+		}
+		name := data[44 : 44+diskNameLen]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		byteCount := func(i int) uint64 { return order.Uint64(data[64+8*i:]) }
+		operations := func(i int) uint64 { return order.Uint64(data[96+8*i:]) }
+		duration := func(i int) float64 { return parseBintime(data[128+16*i:], order) }
+		disks = append(disks, sysctlDisk{
+			name:       fmt.Sprintf("%s%d", name, int32(order.Uint32(data[60:]))),
+			busy:       uint64(order.Uint32(data[8:]) - order.Uint32(data[12:])),
+			reads:      operations(devstatRead),
+			writes:     operations(devstatWrite),
+			readBytes:  byteCount(devstatRead),
+			writeBytes: byteCount(devstatWrite),
+			busyTime:   parseBintime(data[192:], order),
+			readTime:   duration(devstatRead),
+			writeTime:  duration(devstatWrite),
+		})
+	}
+	return disks, nil
+}
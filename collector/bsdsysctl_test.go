@@ -0,0 +1,286 @@
+package collector
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestParseSysctlLongs(t *testing.T) {
+	for _, test := range []struct {
+		data     []byte
+		longSize int
+		want     []uint64
+	}{
+		{[]byte{1, 0, 0, 0, 2, 0, 0, 0}, 4, []uint64{1, 2}},
+		{[]byte{1, 0, 0, 0, 2, 0, 0, 0}, 8, []uint64{0x200000001}},
+		{[]byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0}, 8, []uint64{1, 2}},
+	} {
+		got, err := parseSysctlLongs(test.data, test.longSize, binary.LittleEndian)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(test.want) {
+			t.Fatalf("want %v, got %v", test.want, got)
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("want %v, got %v", test.want, got)
+			}
+		}
+	}
+	if _, err := parseSysctlLongs([]byte{1, 0, 0}, 4, binary.LittleEndian); err == nil {
+		t.Error("want error for truncated array")
+	}
+}
+
+func TestParseSysctlTimeval(t *testing.T) {
+	for _, test := range []struct {
+		data []byte
+		want float64
+	}{
+		{[]byte{0xd2, 0x02, 0x96, 0x49, 0x20, 0xa1, 0x07, 0x00}, 1234567890.5},
+		{[]byte{0xd2, 0x02, 0x96, 0x49, 0, 0, 0, 0, 0x20, 0xa1, 0x07, 0x00}, 1234567890.5},
+		{[]byte{0xd2, 0x02, 0x96, 0x49, 0, 0, 0, 0, 0x20, 0xa1, 0x07, 0x00, 0, 0, 0, 0}, 1234567890.5},
+	} {
+		got, err := parseSysctlTimeval(test.data, binary.LittleEndian)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("want %f from %d bytes, got %f", test.want, len(test.data), got)
+		}
+	}
+}
+
+func TestParseSysctlLoadavg(t *testing.T) {
+	// 0.5, 0.25 and 0.125 at scale 2048, with padding before the long.
+	data := []byte{0, 4, 0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 8, 0, 0, 0, 0, 0, 0}
+	load, err := parseSysctlLoadavg(data, 8, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 0.5; want != load {
+		t.Errorf("want load %f, got %f", want, load)
+	}
+	load, err = parseSysctlLoadavg([]byte{0, 4, 0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 8, 0, 0}, 4, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 0.5; want != load {
+		t.Errorf("want load %f from 32 bit loadavg, got %f", want, load)
+	}
+}
+
+func TestParseSysctlIfList2(t *testing.T) {
+	order := binary.LittleEndian
+	ifInfo := make([]byte, sizeofIfMsghdr2+8+4)
+	order.PutUint16(ifInfo, uint16(len(ifInfo)))
+	ifInfo[3] = rtmIfInfo2
+	order.PutUint64(ifInfo[ifMsghdr2DataOff+64:], 1<<40) // receive bytes
+	order.PutUint64(ifInfo[ifMsghdr2DataOff+88:], 7)     // transmit multicast
+	ifInfo[sizeofIfMsghdr2+5] = 3
+	copy(ifInfo[sizeofIfMsghdr2+8:], "en0")
+	// An address message of the interface, to be skipped.
+	addr := make([]byte, 20)
+	order.PutUint16(addr, uint16(len(addr)))
+	addr[3] = 0x13
+
+	ifaces, err := parseSysctlIfList2(append(ifInfo, addr...), order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ifaces) != 1 {
+		t.Fatalf("want 1 interface, got %d", len(ifaces))
+	}
+	if want, got := "en0", ifaces[0].name; want != got {
+		t.Errorf("want interface %s, got %s", want, got)
+	}
+	if want, got := uint64(1<<40), ifaces[0].receive["bytes"]; want != got {
+		t.Errorf("want %d bytes received, got %d", want, got)
+	}
+	if want, got := uint64(7), ifaces[0].transmit["multicast"]; want != got {
+		t.Errorf("want %d multicast packets transmitted, got %d", want, got)
+	}
+
+	if _, err := parseSysctlIfList2(ifInfo[:sizeofIfMsghdr2], order); err == nil {
+		t.Error("want error for truncated message")
+	}
+}
+
+func TestCString(t *testing.T) {
+	if want, got := "apfs", cString([]int8{'a', 'p', 'f', 's', 0, 'x'}); want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestParseSysctlClockinfo(t *testing.T) {
+	order := binary.LittleEndian
+	for _, ints := range [][]uint32{
+		{100, 10000, 0, 128, 1024}, // FreeBSD, older OpenBSD
+		{100, 10000, 128, 1024},    // OpenBSD without tickadj
+	} {
+		data := make([]byte, 4*len(ints))
+		for i, v := range ints {
+			order.PutUint32(data[4*i:], v)
+		}
+		hz, err := parseSysctlClockinfo(data, order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 128.0; want != hz {
+			t.Errorf("want stathz %f from %d ints, got %f", want, len(ints), hz)
+		}
+	}
+}
+
+func TestParseSysctlUvmexp(t *testing.T) {
+	order := binary.LittleEndian
+	data := make([]byte, 4*64)
+	for i, v := range map[int]uint32{uvmexpPageSize: 4096, uvmexpPages: 1000, uvmexpFree: 100, uvmexpWired: 50, uvmexpSwapPages: 200, uvmexpContextSwitches: 12345} {
+		order.PutUint32(data[4*i:], v)
+	}
+	uvm, err := parseSysctlUvmexp(data, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (uvmexp{pageSize: 4096, pages: 1000, free: 100, wired: 50, swapPages: 200, contextSwitches: 12345}); want != uvm {
+		t.Errorf("want %+v, got %+v", want, uvm)
+	}
+	if _, err := parseSysctlUvmexp(data[:100], order); err == nil {
+		t.Error("want error for truncated uvmexp")
+	}
+}
+
+func TestParseSysctlDiskstats(t *testing.T) {
+	order := binary.LittleEndian
+	data := make([]byte, 2*112)
+	for i, name := range []string{"sd0", "cd0"} {
+		ds := data[i*112:]
+		copy(ds, name)
+		order.PutUint32(ds[16:], uint32(i))
+		order.PutUint64(ds[24:], 10)   // reads
+		order.PutUint64(ds[56:], 4096) // bytes written
+		order.PutUint64(ds[96:], 3)    // busy seconds
+		order.PutUint64(ds[104:], 5e5) // busy microseconds
+	}
+	disks, err := parseSysctlDiskstats(data, 2, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (sysctlDisk{name: "cd0", busy: 1, reads: 10, writeBytes: 4096, busyTime: 3.5}); len(disks) != 2 || disks[1] != want {
+		t.Errorf("want second disk %+v, got %+v", want, disks)
+	}
+	if _, err := parseSysctlDiskstats(data[:100], 1, order); err == nil {
+		t.Error("want error for unknown struct size")
+	}
+}
+
+func TestParseSysctlUvmexp2(t *testing.T) {
+	order := binary.LittleEndian
+	data := make([]byte, 8*64)
+	for i, v := range map[int]uint64{uvmexp2PageSize: 4096, uvmexp2Pages: 1000, uvmexp2FilePages: 300, uvmexp2SwapInUse: 20, uvmexp2Forks: 77} {
+		order.PutUint64(data[8*i:], v)
+	}
+	uvm, err := parseSysctlUvmexp2(data, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (uvmexpSysctl{pageSize: 4096, pages: 1000, filePages: 300, swapInUse: 20, forks: 77}); want != uvm {
+		t.Errorf("want %+v, got %+v", want, uvm)
+	}
+}
+
+func TestParseSysctlIostats(t *testing.T) {
+	order := binary.LittleEndian
+	data := make([]byte, 2*sizeofIoSysctl)
+	copy(data, "wd0")
+	order.PutUint64(data[80:], 9)      // writes
+	order.PutUint64(data[88:], 1024)   // bytes read
+	order.PutUint32(data[64:], 2)      // busy seconds
+	order.PutUint32(data[68:], 250000) // busy microseconds
+	copy(data[sizeofIoSysctl:], "nfs0")
+	order.PutUint32(data[sizeofIoSysctl+diskNameLen+4:], 2) // IOSTAT_NFS
+
+	disks, err := parseSysctlIostats(data, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (sysctlDisk{name: "wd0", writes: 9, readBytes: 1024, busyTime: 2.25}); len(disks) != 1 || disks[0] != want {
+		t.Errorf("want only disk %+v, got %+v", want, disks)
+	}
+}
+
+func TestParseSysctlCputime(t *testing.T) {
+	order := binary.LittleEndian
+	data := make([]byte, 2*96)
+	order.PutUint64(data[96:], 1500000) // user of cpu1
+	order.PutUint64(data[96+32:], 7)    // idle of cpu1
+	times, err := parseSysctlCputime(data, 2, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint64{1500000, 0, 0, 0, 7}; len(times) != 2 || !reflect.DeepEqual(times[1], want) {
+		t.Errorf("want times %v of cpu1, got %v", want, times)
+	}
+	if _, err := parseSysctlCputime(data[:95], 2, order); err == nil {
+		t.Error("want error for truncated cputime")
+	}
+}
+
+func TestParseSysctlDevstatDragonFly(t *testing.T) {
+	order := binary.LittleEndian
+	data := make([]byte, 8+2*sizeofDevstatDragonFly)
+	ds := data[8:]
+	copy(ds[12:], "ad")
+	order.PutUint32(ds[28:], 1)       // unit
+	order.PutUint32(ds[188:], 0x20)   // DEVSTAT_TYPE_DIRECT|DEVSTAT_TYPE_IF_IDE
+	order.PutUint64(ds[32:], 8192)    // bytes read
+	order.PutUint64(ds[64:], 4)       // writes
+	order.PutUint32(ds[88:], 2)       // busy count
+	order.PutUint64(ds[136:], 1)      // busy seconds
+	order.PutUint64(ds[144:], 250000) // busy microseconds
+	pass := data[8+sizeofDevstatDragonFly:]
+	copy(pass[12:], "pass")
+	order.PutUint32(pass[188:], 0x120) // DEVSTAT_TYPE_PASS|DEVSTAT_TYPE_DIRECT|DEVSTAT_TYPE_IF_IDE
+
+	disks, err := parseSysctlDevstatDragonFly(data, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (sysctlDisk{name: "ad1", busy: 2, writes: 4, readBytes: 8192, busyTime: 1.25}); len(disks) != 1 || disks[0] != want {
+		t.Errorf("want only disk %+v, got %+v", want, disks)
+	}
+	if _, err := parseSysctlDevstatDragonFly(data[:100], order); err == nil {
+		t.Error("want error for truncated devstat")
+	}
+}
+
+func TestParseSysctlDevstatFreeBSD(t *testing.T) {
+	order := binary.LittleEndian
+	data := make([]byte, 8+2*sizeofDevstatFreeBSD)
+	ds := data[8:]
+	order.PutUint32(ds[8:], 12) // start count
+	order.PutUint32(ds[12:], 9) // end count
+	copy(ds[44:], "ada")
+	order.PutUint32(ds[260:], 0x20)                   // DEVSTAT_TYPE_DIRECT|DEVSTAT_TYPE_IF_IDE
+	order.PutUint64(ds[64+8*devstatWrite:], 4096)     // bytes written
+	order.PutUint64(ds[96+8*devstatRead:], 7)         // reads
+	order.PutUint64(ds[128+16*devstatRead:], 2)       // read seconds
+	order.PutUint64(ds[128+16*devstatRead+8:], 1<<63) // half a second
+	order.PutUint64(ds[192:], 3)                      // busy seconds
+	pass := data[8+sizeofDevstatFreeBSD:]
+	copy(pass[44:], "pass")
+	order.PutUint32(pass[260:], 0x120) // DEVSTAT_TYPE_PASS|DEVSTAT_TYPE_DIRECT|DEVSTAT_TYPE_IF_IDE
+
+	disks, err := parseSysctlDevstatFreeBSD(data, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (sysctlDisk{name: "ada0", busy: 3, reads: 7, writeBytes: 4096, busyTime: 3, readTime: 2.5}); len(disks) != 1 || disks[0] != want {
+		t.Errorf("want only disk %+v, got %+v", want, disks)
+	}
+	if _, err := parseSysctlDevstatFreeBSD(data[:200], order); err == nil {
+		t.Error("want error for truncated devstat")
+	}
+}
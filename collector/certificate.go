@@ -0,0 +1,140 @@
+// +build !nocertificate
+
+package collector
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const certificateSubsystem = "certificate"
+
+var (
+	certificatePaths = flag.String("collector.certificate.paths", "", "Comma-separated list of PEM certificate files or glob patterns to export expiry of.")
+)
+
+type certificateCollector struct {
+	config   Config
+	patterns []string
+
+	notAfter, daysLeft *prometheus.GaugeVec
+}
+
+func init() {
+	registerCollector("certificate", defaultDisabled, NewCertificateCollector)
+}
+
+// NewCertificateCollector returns a new Collector exposing the expiry of PEM
+// encoded certificates on disk. Paths are taken from
+// --collector.certificate.paths and the certificate_paths config entry.
+func NewCertificateCollector(config Config) (Collector, error) {
+	patterns := []string{}
+	for _, list := range []string{*certificatePaths, config.Config["certificate_paths"]} {
+		for _, p := range strings.Split(list, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				if _, err := filepath.Match(p, ""); err != nil {
+					return nil, fmt.Errorf("invalid certificate pattern %s: %s", p, err)
+				}
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("No certificates specified, see --collector.certificate.paths")
+	}
+
+	var certLabelNames = []string{"path", "subject", "issuer", "serial"}
+
+	return &certificateCollector{
+		config:   config,
+		patterns: patterns,
+		notAfter: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: certificateSubsystem,
+				Name:      "not_after",
+				Help:      "Certificate expiry (notAfter) in unixtime.",
+			},
+			certLabelNames,
+		),
+		daysLeft: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: certificateSubsystem,
+				Name:      "days_until_expiry",
+				Help:      "Days until the certificate expires, negative once expired.",
+			},
+			certLabelNames,
+		),
+	}, nil
+}
+
+func (c *certificateCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	// Certificates get rotated, only export what is currently on disk.
+	c.notAfter.Reset()
+	c.daysLeft.Reset()
+
+	now := time.Now()
+	for _, pattern := range c.patterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			certs, err := readCertificates(p)
+			if err != nil {
+				return fmt.Errorf("couldn't read certificates from %s: %s", p, err)
+			}
+			for _, cert := range certs {
+				labels := []string{p, cert.Subject.CommonName, cert.Issuer.CommonName, cert.SerialNumber.String()}
+				c.notAfter.WithLabelValues(labels...).Set(float64(cert.NotAfter.Unix()))
+				c.daysLeft.WithLabelValues(labels...).Set(cert.NotAfter.Sub(now).Hours() / 24)
+			}
+		}
+	}
+	c.notAfter.Collect(ch)
+	c.daysLeft.Collect(ch)
+	return nil
+}
+
+func readCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCertificates(data)
+}
+
+// parseCertificates decodes all certificates in PEM encoded data. Other PEM
+// blocks, like private keys in combined files, are skipped.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found")
+	}
+	return certs, nil
+}
@@ -0,0 +1,35 @@
+package collector
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestCertificates(t *testing.T) {
+	data, err := ioutil.ReadFile("fixtures/certificate/etcd.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certs, err := parseCertificates(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 1, len(certs); want != got {
+		t.Fatalf("want %d certificates, got %d", want, got)
+	}
+	if want, got := "etcd.example.com", certs[0].Subject.CommonName; want != got {
+		t.Errorf("want subject %s, got %s", want, got)
+	}
+	if want, got := int64(2107324988), certs[0].NotAfter.Unix(); want != got {
+		t.Errorf("want notAfter %d, got %d", want, got)
+	}
+	if want, got := "4242", certs[0].SerialNumber.String(); want != got {
+		t.Errorf("want serial %s, got %s", want, got)
+	}
+
+	if _, err := parseCertificates([]byte("not a certificate")); err == nil {
+		t.Error("want error for data without certificates")
+	}
+}
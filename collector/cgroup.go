@@ -0,0 +1,344 @@
+// +build !nocgroup
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector/procfs"
+	"golang.org/x/net/context"
+)
+
+const cgroupSubsystem = "cgroup"
+
+var (
+	cgroupRoot  = flag.String("collector.cgroup.root", "", "Mount point of the unified (v2) cgroup hierarchy, fs/cgroup below -path.sysfs if empty.")
+	cgroupDepth = flag.Int("collector.cgroup.depth", 2, "Maximum depth below the root of cgroups to export.")
+)
+
+// Keys of cpu.stat exported as seconds, they are reported in microseconds.
+var cgroupCPUSeconds = map[string]string{
+	"usage_usec":     "cpu_usage_seconds",
+	"user_usec":      "cpu_user_seconds",
+	"system_usec":    "cpu_system_seconds",
+	"throttled_usec": "cpu_throttled_seconds",
+}
+
+// Keys of io.stat and the metric names they are exported as.
+var cgroupIOStats = map[string]string{
+	"rbytes": "io_read_bytes",
+	"wbytes": "io_written_bytes",
+	"rios":   "io_reads",
+	"wios":   "io_writes",
+	"dbytes": "io_discarded_bytes",
+	"dios":   "io_discards",
+}
+
+type cgroupCollector struct {
+	config Config
+	root   string
+	depth  int
+
+	cpu, io                        map[string]*prometheus.CounterVec
+	periods, throttledPeriods      *prometheus.CounterVec
+	memoryEvents                   *prometheus.CounterVec
+	memoryCurrent, memoryMax, pids *prometheus.GaugeVec
+}
+
+func init() {
+	registerCollector("cgroup", defaultDisabled, NewCgroupCollector)
+}
+
+// NewCgroupCollector returns a new Collector exposing CPU, memory, I/O and
+// pid usage of the cgroups in the unified hierarchy.
+func NewCgroupCollector(config Config) (Collector, error) {
+	root := *cgroupRoot
+	if root == "" {
+		root = sysFilePath("fs/cgroup")
+	}
+	if _, err := os.Stat(path.Join(root, "cgroup.controllers")); err != nil {
+		return nil, NotApplicable("no unified cgroup hierarchy at %s: %s", root, err)
+	}
+
+	c := &cgroupCollector{
+		config: config,
+		root:   root,
+		depth:  *cgroupDepth,
+		cpu:    map[string]*prometheus.CounterVec{},
+		io:     map[string]*prometheus.CounterVec{},
+		periods: newCgroupCounter("cpu_periods", "Number of elapsed CFS enforcement periods.",
+			"cgroup"),
+		throttledPeriods: newCgroupCounter("cpu_throttled_periods", "Number of CFS enforcement periods the cgroup was throttled in.",
+			"cgroup"),
+		memoryEvents: newCgroupCounter("memory_events", "Number of memory events by type, from memory.events.",
+			"cgroup", "event"),
+		memoryCurrent: newCgroupGauge("memory_current_bytes", "Memory currently used by the cgroup and its descendants in bytes."),
+		memoryMax:     newCgroupGauge("memory_max_bytes", "Memory usage hard limit of the cgroup in bytes, +Inf if unlimited."),
+		pids:          newCgroupGauge("pids_current", "Number of processes in the cgroup and its descendants."),
+	}
+	for _, name := range cgroupCPUSeconds {
+		c.cpu[name] = newCgroupCounter(name, "CPU time of the cgroup in seconds, from cpu.stat.", "cgroup")
+	}
+	for _, name := range cgroupIOStats {
+		c.io[name] = newCgroupCounter(name, "I/O of the cgroup per device, from io.stat.", "cgroup", "device")
+	}
+	return c, nil
+}
+
+func newCgroupCounter(name, help string, labelNames ...string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: cgroupSubsystem,
+			Name:      name,
+			Help:      help,
+		},
+		labelNames,
+	)
+}
+
+func newCgroupGauge(name, help string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: cgroupSubsystem,
+			Name:      name,
+			Help:      help,
+		},
+		[]string{"cgroup"},
+	)
+}
+
+func (c *cgroupCollector) collectors() []prometheus.Collector {
+	collectors := []prometheus.Collector{c.periods, c.throttledPeriods, c.memoryEvents, c.memoryCurrent, c.memoryMax, c.pids}
+	for _, v := range c.cpu {
+		collectors = append(collectors, v)
+	}
+	for _, v := range c.io {
+		collectors = append(collectors, v)
+	}
+	return collectors
+}
+
+func (c *cgroupCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	cgroups, err := listCgroups(c.root, c.depth)
+	if err != nil {
+		return fmt.Errorf("couldn't list cgroups: %s", err)
+	}
+
+	// Cgroups are created and removed all the time, only export the
+	// current ones.
+	c.periods.Reset()
+	c.throttledPeriods.Reset()
+	c.memoryEvents.Reset()
+	c.memoryCurrent.Reset()
+	c.memoryMax.Reset()
+	c.pids.Reset()
+	for _, v := range c.cpu {
+		v.Reset()
+	}
+	for _, v := range c.io {
+		v.Reset()
+	}
+
+	// The files of all cgroups are read at once, as reading them one by one
+	// takes long on hosts with many containers.
+	var names []string
+	for _, cgroup := range cgroups {
+		for _, file := range cgroupFiles {
+			names = append(names, path.Join(strings.TrimPrefix(cgroup, "/"), file))
+		}
+	}
+	contents, err := procfs.ReadFiles(c.root, names, *readWorkers)
+	if err != nil {
+		return fmt.Errorf("couldn't read cgroups: %s", err)
+	}
+	for i, cgroup := range cgroups {
+		files := map[string][]byte{}
+		for j, file := range cgroupFiles {
+			// Cgroups can vanish while being read and not every
+			// controller is enabled everywhere, so missing files are
+			// skipped.
+			result := contents[i*len(cgroupFiles)+j]
+			if result.Err != nil && !os.IsNotExist(result.Err) {
+				return fmt.Errorf("couldn't read cgroup %s: %s", cgroup, result.Err)
+			}
+			if result.Err == nil {
+				files[file] = result.Data
+			}
+		}
+		if err := c.updateCgroup(cgroup, files); err != nil {
+			return fmt.Errorf("couldn't read cgroup %s: %s", cgroup, err)
+		}
+	}
+	for _, m := range c.collectors() {
+		m.Collect(ch)
+	}
+	return nil
+}
+
+// cgroupFiles are the files read of each cgroup.
+var cgroupFiles = []string{"cpu.stat", "memory.events", "memory.current", "memory.max", "pids.current", "io.stat"}
+
+// updateCgroup sets the metrics of cgroup from the contents of its files,
+// leaving out missing ones.
+func (c *cgroupCollector) updateCgroup(cgroup string, files map[string][]byte) error {
+	if data, ok := files["cpu.stat"]; ok {
+		stats, err := parseCgroupFlatKeyed(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		for key, name := range cgroupCPUSeconds {
+			if v, ok := stats[key]; ok {
+				c.cpu[name].WithLabelValues(cgroup).Set(v / 1e6)
+			}
+		}
+		if v, ok := stats["nr_periods"]; ok {
+			c.periods.WithLabelValues(cgroup).Set(v)
+		}
+		if v, ok := stats["nr_throttled"]; ok {
+			c.throttledPeriods.WithLabelValues(cgroup).Set(v)
+		}
+	}
+
+	if data, ok := files["memory.events"]; ok {
+		events, err := parseCgroupFlatKeyed(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		for event, v := range events {
+			c.memoryEvents.WithLabelValues(cgroup, event).Set(v)
+		}
+	}
+
+	for file, gauge := range map[string]*prometheus.GaugeVec{
+		"memory.current": c.memoryCurrent,
+		"memory.max":     c.memoryMax,
+		"pids.current":   c.pids,
+	} {
+		data, ok := files[file]
+		if !ok {
+			continue
+		}
+		v, err := parseCgroupValue(data)
+		if err != nil {
+			return err
+		}
+		gauge.WithLabelValues(cgroup).Set(v)
+	}
+
+	data, ok := files["io.stat"]
+	if !ok {
+		return nil
+	}
+	ioStats, err := parseCgroupIOStat(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for device, stats := range ioStats {
+		for key, v := range stats {
+			if name, ok := cgroupIOStats[key]; ok {
+				c.io[name].WithLabelValues(cgroup, device).Set(v)
+			}
+		}
+	}
+	return nil
+}
+
+// listCgroups returns the cgroups below root up to the given depth, as paths
+// relative to root. The root cgroup itself is returned as "/".
+func listCgroups(root string, depth int) ([]string, error) {
+	cgroups := []string{}
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			// Removed while walking.
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		cgroup := "/" + filepath.ToSlash(rel)
+		if rel == "." {
+			cgroup = "/"
+		} else if strings.Count(cgroup, "/") > depth {
+			return filepath.SkipDir
+		}
+		cgroups = append(cgroups, cgroup)
+		return nil
+	})
+	return cgroups, err
+}
+
+// parseCgroupFlatKeyed parses files consisting of "key value" lines, like
+// cpu.stat and memory.events.
+func parseCgroupFlatKeyed(r io.Reader) (map[string]float64, error) {
+	stats := map[string]float64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid line: %s", scanner.Text())
+		}
+		v, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %s: %s", parts[1], err)
+		}
+		stats[parts[0]] = v
+	}
+	return stats, scanner.Err()
+}
+
+// parseCgroupIOStat parses io.stat, which has one line of key=value pairs
+// per device: "8:0 rbytes=1 wbytes=2 rios=3 wios=4 dbytes=0 dios=0".
+func parseCgroupIOStat(r io.Reader) (map[string]map[string]float64, error) {
+	stats := map[string]map[string]float64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 0 {
+			continue
+		}
+		device := parts[0]
+		stats[device] = map[string]float64{}
+		for _, kv := range parts[1:] {
+			pair := strings.SplitN(kv, "=", 2)
+			if len(pair) != 2 {
+				return nil, fmt.Errorf("invalid io.stat field %s", kv)
+			}
+			v, err := strconv.ParseFloat(pair[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid io.stat value %s: %s", pair[1], err)
+			}
+			stats[device][pair[0]] = v
+		}
+	}
+	return stats, scanner.Err()
+}
+
+// parseCgroupValue parses single value files. Limits are reported as "max"
+// when not set.
+func parseCgroupValue(data []byte) (float64, error) {
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return math.Inf(1), nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestListCgroups(t *testing.T) {
+	cgroups, err := listCgroups("fixtures/sys/fs/cgroup", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"/", "/system.slice", "/system.slice/docker.service", "/user.slice", "/user.slice/user-1000.slice"}
+	if !reflect.DeepEqual(want, cgroups) {
+		t.Errorf("want cgroups %v, got %v", want, cgroups)
+	}
+}
+
+func TestCgroupFlatKeyed(t *testing.T) {
+	file, err := os.Open("fixtures/sys/fs/cgroup/system.slice/docker.service/cpu.stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	stats, err := parseCgroupFlatKeyed(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 350000.0, stats["throttled_usec"]; want != got {
+		t.Errorf("want throttled_usec %f, got %f", want, got)
+	}
+	if want, got := 7.0, stats["nr_throttled"]; want != got {
+		t.Errorf("want nr_throttled %f, got %f", want, got)
+	}
+}
+
+func TestCgroupIOStat(t *testing.T) {
+	file, err := os.Open("fixtures/sys/fs/cgroup/system.slice/docker.service/io.stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	stats, err := parseCgroupIOStat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 1310720.0, stats["8:0"]["rbytes"]; want != got {
+		t.Errorf("want 8:0 rbytes %f, got %f", want, got)
+	}
+	if want, got := 2.0, stats["253:1"]["wios"]; want != got {
+		t.Errorf("want 253:1 wios %f, got %f", want, got)
+	}
+}
+
+func TestCgroupValue(t *testing.T) {
+	max, err := parseCgroupValue([]byte("max\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(max, 1) {
+		t.Errorf("want unlimited memory.max, got %f", max)
+	}
+
+	max, err = parseCgroupValue([]byte("2147483648\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 2147483648.0; want != max {
+		t.Errorf("want memory.max %f, got %f", want, max)
+	}
+}
@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"strconv"
+	"unsafe"
+
+	"github.com/prometheus/node_exporter/log"
+)
+
+const (
+	// atClkTck is the aux vector entry holding the frequency of times(2),
+	// the unit of the CPU times in /proc.
+	atClkTck = 17
+	// defaultUserHZ is USER_HZ on all common architectures.
+	defaultUserHZ = 100
+)
+
+// userHZ is the number of clock ticks per second, like sysconf(_SC_CLK_TCK)
+// but without cgo. It is read before the flags are parsed, from the /proc of
+// the exporter itself.
+var userHZ = clockTicks()
+
+func clockTicks() float64 {
+	data, err := ioutil.ReadFile("/proc/self/auxv")
+	if err != nil {
+		log.Debugf("Couldn't read aux vector, assuming USER_HZ %d: %s", defaultUserHZ, err)
+		return defaultUserHZ
+	}
+	hz, ok := parseAuxv(data, strconv.IntSize/8, nativeEndian(), atClkTck)
+	if !ok || hz == 0 {
+		log.Debugf("No clock ticks in aux vector, assuming USER_HZ %d", defaultUserHZ)
+		return defaultUserHZ
+	}
+	return float64(hz)
+}
+
+// parseAuxv returns the value of the entry with the given type from an aux
+// vector of native words, as found in /proc/[pid]/auxv.
+func parseAuxv(data []byte, wordSize int, order binary.ByteOrder, typ uint64) (uint64, bool) {
+	word := func(b []byte) uint64 {
+		if wordSize == 4 {
+			return uint64(order.Uint32(b))
+		}
+		return order.Uint64(b)
+	}
+	for i := 0; i+2*wordSize <= len(data); i += 2 * wordSize {
+		switch word(data[i:]) {
+		case 0: // AT_NULL ends the vector.
+			return 0, false
+		case typ:
+			return word(data[i+wordSize:]), true
+		}
+	}
+	return 0, false
+}
+
+func nativeEndian() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
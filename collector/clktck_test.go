@@ -0,0 +1,40 @@
+package collector
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseAuxv(t *testing.T) {
+	for _, test := range []struct {
+		wordSize int
+		order    binary.ByteOrder
+		entries  []uint64
+		want     uint64
+		ok       bool
+	}{
+		{8, binary.LittleEndian, []uint64{6, 4096, atClkTck, 100, 0, 0}, 100, true},
+		{4, binary.BigEndian, []uint64{6, 4096, atClkTck, 1024, 0, 0}, 1024, true},
+		{8, binary.LittleEndian, []uint64{6, 4096, 0, 0, atClkTck, 100}, 0, false},
+		{8, binary.LittleEndian, []uint64{6}, 0, false},
+	} {
+		data := make([]byte, test.wordSize*len(test.entries))
+		for i, e := range test.entries {
+			if test.wordSize == 4 {
+				test.order.PutUint32(data[i*4:], uint32(e))
+			} else {
+				test.order.PutUint64(data[i*8:], e)
+			}
+		}
+		got, ok := parseAuxv(data, test.wordSize, test.order, atClkTck)
+		if got != test.want || ok != test.ok {
+			t.Errorf("%v: want %d, %t, got %d, %t", test.entries, test.want, test.ok, got, ok)
+		}
+	}
+}
+
+func TestClockTicks(t *testing.T) {
+	if hz := clockTicks(); hz <= 0 {
+		t.Errorf("want positive clock ticks, got %f", hz)
+	}
+}
@@ -0,0 +1,241 @@
+// +build !nocloud
+
+package collector
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
+)
+
+const (
+	cloudMetadataAddress = "http://169.254.169.254"
+	gceMetadataAddress   = "http://metadata.google.internal"
+	cloudMetadataTimeout = 2 * time.Second
+	// ec2TokenTTL is the lifetime in seconds requested for IMDSv2 tokens,
+	// which are fetched again with each refresh of the metadata.
+	ec2TokenTTL = "60"
+)
+
+var (
+	cloudCacheTTL = flag.Duration("collector.cloud.cache-ttl", time.Hour, "How long to cache instance metadata for the cloud collector.")
+)
+
+type cloudInfo struct {
+	provider, instanceID, instanceType, region, zone string
+}
+
+// A cloudProvider fetches instance metadata from the endpoint at address.
+type cloudProvider struct {
+	name    string
+	address string
+	fetch   func(client *http.Client, address string) (cloudInfo, error)
+}
+
+type cloudCollector struct {
+	config    Config
+	client    *http.Client
+	providers []cloudProvider
+	metric    *prometheus.GaugeVec
+
+	mtx     sync.Mutex
+	info    cloudInfo
+	err     error
+	fetched time.Time
+}
+
+func init() {
+	registerCollector("cloud", defaultDisabled, NewCloudCollector)
+}
+
+// NewCloudCollector returns a new Collector exposing the instance id, type,
+// region and zone of the cloud instance the node is running on. EC2, GCE,
+// Azure and OpenStack are detected automatically.
+func NewCloudCollector(config Config) (Collector, error) {
+	return &cloudCollector{
+		config: config,
+		client: &http.Client{Timeout: cloudMetadataTimeout},
+		// The providers sharing the link-local address are tried with
+		// their specific paths first, EC2's are the most generic.
+		providers: []cloudProvider{
+			{"gce", gceMetadataAddress, fetchGCEMetadata},
+			{"azure", cloudMetadataAddress, fetchAzureMetadata},
+			{"openstack", cloudMetadataAddress, fetchOpenStackMetadata},
+			{"ec2", cloudMetadataAddress, fetchEC2Metadata},
+		},
+		metric: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Name:      "cloud_info",
+				Help:      "Instance metadata of the cloud provider the node runs on.",
+			},
+			[]string{"provider", "instance_id", "instance_type", "region", "availability_zone"},
+		),
+	}, nil
+}
+
+func (c *cloudCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	info, err := c.getInfo()
+	if err != nil {
+		return err
+	}
+	c.metric.Reset()
+	c.metric.WithLabelValues(info.provider, info.instanceID, info.instanceType, info.region, info.zone).Set(1)
+	c.metric.Collect(ch)
+	return nil
+}
+
+// getInfo returns the cached instance metadata, fetching it again once it
+// is older than --collector.cloud.cache-ttl. Not finding any provider is
+// cached as well, so that scrapes off the cloud don't wait for the timeouts
+// of all metadata endpoints.
+func (c *cloudCollector) getInfo() (cloudInfo, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if !c.fetched.IsZero() && time.Since(c.fetched) < *cloudCacheTTL {
+		return c.info, c.err
+	}
+	c.info, c.err, c.fetched = cloudInfo{}, fmt.Errorf("no cloud metadata endpoint found"), time.Now()
+	for _, p := range c.providers {
+		info, err := p.fetch(c.client, p.address)
+		if err != nil {
+			log.Debugf("No %s metadata: %s", p.name, err)
+			continue
+		}
+		info.provider = p.name
+		c.info, c.err = info, nil
+		break
+	}
+	return c.info, c.err
+}
+
+func getMetadata(client *http.Client, url string, header map[string]string) (string, error) {
+	return requestMetadata(client, "GET", url, header)
+}
+
+func requestMetadata(client *http.Client, method, url string, header map[string]string) (string, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+func fetchEC2Metadata(client *http.Client, address string) (info cloudInfo, err error) {
+	// Instances requiring IMDSv2 only answer requests with a session token,
+	// others without IMDSv2 are asked without one.
+	var header map[string]string
+	token, err := requestMetadata(client, "PUT", address+"/latest/api/token",
+		map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": ec2TokenTTL})
+	if err != nil {
+		log.Debugf("No EC2 metadata token, trying without: %s", err)
+	} else {
+		header = map[string]string{"X-aws-ec2-metadata-token": token}
+	}
+
+	base := address + "/latest/meta-data/"
+	if info.instanceID, err = getMetadata(client, base+"instance-id", header); err != nil {
+		return info, err
+	}
+	if info.instanceType, err = getMetadata(client, base+"instance-type", header); err != nil {
+		return info, err
+	}
+	if info.zone, err = getMetadata(client, base+"placement/availability-zone", header); err != nil {
+		return info, err
+	}
+	// us-east-1a is in us-east-1.
+	info.region = strings.TrimRight(info.zone, "abcdefghijklmnopqrstuvwxyz")
+	return info, nil
+}
+
+func fetchGCEMetadata(client *http.Client, address string) (info cloudInfo, err error) {
+	var (
+		base   = address + "/computeMetadata/v1/instance/"
+		header = map[string]string{"Metadata-Flavor": "Google"}
+	)
+	if info.instanceID, err = getMetadata(client, base+"id", header); err != nil {
+		return info, err
+	}
+	// Machine type and zone are returned as projects/123/zones/us-central1-a.
+	machineType, err := getMetadata(client, base+"machine-type", header)
+	if err != nil {
+		return info, err
+	}
+	info.instanceType = machineType[strings.LastIndex(machineType, "/")+1:]
+	zone, err := getMetadata(client, base+"zone", header)
+	if err != nil {
+		return info, err
+	}
+	info.zone = zone[strings.LastIndex(zone, "/")+1:]
+	if i := strings.LastIndex(info.zone, "-"); i > 0 {
+		info.region = info.zone[:i]
+	}
+	return info, nil
+}
+
+func fetchAzureMetadata(client *http.Client, address string) (info cloudInfo, err error) {
+	body, err := getMetadata(client, address+"/metadata/instance/compute?api-version=2017-08-01&format=json",
+		map[string]string{"Metadata": "true"})
+	if err != nil {
+		return info, err
+	}
+	var compute struct {
+		VMID     string `json:"vmId"`
+		VMSize   string `json:"vmSize"`
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+	}
+	if err := json.Unmarshal([]byte(body), &compute); err != nil {
+		return info, err
+	}
+	return cloudInfo{
+		instanceID:   compute.VMID,
+		instanceType: compute.VMSize,
+		region:       compute.Location,
+		zone:         compute.Zone,
+	}, nil
+}
+
+func fetchOpenStackMetadata(client *http.Client, address string) (info cloudInfo, err error) {
+	body, err := getMetadata(client, address+"/openstack/latest/meta_data.json", nil)
+	if err != nil {
+		return info, err
+	}
+	var metadata struct {
+		UUID             string `json:"uuid"`
+		AvailabilityZone string `json:"availability_zone"`
+	}
+	if err := json.Unmarshal([]byte(body), &metadata); err != nil {
+		return info, err
+	}
+	info = cloudInfo{instanceID: metadata.UUID, zone: metadata.AvailabilityZone}
+	// Flavors are only available through the EC2 compatible API.
+	info.instanceType, _ = getMetadata(client, address+"/latest/meta-data/instance-type", nil)
+	return info, nil
+}
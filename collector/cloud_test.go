@@ -0,0 +1,119 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCloudMetadata(t *testing.T) {
+	ec2 := map[string]string{
+		"/latest/meta-data/instance-id":                 "i-0b22a22eec53b9321",
+		"/latest/meta-data/instance-type":               "m4.large",
+		"/latest/meta-data/placement/availability-zone": "eu-west-1b",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// IMDSv2 only.
+		if r.URL.Path == "/latest/api/token" && r.Method == "PUT" {
+			if r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+				http.Error(w, "missing TTL", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("AQAEAFTNrA4eEGx0AQgJ1arIq"))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "AQAEAFTNrA4eEGx0AQgJ1arIq" {
+			http.Error(w, "missing token", http.StatusUnauthorized)
+			return
+		}
+		v, ok := ec2[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(v))
+	}))
+	defer server.Close()
+
+	c := &cloudCollector{
+		client: http.DefaultClient,
+		providers: []cloudProvider{
+			{"azure", server.URL, fetchAzureMetadata},
+			{"openstack", server.URL, fetchOpenStackMetadata},
+			{"ec2", server.URL, fetchEC2Metadata},
+		},
+	}
+	info, err := c.getInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := cloudInfo{
+		provider:     "ec2",
+		instanceID:   "i-0b22a22eec53b9321",
+		instanceType: "m4.large",
+		region:       "eu-west-1",
+		zone:         "eu-west-1b",
+	}
+	if want != info {
+		t.Errorf("want cloud info %+v, got %+v", want, info)
+	}
+
+	// Served from the cache now.
+	server.Close()
+	if _, err := c.getInfo(); err != nil {
+		t.Errorf("want cached cloud info, got %s", err)
+	}
+}
+
+func TestCloudMetadataNotFound(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	c := &cloudCollector{
+		client:    http.DefaultClient,
+		providers: []cloudProvider{{"ec2", server.URL, fetchEC2Metadata}},
+	}
+	if _, err := c.getInfo(); err == nil {
+		t.Fatal("want error without metadata")
+	}
+	n := requests
+	// The failure is cached as well.
+	if _, err := c.getInfo(); err == nil {
+		t.Error("want cached error without metadata")
+	}
+	if requests != n {
+		t.Errorf("want no requests for the cached failure, got %d", requests-n)
+	}
+}
+
+func TestGCEMetadata(t *testing.T) {
+	gce := map[string]string{
+		"/computeMetadata/v1/instance/id":           "4520031799277581759",
+		"/computeMetadata/v1/instance/machine-type": "projects/421227/machineTypes/n1-standard-4",
+		"/computeMetadata/v1/instance/zone":         "projects/421227/zones/us-central1-f",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(gce[r.URL.Path]))
+	}))
+	defer server.Close()
+
+	info, err := fetchGCEMetadata(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "n1-standard-4", info.instanceType; want != got {
+		t.Errorf("want instance type %s, got %s", want, got)
+	}
+	if want, got := "us-central1", info.region; want != got {
+		t.Errorf("want region %s, got %s", want, got)
+	}
+}
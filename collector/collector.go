@@ -2,17 +2,77 @@
 package collector
 
 import (
+	"flag"
+	"fmt"
+	"sort"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
 )
 
-const Namespace = "node"
+// Namespace is the prefix of all metric names. Collectors must only read it
+// once flags are parsed.
+var Namespace = "node"
+
+func init() {
+	flag.StringVar(&Namespace, "collector.namespace", Namespace, "Prefix of all metric names, e.g. to tell apart the metrics of several exporter forks.")
+}
 
 var Factories = make(map[string]func(Config) (Collector, error))
 
+const (
+	defaultEnabled  = true
+	defaultDisabled = false
+)
+
+// registerCollector adds the factory of a collector. Cheap collectors useful
+// on any host are registered defaultEnabled and added to DefaultCollectors,
+// expensive or optional ones are registered defaultDisabled and only run
+// when enabled explicitly.
+func registerCollector(name string, isDefault bool, factory func(Config) (Collector, error)) {
+	Factories[name] = factory
+	if isDefault {
+		DefaultCollectors = append(DefaultCollectors, name)
+		sort.Strings(DefaultCollectors)
+	}
+}
+
+// notApplicableError is returned by the constructor of a collector whose
+// data source doesn't exist on the host, like a driver that isn't loaded or a
+// command that isn't installed.
+type notApplicableError struct {
+	reason string
+}
+
+func (e notApplicableError) Error() string {
+	return "not applicable: " + e.reason
+}
+
+// NotApplicable returns the error a collector constructor returns when the
+// host lacks its data source, with the formatted reason.
+func NotApplicable(format string, args ...interface{}) error {
+	return notApplicableError{reason: fmt.Sprintf(format, args...)}
+}
+
+// IsNotApplicable reports whether err was returned by a collector constructor
+// because the host lacks its data source. Such collectors are left out
+// rather than failing on every scrape, so that collectors for hardware or
+// software found on some hosts only can be enabled everywhere.
+func IsNotApplicable(err error) bool {
+	_, ok := err.(notApplicableError)
+	return ok
+}
+
 // Interface a collector has to implement.
 type Collector interface {
-	// Get new metrics and expose them via prometheus registry.
-	Update(ch chan<- prometheus.Metric) (err error)
+	// Get new metrics and expose them via prometheus registry. Collectors
+	// should send const metrics of descs built by their constructor rather
+	// than keep metric state, so that a scrape only holds what was read.
+	// ctx is done once the collector timed out or the scrape was cancelled,
+	// after which its metrics are discarded. Files of /proc read by
+	// several collectors should be read through readProcFile with ctx, so
+	// they are read once per scrape.
+	Update(ctx context.Context, ch chan<- prometheus.Metric) (err error)
 }
 
 // TODO: Instead of periodically call Update, a Collector could be implemented
@@ -21,7 +81,13 @@ type Collector interface {
 // actually be better to do them proactively before scraping to minimize scrape
 // time.)
 
+// Config holds the options of the collectors. Collectors read their options
+// through Options.
 type Config struct {
-	Config     map[string]string `json:"config"`
+	// Config holds collector options, keyed <collector>_<option>.
+	Config map[string]string `json:"config"`
+	// Collectors holds the options of each collector by collector name.
+	Collectors map[string]Options `json:"collectors"`
+	// Attributes are exported as labels by the attributes collector.
 	Attributes map[string]string `json:"attributes"`
 }
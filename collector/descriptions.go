@@ -0,0 +1,43 @@
+package collector
+
+// Descriptions holds a one-line description of each collector, printed by
+// -collectors.list. Collectors added by plugins may add theirs.
+var Descriptions = map[string]string{
+	"attributes":     "Exposes attributes from the configuration file and --collector.attributes.file.",
+	"balloon":        "Exposes the virtio balloon size and inflate/deflate activity of KVM guests.",
+	"bonding":        "Exposes the number of configured and active slaves of Linux bonding interfaces.",
+	"certificate":    "Exposes the expiry of PEM certificates listed in --collector.certificate.paths.",
+	"cgroup":         "Exposes CPU, memory, I/O and pid usage of cgroups in the unified (v2) hierarchy.",
+	"cloud":          "Exposes instance id, type, region and zone from the EC2, GCE, Azure or OpenStack metadata service.",
+	"diskstats":      "Exposes disk I/O statistics from /proc/diskstats.",
+	"exec":           "Exposes metrics printed in the text format by commands listed in --collector.exec.commands.",
+	"filesystem":     "Exposes filesystem statistics, such as disk space used.",
+	"gmond":          "Exposes statistics from a local gmond (Ganglia), labelled by cluster and host.",
+	"inotify":        "Exposes inotify instance and watch usage per user and the corresponding kernel limits.",
+	"interrupts":     "Exposes detailed interrupts statistics from /proc/interrupts.",
+	"kvm":            "Exposes host-wide KVM statistics from /sys/kernel/debug/kvm.",
+	"lastlogin":      "Exposes the last time there was a login.",
+	"libvirt":        "Exposes state, CPU, memory, block and network statistics of libvirt domains via virsh.",
+	"limits":         "Exposes kernel-wide resource limits, such as pid_max and file-max, next to their current usage.",
+	"loadavg":        "Exposes load average.",
+	"megacli":        "Exposes RAID statistics from MegaCLI.",
+	"meminfo":        "Exposes memory statistics from /proc/meminfo.",
+	"netdev":         "Exposes network interface statistics from netlink or /proc/net/dev, such as bytes transferred.",
+	"netstat":        "Exposes network statistics from /proc/net/netstat.",
+	"ntp":            "Exposes time drift from an NTP server.",
+	"pathsize":       "Exposes the total size, file count and newest mtime of paths listed in --collector.pathsize.paths.",
+	"raspberrypi":    "Exposes the SoC temperature, core voltage and throttling status of Raspberry Pis via vcgencmd.",
+	"pf":             "Exposes the state table, counters, limits and queues of the pf firewall via pfctl.",
+	"procgroup":      "Exposes CPU, memory, fd and thread usage of process groups defined in --collector.procgroup.groups.",
+	"runit":          "Exposes service status from runit.",
+	"smc":            "Exposes temperatures, fan speeds and power draw read from the SMC of Macs.",
+	"stat":           "Exposes CPU usage, boot time, forks, interrupts and softirqs from /proc/stat.",
+	"sysctl":         "Exposes the numeric values of sysctl keys listed in --collector.sysctl.keys.",
+	"taskstats":      "Exposes CPU, block I/O and swap-in delays of processes from the taskstats netlink interface.",
+	"textfile":       "Exposes statistics read from files in --collector.textfile.directory.",
+	"time":           "Exposes the current system time.",
+	"users":          "Exposes CPU, memory and process counts summed up per user.",
+	"virtualization": "Exposes whether the node runs in a container or virtual machine, and which one.",
+	"vmware":         "Exposes ballooned and swapped memory and resource limits of VMware guests via vmware-toolbox-cmd.",
+	"xen":            "Exposes per-domain statistics of a Xen dom0 through xentop.",
+}
@@ -1,38 +1,51 @@
 // +build !nodiskstats
+// +build linux freebsd openbsd netbsd dragonfly
 
 package collector
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
-	"io"
-	"os"
 	"regexp"
-	"strconv"
-	"strings"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
 )
 
 const (
-	procDiskStats = "/proc/diskstats"
 	diskSubsystem = "disk"
 )
 
 var (
-	ignoredDevices = flag.String("collector.diskstats.ignored-devices", "^(ram|loop|fd|(h|s|v|xv)d[a-z])\\d+$", "Regexp of devices to ignore for diskstats.")
+	ignoredDevices = flag.String("collector.diskstats.ignored-devices", "^(ram|loop|fd|(h|s|v|xv)d[a-z]|nvme\\d+n\\d+p|mmcblk\\d+p)\\d+$", "Regexp of devices to ignore for diskstats, by default RAM disks, loop devices, floppies and partitions.")
 )
 
+// Indexes of the metrics of the collector, in the order of the stats of
+// /proc/diskstats, that other platforms have an equivalent of.
+const (
+	diskReadsCompleted  = 0
+	diskSectorsRead     = 2
+	diskReadTimeMs      = 3
+	diskWritesCompleted = 4
+	diskSectorsWritten  = 6
+	diskWriteTimeMs     = 7
+	diskIONow           = 8
+	diskIOTimeMs        = 9
+)
+
+// diskSectorSize is the unit of the sectors read and written.
+const diskSectorSize = 512
+
 type diskstatsCollector struct {
 	config                Config
 	ignoredDevicesPattern *regexp.Regexp
-	metrics               []prometheus.Collector
+	metrics               []typedDesc
+	removed               *removedEntities
 }
 
 func init() {
-	Factories["diskstats"] = NewDiskstatsCollector
+	registerCollector("diskstats", defaultEnabled, NewDiskstatsCollector)
 }
 
 // Takes a config struct and prometheus registry and returns a new Collector exposing
@@ -40,178 +53,52 @@ func init() {
 func NewDiskstatsCollector(config Config) (Collector, error) {
 	var diskLabelNames = []string{"device"}
 
+	pattern, err := regexp.Compile(*ignoredDevices)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignored devices pattern: %s", err)
+	}
 	return &diskstatsCollector{
 		config:                config,
-		ignoredDevicesPattern: regexp.MustCompile(*ignoredDevices),
+		ignoredDevicesPattern: pattern,
+		removed:               newRemovedEntities(diskSubsystem, "Number of disk devices that were removed."),
 		// Docs from https://www.kernel.org/doc/Documentation/iostats.txt
-		metrics: []prometheus.Collector{
-			prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Namespace: Namespace,
-					Subsystem: diskSubsystem,
-					Name:      "reads_completed",
-					Help:      "The total number of reads completed successfully.",
-				},
-				diskLabelNames,
-			),
-			prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Namespace: Namespace,
-					Subsystem: diskSubsystem,
-					Name:      "reads_merged",
-					Help:      "The number of reads merged. See https://www.kernel.org/doc/Documentation/iostats.txt.",
-				},
-				diskLabelNames,
-			),
-			prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Namespace: Namespace,
-					Subsystem: diskSubsystem,
-					Name:      "sectors_read",
-					Help:      "The total number of sectors read successfully.",
-				},
-				diskLabelNames,
-			),
-			prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Namespace: Namespace,
-					Subsystem: diskSubsystem,
-					Name:      "read_time_ms",
-					Help:      "The total number of milliseconds spent by all reads.",
-				},
-				diskLabelNames,
-			),
-			prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Namespace: Namespace,
-					Subsystem: diskSubsystem,
-					Name:      "writes_completed",
-					Help:      "The total number of writes completed successfully.",
-				},
-				diskLabelNames,
-			),
-			prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Namespace: Namespace,
-					Subsystem: diskSubsystem,
-					Name:      "writes_merged",
-					Help:      "The number of writes merged. See https://www.kernel.org/doc/Documentation/iostats.txt.",
-				},
-				diskLabelNames,
-			),
-			prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Namespace: Namespace,
-					Subsystem: diskSubsystem,
-					Name:      "sectors_written",
-					Help:      "The total number of sectors written successfully.",
-				},
-				diskLabelNames,
-			),
-			prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Namespace: Namespace,
-					Subsystem: diskSubsystem,
-					Name:      "write_time_ms",
-					Help:      "This is the total number of milliseconds spent by all writes.",
-				},
-				diskLabelNames,
-			),
-			prometheus.NewGaugeVec(
-				prometheus.GaugeOpts{
-					Namespace: Namespace,
-					Subsystem: diskSubsystem,
-					Name:      "io_now",
-					Help:      "The number of I/Os currently in progress.",
-				},
-				diskLabelNames,
-			),
-			prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Namespace: Namespace,
-					Subsystem: diskSubsystem,
-					Name:      "io_time_ms",
-					Help:      "Milliseconds spent doing I/Os.",
-				},
-				diskLabelNames,
-			),
-			prometheus.NewCounterVec(
-				prometheus.CounterOpts{
-					Namespace: Namespace,
-					Subsystem: diskSubsystem,
-					Name:      "io_time_weighted",
-					Help:      "The weighted # of milliseconds spent doing I/Os. See https://www.kernel.org/doc/Documentation/iostats.txt.",
-				},
-				diskLabelNames,
-			),
+		metrics: []typedDesc{
+			newTypedDesc(diskSubsystem, "reads_completed", "The total number of reads completed successfully.", prometheus.CounterValue, diskLabelNames...),
+			newTypedDesc(diskSubsystem, "reads_merged", "The number of reads merged. See https://www.kernel.org/doc/Documentation/iostats.txt.", prometheus.CounterValue, diskLabelNames...),
+			newTypedDesc(diskSubsystem, "sectors_read", "The total number of sectors read successfully.", prometheus.CounterValue, diskLabelNames...),
+			newTypedDesc(diskSubsystem, "read_time_ms", "The total number of milliseconds spent by all reads.", prometheus.CounterValue, diskLabelNames...),
+			newTypedDesc(diskSubsystem, "writes_completed", "The total number of writes completed successfully.", prometheus.CounterValue, diskLabelNames...),
+			newTypedDesc(diskSubsystem, "writes_merged", "The number of writes merged. See https://www.kernel.org/doc/Documentation/iostats.txt.", prometheus.CounterValue, diskLabelNames...),
+			newTypedDesc(diskSubsystem, "sectors_written", "The total number of sectors written successfully.", prometheus.CounterValue, diskLabelNames...),
+			newTypedDesc(diskSubsystem, "write_time_ms", "This is the total number of milliseconds spent by all writes.", prometheus.CounterValue, diskLabelNames...),
+			newTypedDesc(diskSubsystem, "io_now", "The number of I/Os currently in progress.", prometheus.GaugeValue, diskLabelNames...),
+			newTypedDesc(diskSubsystem, "io_time_ms", "Milliseconds spent doing I/Os.", prometheus.CounterValue, diskLabelNames...),
+			newTypedDesc(diskSubsystem, "io_time_weighted", "The weighted # of milliseconds spent doing I/Os. See https://www.kernel.org/doc/Documentation/iostats.txt.", prometheus.CounterValue, diskLabelNames...),
 		},
 	}, nil
 }
 
-func (c *diskstatsCollector) Update(ch chan<- prometheus.Metric) (err error) {
-	diskStats, err := getDiskStats()
+func (c *diskstatsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	diskStats, err := getDiskStats(ctx)
 	if err != nil {
 		return fmt.Errorf("couldn't get diskstats: %s", err)
 	}
 
+	var devices []string
 	for dev, stats := range diskStats {
 		if c.ignoredDevicesPattern.MatchString(dev) {
-			glog.V(1).Infof("Ignoring device: %s", dev)
+			log.Debugf("Ignoring device: %s", dev)
 			continue
 		}
-
-		if len(stats) != len(c.metrics) {
-			return fmt.Errorf("invalid line for %s for %s", procDiskStats, dev)
-		}
+		devices = append(devices, dev)
 
 		for k, value := range stats {
-			v, err := strconv.ParseFloat(value, 64)
-			if err != nil {
-				return fmt.Errorf("invalid value %s in diskstats: %s", value, err)
-			}
-
-			if counter, ok := c.metrics[k].(*prometheus.CounterVec); ok {
-				counter.WithLabelValues(dev).Set(v)
-			} else if gauge, ok := c.metrics[k].(*prometheus.GaugeVec); ok {
-				gauge.WithLabelValues(dev).Set(v)
-			} else {
-				return fmt.Errorf("unexpected collector %d", k)
+			if k >= len(c.metrics) {
+				return fmt.Errorf("invalid diskstats for %s", dev)
 			}
+			ch <- c.metrics[k].mustNewConstMetric(float64(value), dev)
 		}
 	}
-	for _, c := range c.metrics {
-		c.Collect(ch)
-	}
+	ch <- c.removed.update(devices)
 	return err
 }
-
-func getDiskStats() (map[string]map[int]string, error) {
-	file, err := os.Open(procDiskStats)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	return parseDiskStats(file)
-}
-
-func parseDiskStats(r io.Reader) (map[string]map[int]string, error) {
-	var (
-		diskStats = map[string]map[int]string{}
-		scanner   = bufio.NewScanner(r)
-	)
-
-	for scanner.Scan() {
-		parts := strings.Fields(string(scanner.Text()))
-		if len(parts) < 4 { // we strip major, minor and dev
-			return nil, fmt.Errorf("invalid line in %s: %s", procDiskStats, scanner.Text())
-		}
-		dev := parts[2]
-		diskStats[dev] = map[int]string{}
-		for i, v := range parts[3:] {
-			diskStats[dev][i] = v
-		}
-	}
-
-	return diskStats, nil
-}
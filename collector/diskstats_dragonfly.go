@@ -0,0 +1,33 @@
+// +build !nodiskstats,dragonfly
+
+package collector
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+func getDiskStats(ctx context.Context) (map[string]map[int]uint64, error) {
+	data, err := unix.SysctlRaw("kern.devstat.all")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get kern.devstat.all: %s", err)
+	}
+	disks, err := parseSysctlDevstatDragonFly(data, nativeEndian())
+	if err != nil {
+		return nil, err
+	}
+	diskStats := map[string]map[int]uint64{}
+	for _, d := range disks {
+		diskStats[d.name] = map[int]uint64{
+			diskReadsCompleted:  d.reads,
+			diskSectorsRead:     d.readBytes / diskSectorSize,
+			diskWritesCompleted: d.writes,
+			diskSectorsWritten:  d.writeBytes / diskSectorSize,
+			diskIONow:           d.busy,
+			diskIOTimeMs:        uint64(d.busyTime * 1000),
+		}
+	}
+	return diskStats, nil
+}
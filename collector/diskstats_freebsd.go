@@ -0,0 +1,35 @@
+// +build !nodiskstats,freebsd
+
+package collector
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+func getDiskStats(ctx context.Context) (map[string]map[int]uint64, error) {
+	data, err := unix.SysctlRaw("kern.devstat.all")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get kern.devstat.all: %s", err)
+	}
+	disks, err := parseSysctlDevstatFreeBSD(data, nativeEndian())
+	if err != nil {
+		return nil, err
+	}
+	diskStats := map[string]map[int]uint64{}
+	for _, d := range disks {
+		diskStats[d.name] = map[int]uint64{
+			diskReadsCompleted:  d.reads,
+			diskSectorsRead:     d.readBytes / diskSectorSize,
+			diskReadTimeMs:      uint64(d.readTime * 1000),
+			diskWritesCompleted: d.writes,
+			diskSectorsWritten:  d.writeBytes / diskSectorSize,
+			diskWriteTimeMs:     uint64(d.writeTime * 1000),
+			diskIONow:           d.busy,
+			diskIOTimeMs:        uint64(d.busyTime * 1000),
+		}
+	}
+	return diskStats, nil
+}
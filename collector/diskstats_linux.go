@@ -0,0 +1,47 @@
+// +build !nodiskstats,linux
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+func getDiskStats(ctx context.Context) (map[string]map[int]uint64, error) {
+	data, err := readProcFile(ctx, "diskstats")
+	if err != nil {
+		return nil, err
+	}
+	return parseDiskStats(bytes.NewReader(data))
+}
+
+func parseDiskStats(r io.Reader) (map[string]map[int]uint64, error) {
+	var (
+		diskStats = map[string]map[int]uint64{}
+		scanner   = bufio.NewScanner(r)
+	)
+
+	for scanner.Scan() {
+		parts := strings.Fields(string(scanner.Text()))
+		if len(parts) < 4 { // we strip major, minor and dev
+			return nil, fmt.Errorf("invalid line in %s: %s", procFilePath("diskstats"), scanner.Text())
+		}
+		dev := parts[2]
+		stats := map[int]uint64{}
+		for i, v := range parts[3:] {
+			value, err := parseUint(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %s in diskstats: %s", v, err)
+			}
+			stats[i] = value
+		}
+		diskStats[dev] = stats
+	}
+
+	return diskStats, nil
+}
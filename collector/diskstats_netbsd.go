@@ -0,0 +1,33 @@
+// +build !nodiskstats,netbsd
+
+package collector
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+func getDiskStats(ctx context.Context) (map[string]map[int]uint64, error) {
+	data, err := unix.SysctlRaw("hw.iostats", sizeofIoSysctl)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get hw.iostats: %s", err)
+	}
+	disks, err := parseSysctlIostats(data, nativeEndian())
+	if err != nil {
+		return nil, err
+	}
+	diskStats := map[string]map[int]uint64{}
+	for _, d := range disks {
+		diskStats[d.name] = map[int]uint64{
+			diskReadsCompleted:  d.reads,
+			diskSectorsRead:     d.readBytes / diskSectorSize,
+			diskWritesCompleted: d.writes,
+			diskSectorsWritten:  d.writeBytes / diskSectorSize,
+			diskIONow:           d.busy,
+			diskIOTimeMs:        uint64(d.busyTime * 1000),
+		}
+	}
+	return diskStats, nil
+}
@@ -0,0 +1,37 @@
+// +build !nodiskstats,openbsd
+
+package collector
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+func getDiskStats(ctx context.Context) (map[string]map[int]uint64, error) {
+	count, err := unix.SysctlUint32("hw.diskcount")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get hw.diskcount: %s", err)
+	}
+	data, err := unix.SysctlRaw("hw.diskstats")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get hw.diskstats: %s", err)
+	}
+	disks, err := parseSysctlDiskstats(data, int(count), nativeEndian())
+	if err != nil {
+		return nil, err
+	}
+	diskStats := map[string]map[int]uint64{}
+	for _, d := range disks {
+		diskStats[d.name] = map[int]uint64{
+			diskReadsCompleted:  d.reads,
+			diskSectorsRead:     d.readBytes / diskSectorSize,
+			diskWritesCompleted: d.writes,
+			diskSectorsWritten:  d.writeBytes / diskSectorSize,
+			diskIONow:           d.busy,
+			diskIOTimeMs:        uint64(d.busyTime * 1000),
+		}
+	}
+	return diskStats, nil
+}
@@ -1,12 +1,15 @@
+// +build linux
+
 package collector
 
 import (
+	"bytes"
 	"os"
 	"testing"
 )
 
 func TestDiskStats(t *testing.T) {
-	file, err := os.Open("fixtures/diskstats")
+	file, err := os.Open("fixtures/proc/diskstats")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -17,11 +20,42 @@ func TestDiskStats(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if want, got := "25353629", diskStats["sda4"][0]; want != got {
-		t.Errorf("want diskstats sda4 %s, got %s", want, got)
+	if want, got := uint64(25353629), diskStats["sda4"][0]; want != got {
+		t.Errorf("want diskstats sda4 %d, got %d", want, got)
 	}
 
-	if want, got := "68", diskStats["mmcblk0p2"][10]; want != got {
-		t.Errorf("want diskstats mmcblk0p2 %s, got %s", want, got)
+	if want, got := uint64(68), diskStats["mmcblk0p2"][10]; want != got {
+		t.Errorf("want diskstats mmcblk0p2 %d, got %d", want, got)
 	}
 }
+
+func TestDiskStatsIgnoredDevices(t *testing.T) {
+	c, err := NewDiskstatsCollector(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern := c.(*diskstatsCollector).ignoredDevicesPattern
+	for dev, ignored := range map[string]bool{
+		"sda":       false,
+		"sda4":      true,
+		"xvda1":     true,
+		"nvme0n1":   false,
+		"nvme0n1p2": true,
+		"mmcblk0":   false,
+		"mmcblk0p2": true,
+		"dm-0":      false,
+		"loop0":     true,
+		"ram15":     true,
+	} {
+		if got := pattern.MatchString(dev); got != ignored {
+			t.Errorf("want %s ignored %t, got %t", dev, ignored, got)
+		}
+	}
+}
+
+func BenchmarkParseDiskStats(b *testing.B) {
+	benchmarkParser(b, "fixtures/proc/diskstats", func(r *bytes.Reader) error {
+		_, err := parseDiskStats(r)
+		return err
+	})
+}
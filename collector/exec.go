@@ -0,0 +1,250 @@
+// +build !noexec
+
+package collector
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/text"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
+)
+
+const execSubsystem = "exec"
+
+var (
+	execCommands       = flag.String("collector.exec.commands", "", "Comma-separated list of commands to run on each scrape, their output is parsed in the text exposition format.")
+	execTimeout        = flag.Duration("collector.exec.command-timeout", 10*time.Second, "Time after which commands of the exec collector are killed. Unlike the exec_timeout config option, which bounds the whole collector, it applies to each command.")
+	execMaxConcurrency = flag.Int("collector.exec.max-concurrency", 4, "Maximum number of commands the exec collector runs at the same time.")
+)
+
+type execCollector struct {
+	config         Config
+	commands       map[string][]string
+	timeout        time.Duration
+	maxConcurrency int
+
+	success, duration *prometheus.GaugeVec
+}
+
+type execResult struct {
+	families map[string]*dto.MetricFamily
+	err      error
+	duration time.Duration
+}
+
+func init() {
+	registerCollector("exec", defaultDisabled, NewExecCollector)
+}
+
+// execOptions are the options of the exec collector that aren't named
+// commands, including the timeout of the whole collector.
+var execOptions = map[string]bool{"commands": true, "command-timeout": true, "timeout": true, "max-concurrency": true, "enabled": true}
+
+// NewExecCollector returns a new Collector exposing the metrics printed by
+// external commands. Commands are taken from --collector.exec.commands and
+// the other options of the exec collector, named by the option. They are run
+// without a shell.
+func NewExecCollector(config Config) (Collector, error) {
+	options := config.Options("exec")
+	commands := map[string][]string{}
+	for _, command := range options.Strings("commands") {
+		if args := strings.Fields(command); len(args) > 0 {
+			commands[strings.Join(args, " ")] = args
+		}
+	}
+	for k, v := range options {
+		if execOptions[k] {
+			continue
+		}
+		if args := strings.Fields(v); len(args) > 0 {
+			commands[k] = args
+		}
+	}
+	if len(commands) == 0 {
+		return nil, fmt.Errorf("No commands specified, see --collector.exec.commands")
+	}
+	timeout, err := options.Duration("command-timeout")
+	if err != nil {
+		return nil, err
+	}
+	maxConcurrency, err := options.Int("max-concurrency")
+	if err != nil {
+		return nil, err
+	}
+	if maxConcurrency < 1 {
+		return nil, fmt.Errorf("--collector.exec.max-concurrency must be at least 1")
+	}
+
+	return &execCollector{
+		config:         config,
+		commands:       commands,
+		timeout:        timeout,
+		maxConcurrency: maxConcurrency,
+		success: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: execSubsystem,
+				Name:      "success",
+				Help:      "1 if the command ran successfully and its output was parsed, 0 otherwise.",
+			},
+			[]string{"command"},
+		),
+		duration: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: execSubsystem,
+				Name:      "duration_seconds",
+				Help:      "Time the command took to run in seconds.",
+			},
+			[]string{"command"},
+		),
+	}, nil
+}
+
+func (c *execCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	var (
+		wg      sync.WaitGroup
+		mtx     sync.Mutex
+		results = map[string]execResult{}
+		sem     = make(chan struct{}, c.maxConcurrency)
+	)
+	for name, args := range c.commands {
+		wg.Add(1)
+		go func(name string, args []string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result := runExecCommand(ctx, args, c.timeout)
+			mtx.Lock()
+			results[name] = result
+			mtx.Unlock()
+		}(name, args)
+	}
+	wg.Wait()
+
+	for name, result := range results {
+		c.duration.WithLabelValues(name).Set(result.duration.Seconds())
+		if result.err != nil {
+			log.Errorf("Error running %s: %s", name, result.err)
+			c.success.WithLabelValues(name).Set(0)
+			continue
+		}
+		c.success.WithLabelValues(name).Set(1)
+		for _, mf := range result.families {
+			for _, m := range metricFamilyToConstMetrics(mf) {
+				ch <- m
+			}
+		}
+	}
+	c.success.Collect(ch)
+	c.duration.Collect(ch)
+	return nil
+}
+
+// runExecCommand runs args, killing it after timeout or once ctx is done, and
+// parses its standard output.
+func runExecCommand(ctx context.Context, args []string, timeout time.Duration) (result execResult) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	begin := time.Now()
+	defer func() { result.duration = time.Since(begin) }()
+	if err := cmd.Start(); err != nil {
+		result.err = err
+		return result
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		<-done
+		if ctx.Err() == context.DeadlineExceeded {
+			result.err = fmt.Errorf("killed after %s", timeout)
+		} else {
+			result.err = fmt.Errorf("killed: %s", ctx.Err())
+		}
+		return result
+	}
+	if err != nil {
+		result.err = fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+		return result
+	}
+
+	var parser text.Parser
+	result.families, result.err = parser.TextToMetricFamilies(&stdout)
+	return result
+}
+
+// metricFamilyToConstMetrics converts parsed counters, gauges and untyped
+// metrics to const metrics. Other types are skipped.
+func metricFamilyToConstMetrics(mf *dto.MetricFamily) []prometheus.Metric {
+	var valueType prometheus.ValueType
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		valueType = prometheus.CounterValue
+	case dto.MetricType_GAUGE:
+		valueType = prometheus.GaugeValue
+	case dto.MetricType_UNTYPED:
+		valueType = prometheus.UntypedValue
+	default:
+		log.Debugf("Skipping %s of unsupported type %s", mf.GetName(), mf.GetType())
+		return nil
+	}
+
+	metrics := []prometheus.Metric{}
+	for _, m := range mf.GetMetric() {
+		names := make([]string, 0, len(m.GetLabel()))
+		labels := map[string]string{}
+		for _, l := range m.GetLabel() {
+			names = append(names, l.GetName())
+			labels[l.GetName()] = l.GetValue()
+		}
+		sort.Strings(names)
+		values := make([]string, len(names))
+		for i, name := range names {
+			values[i] = labels[name]
+		}
+
+		var value float64
+		switch valueType {
+		case prometheus.CounterValue:
+			value = m.GetCounter().GetValue()
+		case prometheus.GaugeValue:
+			value = m.GetGauge().GetValue()
+		default:
+			value = m.GetUntyped().GetValue()
+		}
+
+		help := mf.GetHelp()
+		if help == "" {
+			help = "Metric read from an exec collector command."
+		}
+		metric, err := prometheus.NewConstMetric(
+			prometheus.NewDesc(mf.GetName(), help, names, nil),
+			valueType, value, values...,
+		)
+		if err != nil {
+			log.Errorf("Skipping invalid metric %s: %s", mf.GetName(), err)
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+func TestRunExecCommand(t *testing.T) {
+	result := runExecCommand(context.Background(), []string{"echo", `vendor_raid_degraded{array="md0"} 1`}, time.Second)
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	mf, ok := result.families["vendor_raid_degraded"]
+	if !ok {
+		t.Fatalf("want metric vendor_raid_degraded, got %v", result.families)
+	}
+
+	metrics := metricFamilyToConstMetrics(mf)
+	if want, got := 1, len(metrics); want != got {
+		t.Fatalf("want %d metrics, got %d", want, got)
+	}
+	var m dto.Metric
+	if err := metrics[0].Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "md0", m.GetLabel()[0].GetValue(); want != got {
+		t.Errorf("want label array=%s, got %s", want, got)
+	}
+
+	if result := runExecCommand(context.Background(), []string{"sleep", "5"}, 10*time.Millisecond); result.err == nil {
+		t.Error("want error for command exceeding its timeout")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := runExecCommand(ctx, []string{"sleep", "5"}, time.Second); result.err == nil {
+		t.Error("want error for cancelled command")
+	}
+	if result := runExecCommand(context.Background(), []string{"false"}, time.Second); result.err == nil {
+		t.Error("want error for failing command")
+	}
+}
+
+func TestExecCommandTimeout(t *testing.T) {
+	config := Config{Config: map[string]string{"exec_timeout": "1m", "exec_command-timeout": "3s", "exec_uptime": "uptime"}}
+	c, err := NewExecCollector(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec := c.(*execCollector)
+	if want, got := 3*time.Second, exec.timeout; want != got {
+		t.Errorf("want command timeout %s, got %s", want, got)
+	}
+	if _, ok := exec.commands["timeout"]; ok {
+		t.Error("want the collector timeout not taken as a command")
+	}
+}
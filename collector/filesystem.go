@@ -1,38 +1,38 @@
 // +build !nofilesystem
+// +build linux darwin
 
 package collector
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
-	"os"
 	"regexp"
-	"strings"
-	"syscall"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
 )
 
 const (
-	procMounts          = "/proc/mounts"
 	filesystemSubsystem = "filesystem"
 )
 
 var (
 	ignoredMountPoints = flag.String("collector.filesystem.ignored-mount-points", "^/(sys|proc|dev)($|/)", "Regexp of mount points to ignore for filesystem collector.")
+	ignoredFSTypes     = flag.String("collector.filesystem.ignored-fs-types", "^(autofs|binfmt_misc|cgroup2?|configfs|debugfs|devpts|devtmpfs|fusectl|hugetlbfs|mqueue|overlay|proc|pstore|rpc_pipefs|securityfs|selinuxfs|squashfs|sysfs|tracefs)$", "Regexp of filesystem types to ignore for filesystem collector.")
 )
 
 type filesystemCollector struct {
 	config                    Config
 	ignoredMountPointsPattern *regexp.Regexp
+	ignoredFSTypesPattern     *regexp.Regexp
 
-	size, free, avail, files, filesFree *prometheus.GaugeVec
+	size, free, avail, files, filesFree typedDesc
+	removed                             *removedEntities
 }
 
 func init() {
-	Factories["filesystem"] = NewFilesystemCollector
+	registerCollector("filesystem", defaultEnabled, NewFilesystemCollector)
 }
 
 // Takes a config struct and prometheus registry and returns a new Collector exposing
@@ -40,99 +40,67 @@ func init() {
 func NewFilesystemCollector(config Config) (Collector, error) {
 	var filesystemLabelNames = []string{"filesystem"}
 
+	pattern, err := regexp.Compile(*ignoredMountPoints)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignored mount points pattern: %s", err)
+	}
+	fsTypesPattern, err := regexp.Compile(*ignoredFSTypes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignored filesystem types pattern: %s", err)
+	}
 	return &filesystemCollector{
-		config: config,
-		ignoredMountPointsPattern: regexp.MustCompile(*ignoredMountPoints),
-		size: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Namespace: Namespace,
-				Subsystem: filesystemSubsystem,
-				Name:      "size",
-				Help:      "Filesystem size in bytes.",
-			},
-			filesystemLabelNames,
-		),
-		free: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Namespace: Namespace,
-				Subsystem: filesystemSubsystem,
-				Name:      "free",
-				Help:      "Filesystem free space in bytes.",
-			},
-			filesystemLabelNames,
-		),
-		avail: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Namespace: Namespace,
-				Subsystem: filesystemSubsystem,
-				Name:      "avail",
-				Help:      "Filesystem space available to non-root users in bytes.",
-			},
-			filesystemLabelNames,
-		),
-		files: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Namespace: Namespace,
-				Subsystem: filesystemSubsystem,
-				Name:      "files",
-				Help:      "Filesystem total file nodes.",
-			},
-			filesystemLabelNames,
-		),
-		filesFree: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Namespace: Namespace,
-				Subsystem: filesystemSubsystem,
-				Name:      "files_free",
-				Help:      "Filesystem total free file nodes.",
-			},
-			filesystemLabelNames,
-		),
+		config:                    config,
+		ignoredMountPointsPattern: pattern,
+		ignoredFSTypesPattern:     fsTypesPattern,
+		size:                      newTypedDesc(filesystemSubsystem, "size", "Filesystem size in bytes.", prometheus.GaugeValue, filesystemLabelNames...),
+		free:                      newTypedDesc(filesystemSubsystem, "free", "Filesystem free space in bytes.", prometheus.GaugeValue, filesystemLabelNames...),
+		avail:                     newTypedDesc(filesystemSubsystem, "avail", "Filesystem space available to non-root users in bytes.", prometheus.GaugeValue, filesystemLabelNames...),
+		files:                     newTypedDesc(filesystemSubsystem, "files", "Filesystem total file nodes.", prometheus.GaugeValue, filesystemLabelNames...),
+		filesFree:                 newTypedDesc(filesystemSubsystem, "files_free", "Filesystem total free file nodes.", prometheus.GaugeValue, filesystemLabelNames...),
+		removed:                   newRemovedEntities(filesystemSubsystem, "Number of filesystems that were unmounted."),
 	}, nil
 }
 
 // Expose filesystem fullness.
-func (c *filesystemCollector) Update(ch chan<- prometheus.Metric) (err error) {
-	mps, err := mountPoints()
+func (c *filesystemCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	mounts, err := mountPoints()
 	if err != nil {
 		return err
 	}
-	for _, mp := range mps {
-		if c.ignoredMountPointsPattern.MatchString(mp) {
-			glog.V(1).Infof("Ignoring mount point: %s", mp)
+	var exported []string
+	for _, m := range mounts {
+		mp := m.mountPoint
+		if c.ignored(m) {
+			log.Debugf("Ignoring %s mount point: %s", m.fsType, mp)
 			continue
 		}
-		buf := new(syscall.Statfs_t)
-		err := syscall.Statfs(mp, buf)
+		exported = append(exported, mp)
+		stats, err := statFilesystem(mp)
 		if err != nil {
 			return fmt.Errorf("Statfs on %s returned %s", mp, err)
 		}
-		c.size.WithLabelValues(mp).Set(float64(buf.Blocks) * float64(buf.Bsize))
-		c.free.WithLabelValues(mp).Set(float64(buf.Bfree) * float64(buf.Bsize))
-		c.avail.WithLabelValues(mp).Set(float64(buf.Bavail) * float64(buf.Bsize))
-		c.files.WithLabelValues(mp).Set(float64(buf.Files))
-		c.filesFree.WithLabelValues(mp).Set(float64(buf.Ffree))
+		ch <- c.size.mustNewConstMetric(stats.size, mp)
+		ch <- c.free.mustNewConstMetric(stats.free, mp)
+		ch <- c.avail.mustNewConstMetric(stats.avail, mp)
+		ch <- c.files.mustNewConstMetric(stats.files, mp)
+		ch <- c.filesFree.mustNewConstMetric(stats.filesFree, mp)
 	}
-	c.size.Collect(ch)
-	c.free.Collect(ch)
-	c.avail.Collect(ch)
-	c.files.Collect(ch)
-	c.filesFree.Collect(ch)
+	ch <- c.removed.update(exported)
 	return err
 }
 
-func mountPoints() ([]string, error) {
-	file, err := os.Open(procMounts)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
+// ignored returns whether the mount point or the filesystem type of m are
+// ignored.
+func (c *filesystemCollector) ignored(m mount) bool {
+	return c.ignoredMountPointsPattern.MatchString(m.mountPoint) || c.ignoredFSTypesPattern.MatchString(m.fsType)
+}
 
-	mountPoints := []string{}
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		parts := strings.Fields(scanner.Text())
-		mountPoints = append(mountPoints, parts[1])
-	}
-	return mountPoints, nil
+type mount struct {
+	mountPoint, fsType string
+}
+
+// filesystemStats are the sizes in bytes and the file node counts of a
+// mounted filesystem.
+type filesystemStats struct {
+	size, free, avail, files, filesFree float64
 }
@@ -0,0 +1,46 @@
+// +build !nofilesystem,darwin
+
+package collector
+
+import (
+	"syscall"
+)
+
+// mntNoWait is MNT_NOWAIT of sys/mount.h.
+const mntNoWait = 2
+
+// mountPoints returns the mounted filesystems from getfsstat(2), without
+// waiting for the stats of unresponsive network filesystems.
+func mountPoints() ([]mount, error) {
+	n, err := syscall.Getfsstat(nil, mntNoWait)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]syscall.Statfs_t, n)
+	n, err = syscall.Getfsstat(buf, mntNoWait)
+	if err != nil {
+		return nil, err
+	}
+	mounts := make([]mount, 0, n)
+	for _, fs := range buf[:n] {
+		mounts = append(mounts, mount{
+			mountPoint: cString(fs.Mntonname[:]),
+			fsType:     cString(fs.Fstypename[:]),
+		})
+	}
+	return mounts, nil
+}
+
+func statFilesystem(mountPoint string) (filesystemStats, error) {
+	var buf syscall.Statfs_t
+	if err := syscall.Statfs(mountPoint, &buf); err != nil {
+		return filesystemStats{}, err
+	}
+	return filesystemStats{
+		size:      float64(buf.Blocks) * float64(buf.Bsize),
+		free:      float64(buf.Bfree) * float64(buf.Bsize),
+		avail:     float64(buf.Bavail) * float64(buf.Bsize),
+		files:     float64(buf.Files),
+		filesFree: float64(buf.Ffree),
+	}, nil
+}
@@ -0,0 +1,53 @@
+// +build !nofilesystem,linux
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// mountPoints returns the mount points of the host, as seen by init, falling
+// back to those of the exporter where the mounts of init can't be read.
+func mountPoints() ([]mount, error) {
+	file, err := os.Open(procFilePath("1/mounts"))
+	if os.IsNotExist(err) || os.IsPermission(err) {
+		file, err = os.Open(procFilePath("mounts"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseMounts(file)
+}
+
+func parseMounts(r io.Reader) ([]mount, error) {
+	mounts := []mount{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("invalid line in mounts: %s", scanner.Text())
+		}
+		mounts = append(mounts, mount{mountPoint: parts[1], fsType: parts[2]})
+	}
+	return mounts, scanner.Err()
+}
+
+func statFilesystem(mountPoint string) (filesystemStats, error) {
+	buf := new(syscall.Statfs_t)
+	if err := syscall.Statfs(rootfsFilePath(mountPoint), buf); err != nil {
+		return filesystemStats{}, err
+	}
+	return filesystemStats{
+		size:      float64(buf.Blocks) * float64(buf.Bsize),
+		free:      float64(buf.Bfree) * float64(buf.Bsize),
+		avail:     float64(buf.Bavail) * float64(buf.Bsize),
+		files:     float64(buf.Files),
+		filesFree: float64(buf.Ffree),
+	}, nil
+}
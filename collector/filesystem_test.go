@@ -0,0 +1,36 @@
+// +build linux
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestFilesystemIgnored(t *testing.T) {
+	file, err := os.Open("fixtures/mounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	mounts, err := parseMounts(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewFilesystemCollector(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var exported []string
+	for _, m := range mounts {
+		if !c.(*filesystemCollector).ignored(m) {
+			exported = append(exported, m.mountPoint)
+		}
+	}
+	if want, got := "[/ /run /home]", fmt.Sprint(exported); want != got {
+		t.Errorf("want mount points %s, got %s", want, got)
+	}
+}
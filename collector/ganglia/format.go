@@ -17,8 +17,10 @@ type ExtraData struct {
 }
 
 type Metric struct {
-	Name  string  `xml:"NAME,attr"`
-	Value float64 `xml:"VAL,attr"`
+	Name string `xml:"NAME,attr"`
+	// Value is kept as string, as string metrics like os_name exist.
+	Value string `xml:"VAL,attr"`
+	Type  string `xml:"TYPE,attr"`
 	/*
 		Unit      string    `xml:"UNITS,attr"`
 		Slope     string    `xml:"SLOPE,attr"`
@@ -5,19 +5,21 @@ package collector
 import (
 	"bufio"
 	"encoding/xml"
+	"flag"
 	"fmt"
 	"io"
 	"net"
 	"regexp"
+	"strconv"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/node_exporter/collector/ganglia"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
 )
 
 const (
-	gangliaAddress   = "127.0.0.1:8649"
 	gangliaProto     = "tcp"
 	gangliaTimeout   = 30 * time.Second
 	gangliaNamespace = "ganglia"
@@ -29,10 +31,14 @@ type gmondCollector struct {
 }
 
 func init() {
-	Factories["gmond"] = NewGmondCollector
+	registerCollector("gmond", defaultDisabled, NewGmondCollector)
 }
 
-var illegalCharsRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+var (
+	gangliaAddress = flag.String("collector.gmond.address", "127.0.0.1:8649", "Address of the gmond to read metrics from.")
+
+	illegalCharsRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
 
 // Takes a config struct and prometheus registry and returns a new Collector scraping ganglia.
 func NewGmondCollector(config Config) (Collector, error) {
@@ -44,30 +50,38 @@ func NewGmondCollector(config Config) (Collector, error) {
 	return &c, nil
 }
 
-func (c *gmondCollector) Update(ch chan<- prometheus.Metric) (err error) {
-	conn, err := net.Dial(gangliaProto, gangliaAddress)
-	glog.V(1).Infof("gmondCollector Update")
+func (c *gmondCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	conn, err := net.Dial(gangliaProto, *gangliaAddress)
+	log.Debugf("gmondCollector Update")
 	if err != nil {
 		return fmt.Errorf("Can't connect to gmond: %s", err)
 	}
+	defer conn.Close()
 	conn.SetDeadline(time.Now().Add(gangliaTimeout))
 
-	ganglia := ganglia.Ganglia{}
-	decoder := xml.NewDecoder(bufio.NewReader(conn))
-	decoder.CharsetReader = toUtf8
-
-	err = decoder.Decode(&ganglia)
+	ganglia, err := parseGmond(bufio.NewReader(conn))
 	if err != nil {
-		return fmt.Errorf("Couldn't parse xml: %s", err)
+		return err
 	}
 
+	// Hosts leave the cluster, don't keep exporting removed ones.
+	for _, m := range c.metrics {
+		m.Reset()
+	}
 	for _, cluster := range ganglia.Clusters {
 		for _, host := range cluster.Hosts {
 
 			for _, metric := range host.Metrics {
-				name := illegalCharsRE.ReplaceAllString(metric.Name, "_")
-
-				c.setMetric(name, cluster.Name, metric)
+				if metric.Type == "string" {
+					continue
+				}
+				value, err := strconv.ParseFloat(metric.Value, 64)
+				if err != nil {
+					log.Debugf("Skipping %s with invalid value %q", metric.Name, metric.Value)
+					continue
+				}
+
+				c.setMetric(sanitizeGangliaName(metric.Name), cluster.Name, host.Name, value, metric)
 			}
 		}
 	}
@@ -77,7 +91,28 @@ func (c *gmondCollector) Update(ch chan<- prometheus.Metric) (err error) {
 	return err
 }
 
-func (c *gmondCollector) setMetric(name, cluster string, metric ganglia.Metric) {
+func parseGmond(r io.Reader) (ganglia.Ganglia, error) {
+	g := ganglia.Ganglia{}
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = toUtf8
+
+	if err := decoder.Decode(&g); err != nil {
+		return g, fmt.Errorf("Couldn't parse xml: %s", err)
+	}
+	return g, nil
+}
+
+// sanitizeGangliaName turns a ganglia metric name into a valid Prometheus
+// metric name.
+func sanitizeGangliaName(name string) string {
+	name = illegalCharsRE.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+func (c *gmondCollector) setMetric(name, cluster, host string, value float64, metric ganglia.Metric) {
 	if _, ok := c.metrics[name]; !ok {
 		var desc string
 		var title string
@@ -92,18 +127,18 @@ func (c *gmondCollector) setMetric(name, cluster string, metric ganglia.Metric)
 				break
 			}
 		}
-		glog.V(1).Infof("Register %s: %s", name, desc)
+		log.Debugf("Register %s: %s", name, desc)
 		c.metrics[name] = prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Namespace: gangliaNamespace,
 				Name:      name,
 				Help:      desc,
 			},
-			[]string{"cluster"},
+			[]string{"cluster", "host"},
 		)
 	}
-	glog.V(1).Infof("Set %s{cluster=%q}: %f", name, cluster, metric.Value)
-	c.metrics[name].WithLabelValues(cluster).Set(metric.Value)
+	log.Debugf("Set %s{cluster=%q,host=%q}: %f", name, cluster, host, value)
+	c.metrics[name].WithLabelValues(cluster, host).Set(value)
 }
 
 func toUtf8(charset string, input io.Reader) (io.Reader, error) {
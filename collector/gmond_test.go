@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGmond(t *testing.T) {
+	file, err := os.Open("fixtures/gmond.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	ganglia, err := parseGmond(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := ganglia.Clusters[0].Hosts
+	if want, got := 2, len(hosts); want != got {
+		t.Fatalf("want %d hosts, got %d", want, got)
+	}
+	if want, got := "web02.example.com", hosts[1].Name; want != got {
+		t.Errorf("want host %s, got %s", want, got)
+	}
+	if want, got := "Linux", hosts[0].Metrics[1].Value; want != got {
+		t.Errorf("want os_name %s, got %s", want, got)
+	}
+}
+
+func TestSanitizeGangliaName(t *testing.T) {
+	for name, want := range map[string]string{
+		"load_one":       "load_one",
+		"disk.sda.reads": "disk_sda_reads",
+		"5min_load":      "_5min_load",
+	} {
+		if got := sanitizeGangliaName(name); want != got {
+			t.Errorf("want sanitized %s, got %s", want, got)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// +build linux
+
+package collector
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/text"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+var updateGolden = flag.Bool("update", false, "Rewrite the golden files of the collector tests with the current output.")
+
+// fixtureFlags point the collectors at the fake /proc and /sys below
+// fixtures, so that their output doesn't depend on the host running the
+// tests.
+var fixtureFlags = map[string]string{
+	"path.procfs": "fixtures/proc",
+	"path.sysfs":  "fixtures/sys",
+}
+
+// descFields matches the String of a prometheus.Desc, the only way to get at
+// its name and help.
+var descFields = regexp.MustCompile(`^Desc\{fqName: ("(?:[^"\\]|\\.)*"), help: ("(?:[^"\\]|\\.)*"), `)
+
+// testCollectorGolden updates the named collector against the fixture trees, with
+// flags overriding the defaults of collector flags, and compares its output in
+// the text format with fixtures/golden/<name>.prom. Run the tests with
+// -update to rewrite the golden file after an intended change.
+func testCollectorGolden(t *testing.T, name string, flags map[string]string) {
+	defer setFlags(t, fixtureFlags)()
+	defer setFlags(t, flags)()
+	defer func(hz float64) { userHZ = hz }(userHZ)
+	userHZ = defaultUserHZ
+
+	factory, ok := Factories[name]
+	if !ok {
+		t.Fatalf("collector %s not registered", name)
+	}
+	c, err := factory(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan prometheus.Metric)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Update(WithScrapeCache(context.Background()), ch)
+		close(ch)
+	}()
+	var metrics []prometheus.Metric
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("update of %s collector failed: %s", name, err)
+	}
+	got, err := metricsText(metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("fixtures", "golden", name+".prom")
+	if *updateGolden {
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("couldn't read golden file, run the test with -update to create it: %s", err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("output of %s collector differs from %s, run the test with -update if that is intended:\n%s", name, golden, diffLines(want, got))
+	}
+}
+
+// setFlags sets the given flags and returns a function restoring them.
+func setFlags(t *testing.T, flags map[string]string) func() {
+	old := map[string]string{}
+	for name, value := range flags {
+		f := flag.Lookup(name)
+		if f == nil {
+			t.Fatalf("flag %s not defined", name)
+		}
+		old[name] = f.Value.String()
+		if err := flag.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return func() {
+		for name, value := range old {
+			flag.Set(name, value)
+		}
+	}
+}
+
+// metricsText returns metrics in the text format, with families sorted by
+// name and metrics sorted by labels, so that the output is stable.
+func metricsText(metrics []prometheus.Metric) ([]byte, error) {
+	families := map[string]*dto.MetricFamily{}
+	for _, m := range metrics {
+		fields := descFields.FindStringSubmatch(m.Desc().String())
+		if fields == nil {
+			return nil, fmt.Errorf("can't parse %s", m.Desc())
+		}
+		name, err := strconv.Unquote(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		help, err := strconv.Unquote(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		pb := &dto.Metric{}
+		if err := m.Write(pb); err != nil {
+			return nil, err
+		}
+		mf, ok := families[name]
+		if !ok {
+			mf = &dto.MetricFamily{Name: &name, Help: &help, Type: metricType(pb)}
+			families[name] = mf
+		}
+		mf.Metric = append(mf.Metric, pb)
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		mf := families[name]
+		sort.Sort(byLabels(mf.Metric))
+		if _, err := text.MetricFamilyToText(&buf, mf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func metricType(m *dto.Metric) *dto.MetricType {
+	switch {
+	case m.Counter != nil:
+		return dto.MetricType_COUNTER.Enum()
+	case m.Gauge != nil:
+		return dto.MetricType_GAUGE.Enum()
+	case m.Summary != nil:
+		return dto.MetricType_SUMMARY.Enum()
+	case m.Histogram != nil:
+		return dto.MetricType_HISTOGRAM.Enum()
+	}
+	return dto.MetricType_UNTYPED.Enum()
+}
+
+type byLabels []*dto.Metric
+
+func (m byLabels) Len() int           { return len(m) }
+func (m byLabels) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m byLabels) Less(i, j int) bool { return labelString(m[i]) < labelString(m[j]) }
+
+func labelString(m *dto.Metric) string {
+	pairs := make([]string, 0, len(m.Label))
+	for _, l := range m.Label {
+		pairs = append(pairs, l.GetName()+"="+strconv.Quote(l.GetValue()))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// diffLines lists the lines only in want with -, those only in got with +.
+func diffLines(want, got []byte) string {
+	count := func(b []byte) map[string]int {
+		lines := map[string]int{}
+		for _, l := range strings.Split(string(b), "\n") {
+			lines[l]++
+		}
+		return lines
+	}
+	wantLines, gotLines := count(want), count(got)
+	var diff []string
+	for l, n := range wantLines {
+		if gotLines[l] < n {
+			diff = append(diff, "- "+l)
+		}
+	}
+	for l, n := range gotLines {
+		if wantLines[l] < n {
+			diff = append(diff, "+ "+l)
+		}
+	}
+	sort.Strings(diff)
+	return strings.Join(diff, "\n")
+}
+
+func TestCollectorsGolden(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		flags map[string]string
+	}{
+		{name: "bonding"},
+		{name: "cgroup"},
+		{name: "diskstats"},
+		{name: "interrupts"},
+		{name: "kvm"},
+		{name: "loadavg"},
+		{name: "meminfo"},
+		{name: "netdev", flags: map[string]string{"collector.netdev.netlink": "false"}},
+		{name: "netstat"},
+		{name: "stat"},
+	} {
+		testCollectorGolden(t, test.name, test.flags)
+	}
+}
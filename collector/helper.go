@@ -1,11 +1,84 @@
 package collector
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
+	"os/exec"
+	"path"
 	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector/procfs"
+	"golang.org/x/net/context"
+)
+
+var (
+	procPath    = flag.String("path.procfs", "/proc", "procfs mount point.")
+	sysPath     = flag.String("path.sysfs", "/sys", "sysfs mount point.")
+	rootfsPath  = flag.String("path.rootfs", "/", "Mount point of the root filesystem of the host, prefixed to the mount points stat'ed by the filesystem collector.")
+	readWorkers = flag.Int("collector.read-workers", 8, "Number of files read at a time by collectors reading many small files of /sys, like kvm and cgroup.")
 )
 
+// procFilePath returns the path of name below -path.procfs.
+func procFilePath(name string) string {
+	return path.Join(*procPath, name)
+}
+
+// sysFilePath returns the path of name below -path.sysfs.
+func sysFilePath(name string) string {
+	return path.Join(*sysPath, name)
+}
+
+// rootfsFilePath returns the path of name below -path.rootfs.
+func rootfsFilePath(name string) string {
+	return path.Join(*rootfsPath, name)
+}
+
+// startCommand starts cmd and kills it once ctx is done. The returned wait
+// reaps cmd, returning the error of ctx if cmd was killed for it. Commands
+// run by collectors have to be tied to the scrape this way, as abandoned
+// updates would otherwise leave them running.
+func startCommand(ctx context.Context, cmd *exec.Cmd) (wait func() error, err error) {
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+	return func() error {
+		err := cmd.Wait()
+		close(done)
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}, nil
+}
+
+// commandOutput runs the named command, killing it once ctx is done, and
+// returns its standard output.
+func commandOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &stdout
+	wait, err := startCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err := wait(); err != nil {
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
 func splitToInts(str string, sep string) (ints []int, err error) {
 	for _, part := range strings.Split(str, sep) {
 		i, err := strconv.Atoi(part)
@@ -16,3 +89,94 @@ func splitToInts(str string, sep string) (ints []int, err error) {
 	}
 	return ints, nil
 }
+
+// parseUint parses the unsigned decimal integers most /proc files consist
+// of, which is several times faster than strconv.ParseFloat and doesn't
+// allocate. Anything it doesn't handle itself, like overflows, is left to
+// strconv.ParseUint.
+func parseUint(s string) (uint64, error) {
+	if len(s) == 0 || len(s) > 19 {
+		return strconv.ParseUint(s, 10, 64)
+	}
+	var n uint64
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return strconv.ParseUint(s, 10, 64)
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	return n, nil
+}
+
+func readUintFromFile(path string) (uint64, error) {
+	data, err := procfs.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := parseUint(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// typedDesc is a metric description together with the value type of its
+// metrics.
+type typedDesc struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+}
+
+func newTypedDesc(subsystem, name, help string, valueType prometheus.ValueType, labels ...string) typedDesc {
+	return typedDesc{
+		desc:      prometheus.NewDesc(prometheus.BuildFQName(Namespace, subsystem, name), help, labels, nil),
+		valueType: valueType,
+	}
+}
+
+func (d typedDesc) mustNewConstMetric(value float64, labels ...string) prometheus.Metric {
+	return prometheus.MustNewConstMetric(d.desc, d.valueType, value, labels...)
+}
+
+// removedEntities counts the entities of a collector, like devices or mount
+// points, that disappeared between two updates. Their series are gone with
+// the const metrics of the next update, the counter records that they were
+// removed.
+type removedEntities struct {
+	mu sync.Mutex
+	// last holds the entities of the last update, current is reused for
+	// the next one.
+	last, current map[string]bool
+	total         float64
+	desc          *prometheus.Desc
+}
+
+func newRemovedEntities(subsystem, help string) *removedEntities {
+	return &removedEntities{
+		desc: prometheus.NewDesc(prometheus.BuildFQName(Namespace, subsystem, "removed_total"), help, nil, nil),
+	}
+}
+
+// update records the entities seen by an update and returns the counter of
+// those removed so far.
+func (r *removedEntities) update(names []string) prometheus.Metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current == nil {
+		r.current = make(map[string]bool, len(names))
+	}
+	for name := range r.current {
+		delete(r.current, name)
+	}
+	for _, name := range names {
+		r.current[name] = true
+	}
+	for name := range r.last {
+		if !r.current[name] {
+			r.total++
+		}
+	}
+	r.last, r.current = r.current, r.last
+	return prometheus.MustNewConstMetric(r.desc, prometheus.CounterValue, r.total)
+}
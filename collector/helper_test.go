@@ -0,0 +1,109 @@
+package collector
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strconv"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+func TestRemovedEntities(t *testing.T) {
+	r := newRemovedEntities("test", "Number of test entities that were removed.")
+	for _, test := range []struct {
+		names []string
+		want  float64
+	}{
+		{[]string{"eth0", "eth1", "veth0"}, 0},
+		{[]string{"eth0", "eth1"}, 1},
+		{[]string{"eth0", "eth1", "veth1"}, 1},
+		{[]string{"eth0"}, 3},
+	} {
+		m := &dto.Metric{}
+		if err := r.update(test.names).Write(m); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.GetCounter().GetValue(); test.want != got {
+			t.Errorf("after %v: want %f removed, got %f", test.names, test.want, got)
+		}
+	}
+}
+
+func TestParseUint(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want uint64
+		err  bool
+	}{
+		{"0", 0, false},
+		{"25353629", 25353629, false},
+		{"18446744073709551615", 18446744073709551615, false},
+		{"18446744073709551616", 0, true},
+		{"", 0, true},
+		{"-1", 0, true},
+		{"12kB", 0, true},
+	} {
+		got, err := parseUint(test.in)
+		if (err != nil) != test.err {
+			t.Errorf("parseUint(%q): want error %t, got %v", test.in, test.err, err)
+			continue
+		}
+		if err == nil && got != test.want {
+			t.Errorf("parseUint(%q): want %d, got %d", test.in, test.want, got)
+		}
+	}
+}
+
+func BenchmarkParseUint(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := parseUint("10437182923"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseFloat(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := strconv.ParseFloat("10437182923", 64); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkParser runs parse on the contents of a fixture.
+func benchmarkParser(b *testing.B, fixture string, parse func(r *bytes.Reader) error) {
+	data, err := ioutil.ReadFile(fixture)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := parse(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCommandOutput(t *testing.T) {
+	out, err := commandOutput(context.Background(), "echo", "512 MB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "512 MB\n", string(out); want != got {
+		t.Errorf("want output %q, got %q", want, got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	if _, err := commandOutput(ctx, "sleep", "10"); err != context.DeadlineExceeded {
+		t.Errorf("want %v, got %v", context.DeadlineExceeded, err)
+	}
+	if d := time.Since(begin); d > 5*time.Second {
+		t.Errorf("want command killed at the deadline, took %s", d)
+	}
+}
@@ -0,0 +1,176 @@
+// +build !noinotify
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const (
+	inotifySubsystem = "inotify"
+)
+
+type inotifyCollector struct {
+	config Config
+
+	instances, watches       *prometheus.GaugeVec
+	maxInstances, maxWatches prometheus.Gauge
+	maxQueuedEvents          prometheus.Gauge
+}
+
+type inotifyUsage struct {
+	instances int
+	watches   int
+}
+
+func init() {
+	registerCollector("inotify", defaultDisabled, NewInotifyCollector)
+}
+
+// NewInotifyCollector returns a new Collector exposing inotify instance and
+// watch usage per user, next to the kernel limits for both.
+func NewInotifyCollector(config Config) (Collector, error) {
+	if _, err := os.Stat(procFilePath("sys/fs/inotify")); err != nil {
+		return nil, NotApplicable("kernel without inotify: %s", err)
+	}
+	return &inotifyCollector{
+		config: config,
+		instances: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: inotifySubsystem,
+				Name:      "instances",
+				Help:      "Number of inotify instances held by processes of a user.",
+			},
+			[]string{"uid"},
+		),
+		watches: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: inotifySubsystem,
+				Name:      "watches",
+				Help:      "Number of inotify watches held by processes of a user.",
+			},
+			[]string{"uid"},
+		),
+		maxInstances: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: inotifySubsystem,
+			Name:      "max_user_instances",
+			Help:      "Maximum number of inotify instances per user, from fs.inotify.max_user_instances.",
+		}),
+		maxWatches: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: inotifySubsystem,
+			Name:      "max_user_watches",
+			Help:      "Maximum number of inotify watches per user, from fs.inotify.max_user_watches.",
+		}),
+		maxQueuedEvents: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: inotifySubsystem,
+			Name:      "max_queued_events",
+			Help:      "Maximum number of queued events per inotify instance, from fs.inotify.max_queued_events.",
+		}),
+	}, nil
+}
+
+func (c *inotifyCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	limits := map[string]prometheus.Gauge{
+		"max_user_instances": c.maxInstances,
+		"max_user_watches":   c.maxWatches,
+		"max_queued_events":  c.maxQueuedEvents,
+	}
+	for name, gauge := range limits {
+		value, err := readUintFromFile(path.Join(procFilePath("sys/fs/inotify"), name))
+		if err != nil {
+			return fmt.Errorf("couldn't get inotify limit %s: %s", name, err)
+		}
+		gauge.Set(float64(value))
+		gauge.Collect(ch)
+	}
+
+	usage, err := getInotifyUsage(*procPath)
+	if err != nil {
+		return fmt.Errorf("couldn't get inotify usage: %s", err)
+	}
+	// Users come and go, don't keep exporting those without instances.
+	c.instances.Reset()
+	c.watches.Reset()
+	for uid, u := range usage {
+		c.instances.WithLabelValues(uid).Set(float64(u.instances))
+		c.watches.WithLabelValues(uid).Set(float64(u.watches))
+	}
+	c.instances.Collect(ch)
+	c.watches.Collect(ch)
+	return nil
+}
+
+// getInotifyUsage walks all processes below root and sums up their inotify
+// instances and watches by the uid owning the process.
+func getInotifyUsage(root string) (map[string]inotifyUsage, error) {
+	procs, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := map[string]inotifyUsage{}
+	for _, proc := range procs {
+		if _, err := strconv.Atoi(proc.Name()); err != nil || !proc.IsDir() {
+			continue
+		}
+		stat, ok := proc.Sys().(*syscall.Stat_t)
+		if !ok {
+			continue
+		}
+		uid := strconv.FormatUint(uint64(stat.Uid), 10)
+
+		// Processes may exit or deny access to their fds while we walk
+		// them, so errors for single processes are not fatal.
+		fdDir := path.Join(root, proc.Name(), "fd")
+		fds, err := ioutil.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(path.Join(fdDir, fd.Name()))
+			if err != nil || target != "anon_inode:inotify" {
+				continue
+			}
+			u := usage[uid]
+			u.instances++
+			if file, err := os.Open(path.Join(root, proc.Name(), "fdinfo", fd.Name())); err == nil {
+				watches, err := parseInotifyFdinfo(file)
+				file.Close()
+				if err == nil {
+					u.watches += watches
+				}
+			}
+			usage[uid] = u
+		}
+	}
+	return usage, nil
+}
+
+// parseInotifyFdinfo counts the watches listed in /proc/[pid]/fdinfo/[fd]
+// of an inotify instance. Each watch is listed on an "inotify wd:" line.
+func parseInotifyFdinfo(r io.Reader) (int, error) {
+	watches := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "inotify wd:") {
+			watches++
+		}
+	}
+	return watches, scanner.Err()
+}
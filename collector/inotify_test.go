@@ -0,0 +1,23 @@
+package collector
+
+import (
+	"os"
+	"testing"
+)
+
+func TestInotifyFdinfo(t *testing.T) {
+	file, err := os.Open("fixtures/inotify/fdinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	watches, err := parseInotifyFdinfo(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 3, watches; want != got {
+		t.Errorf("want inotify watches %d, got %d", want, got)
+	}
+}
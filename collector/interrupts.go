@@ -4,26 +4,23 @@ package collector
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
-	"os"
 	"strconv"
 	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
-)
-
-const (
-	procInterrupts = "/proc/interrupts"
+	"golang.org/x/net/context"
 )
 
 type interruptsCollector struct {
 	config Config
-	metric *prometheus.CounterVec
+	metric typedDesc
 }
 
 func init() {
-	Factories["interrupts"] = NewInterruptsCollector
+	registerCollector("interrupts", defaultDisabled, NewInterruptsCollector)
 }
 
 // Takes a config struct and prometheus registry and returns a new Collector exposing
@@ -31,55 +28,35 @@ func init() {
 func NewInterruptsCollector(config Config) (Collector, error) {
 	return &interruptsCollector{
 		config: config,
-		metric: prometheus.NewCounterVec(
-			prometheus.CounterOpts{
-				Namespace: Namespace,
-				Name:      "interrupts",
-				Help:      "Interrupt details from /proc/interrupts.",
-			},
-			[]string{"CPU", "type", "info", "devices"},
-		),
+		metric: newTypedDesc("", "interrupts", "Interrupt details from /proc/interrupts.", prometheus.CounterValue, "CPU", "type", "info", "devices"),
 	}, nil
 }
 
-func (c *interruptsCollector) Update(ch chan<- prometheus.Metric) (err error) {
-	interrupts, err := getInterrupts()
+func (c *interruptsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	interrupts, err := getInterrupts(ctx)
 	if err != nil {
 		return fmt.Errorf("Couldn't get interrupts: %s", err)
 	}
 	for name, interrupt := range interrupts {
 		for cpuNo, value := range interrupt.values {
-			fv, err := strconv.ParseFloat(value, 64)
-			if err != nil {
-				return fmt.Errorf("Invalid value %s in interrupts: %s", value, err)
-			}
-			labels := prometheus.Labels{
-				"CPU":     strconv.Itoa(cpuNo),
-				"type":    name,
-				"info":    interrupt.info,
-				"devices": interrupt.devices,
-			}
-			c.metric.With(labels).Set(fv)
+			ch <- c.metric.mustNewConstMetric(float64(value), strconv.Itoa(cpuNo), name, interrupt.info, interrupt.devices)
 		}
 	}
-	c.metric.Collect(ch)
 	return err
 }
 
 type interrupt struct {
 	info    string
 	devices string
-	values  []string
+	values  []uint64
 }
 
-func getInterrupts() (map[string]interrupt, error) {
-	file, err := os.Open(procInterrupts)
+func getInterrupts(ctx context.Context) (map[string]interrupt, error) {
+	data, err := readProcFile(ctx, "interrupts")
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	return parseInterrupts(file)
+	return parseInterrupts(bytes.NewReader(data))
 }
 
 func parseInterrupts(r io.Reader) (map[string]interrupt, error) {
@@ -89,7 +66,7 @@ func parseInterrupts(r io.Reader) (map[string]interrupt, error) {
 	)
 
 	if !scanner.Scan() {
-		return nil, fmt.Errorf("%s empty", procInterrupts)
+		return nil, fmt.Errorf("%s empty", procFilePath("interrupts"))
 	}
 	cpuNum := len(strings.Fields(string(scanner.Text()))) // one header per cpu
 
@@ -101,7 +78,14 @@ func parseInterrupts(r io.Reader) (map[string]interrupt, error) {
 		}
 		intName := parts[0][:len(parts[0])-1] // remove trailing :
 		intr := interrupt{
-			values: parts[1:cpuNum],
+			values: make([]uint64, cpuNum),
+		}
+		for i, v := range parts[1 : cpuNum+1] {
+			value, err := parseUint(v)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value %s in interrupts: %s", v, err)
+			}
+			intr.values[i] = value
 		}
 
 		if _, err := strconv.Atoi(intName); err == nil { // numeral interrupt
@@ -1,12 +1,15 @@
 package collector
 
 import (
+	"bytes"
 	"os"
 	"testing"
+
+	"golang.org/x/net/context"
 )
 
 func TestInterrupts(t *testing.T) {
-	file, err := os.Open("fixtures/interrupts")
+	file, err := os.Open("fixtures/proc/interrupts")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -17,7 +20,31 @@ func TestInterrupts(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if want, got := "5031", interrupts["NMI"].values[1]; want != got {
-		t.Errorf("want interrupts %s, got %s", want, got)
+	if want, got := uint64(5031), interrupts["NMI"].values[1]; want != got {
+		t.Errorf("want interrupts %d, got %d", want, got)
+	}
+
+	if want, got := 4, len(interrupts["NMI"].values); want != got {
+		t.Errorf("want interrupts of %d CPUs, got %d", want, got)
+	}
+}
+
+func TestInterruptsProcPath(t *testing.T) {
+	defer func(old string) { *procPath = old }(*procPath)
+	*procPath = "fixtures/proc"
+
+	interrupts, err := getInterrupts(context.Background())
+	if err != nil {
+		t.Fatal(err)
 	}
+	if want, got := uint64(5031), interrupts["NMI"].values[1]; want != got {
+		t.Errorf("want interrupts %d, got %d", want, got)
+	}
+}
+
+func BenchmarkParseInterrupts(b *testing.B) {
+	benchmarkParser(b, "fixtures/proc/interrupts", func(r *bytes.Reader) error {
+		_, err := parseInterrupts(r)
+		return err
+	})
 }
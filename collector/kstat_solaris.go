@@ -0,0 +1,108 @@
+// +build solaris,cgo
+
+package collector
+
+/*
+#cgo LDFLAGS: -lkstat
+#include <kstat.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// kstatCtl is an open kstat(3KSTAT) chain. It is opened per update, so that
+// CPUs and datasets added since are seen.
+type kstatCtl struct {
+	kc *C.kstat_ctl_t
+}
+
+func openKstat() (*kstatCtl, error) {
+	kc, err := C.kstat_open()
+	if kc == nil {
+		return nil, fmt.Errorf("kstat_open failed: %s", err)
+	}
+	return &kstatCtl{kc: kc}, nil
+}
+
+func (k *kstatCtl) close() {
+	C.kstat_close(k.kc)
+}
+
+// kstat is a read kstat of a kstatCtl, valid until it is closed.
+type kstat struct {
+	ks *C.kstat_t
+}
+
+// lookup returns the read kstat module:instance:name.
+func (k *kstatCtl) lookup(module string, instance int, name string) (kstat, error) {
+	cmodule, cname := C.CString(module), C.CString(name)
+	defer C.free(unsafe.Pointer(cmodule))
+	defer C.free(unsafe.Pointer(cname))
+	ks, err := C.kstat_lookup(k.kc, cmodule, C.int(instance), cname)
+	if ks == nil {
+		return kstat{}, fmt.Errorf("no kstat %s:%d:%s: %s", module, instance, name, err)
+	}
+	if err := k.read(ks); err != nil {
+		return kstat{}, err
+	}
+	return kstat{ks: ks}, nil
+}
+
+// module returns the read kstats of module, like cpu_stat with one per CPU.
+func (k *kstatCtl) module(module string) ([]kstat, error) {
+	var kstats []kstat
+	for ks := k.kc.kc_chain; ks != nil; ks = ks.ks_next {
+		if C.GoString(&ks.ks_module[0]) != module {
+			continue
+		}
+		if err := k.read(ks); err != nil {
+			return nil, err
+		}
+		kstats = append(kstats, kstat{ks: ks})
+	}
+	return kstats, nil
+}
+
+func (k *kstatCtl) read(ks *C.kstat_t) error {
+	if id, err := C.kstat_read(k.kc, ks, nil); id == -1 {
+		return fmt.Errorf("kstat_read of %s failed: %s", C.GoString(&ks.ks_name[0]), err)
+	}
+	return nil
+}
+
+// instance returns the instance number of k, e.g. the CPU of a cpu_stat.
+func (k kstat) instance() int {
+	return int(k.ks.ks_instance)
+}
+
+// data returns the data of a raw kstat, to be cast to its struct.
+func (k kstat) data() unsafe.Pointer {
+	return k.ks.ks_data
+}
+
+// named returns the value of the named statistic of a named kstat.
+func (k kstat) named(name string) (float64, error) {
+	ks := k.ks
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	kn := (*C.kstat_named_t)(C.kstat_data_lookup(ks, cname))
+	if kn == nil {
+		return 0, fmt.Errorf("no statistic %s in kstat %s", name, C.GoString(&ks.ks_name[0]))
+	}
+	value := unsafe.Pointer(&kn.value)
+	switch kn.data_type {
+	case C.KSTAT_DATA_INT32:
+		return float64(*(*int32)(value)), nil
+	case C.KSTAT_DATA_UINT32:
+		return float64(*(*uint32)(value)), nil
+	case C.KSTAT_DATA_INT64:
+		return float64(*(*int64)(value)), nil
+	case C.KSTAT_DATA_UINT64:
+		return float64(*(*uint64)(value)), nil
+	}
+	return 0, fmt.Errorf("statistic %s in kstat %s has non-numeric type %d", name, C.GoString(&ks.ks_name[0]), kn.data_type)
+}
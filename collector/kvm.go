@@ -0,0 +1,81 @@
+// +build !nokvm
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector/procfs"
+	"golang.org/x/net/context"
+)
+
+const (
+	kvmSubsystem = "kvm"
+)
+
+type kvmCollector struct {
+	config Config
+}
+
+func init() {
+	registerCollector("kvm", defaultDisabled, NewKVMCollector)
+}
+
+// NewKVMCollector returns a new Collector exposing the host-wide KVM
+// statistics from debugfs, such as exits, irq injections and halt polling.
+func NewKVMCollector(config Config) (Collector, error) {
+	if _, err := os.Stat(sysFilePath("kernel/debug/kvm")); err != nil {
+		return nil, NotApplicable("no KVM statistics in debugfs: %s", err)
+	}
+	return &kvmCollector{config: config}, nil
+}
+
+func (c *kvmCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	stats, err := getKVMStats(sysFilePath("kernel/debug/kvm"))
+	if err != nil {
+		return fmt.Errorf("couldn't get kvm stats: %s", err)
+	}
+	// The statistics differ between kernels, so their descs are built on
+	// each update, which keeps updates free of shared state.
+	for name, value := range stats {
+		desc := newTypedDesc(kvmSubsystem, name, fmt.Sprintf("KVM statistic %s from %s.", name, sysFilePath("kernel/debug/kvm")), prometheus.CounterValue)
+		ch <- desc.mustNewConstMetric(value)
+	}
+	return nil
+}
+
+// getKVMStats reads all statistics files in dir. The per-VM directories
+// (named pid-fd) are skipped, only host-wide sums are exported.
+func getKVMStats(dir string) (map[string]float64, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, f := range files {
+		if f.Mode().IsRegular() {
+			names = append(names, f.Name())
+		}
+	}
+	contents, err := procfs.ReadFiles(dir, names, *readWorkers)
+	if err != nil {
+		return nil, err
+	}
+	stats := map[string]float64{}
+	for i, name := range names {
+		if contents[i].Err != nil {
+			return nil, contents[i].Err
+		}
+		value, err := parseUint(strings.TrimSpace(string(contents[i].Data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in %s: %s", name, err)
+		}
+		stats[name] = float64(value)
+	}
+	return stats, nil
+}
@@ -0,0 +1,20 @@
+package collector
+
+import "testing"
+
+func TestKVMStats(t *testing.T) {
+	stats, err := getKVMStats("fixtures/sys/kernel/debug/kvm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 1521402644.0, stats["exits"]; want != got {
+		t.Errorf("want kvm exits %f, got %f", want, got)
+	}
+	if want, got := 6257202.0, stats["halt_successful_poll"]; want != got {
+		t.Errorf("want kvm halt_successful_poll %f, got %f", want, got)
+	}
+	if _, ok := stats["4297-12"]; ok {
+		t.Error("want per-VM directories to be skipped")
+	}
+}
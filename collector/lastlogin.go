@@ -10,8 +10,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
 )
 
 const lastLoginSubsystem = "last_login"
@@ -22,7 +23,7 @@ type lastLoginCollector struct {
 }
 
 func init() {
-	Factories["lastlogin"] = NewLastLoginCollector
+	registerCollector("lastlogin", defaultDisabled, NewLastLoginCollector)
 }
 
 // Takes a config struct and prometheus registry and returns a new Collector exposing
@@ -39,12 +40,12 @@ func NewLastLoginCollector(config Config) (Collector, error) {
 	}, nil
 }
 
-func (c *lastLoginCollector) Update(ch chan<- prometheus.Metric) (err error) {
+func (c *lastLoginCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
 	last, err := getLastLoginTime()
 	if err != nil {
 		return fmt.Errorf("Couldn't get last seen: %s", err)
 	}
-	glog.V(1).Infof("Set node_last_login_time: %f", last)
+	log.Debugf("Set node_last_login_time: %f", last)
 	c.metric.Set(last)
 	c.metric.Collect(ch)
 	return err
@@ -0,0 +1,235 @@
+// +build !nolibvirt
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultVirsh      = "virsh"
+	defaultLibvirtURI = "qemu:///system"
+	libvirtSubsystem  = "libvirt_domain"
+)
+
+// Per-device statistics of "virsh domstats" and the metrics they are
+// exported as. Keys are relative to e.g. "block.0.".
+var (
+	libvirtBlockStats = map[string]string{
+		"rd.reqs":  "block_read_requests",
+		"rd.bytes": "block_read_bytes",
+		"wr.reqs":  "block_write_requests",
+		"wr.bytes": "block_written_bytes",
+	}
+	libvirtNetStats = map[string]string{
+		"rx.bytes": "network_receive_bytes",
+		"rx.pkts":  "network_receive_packets",
+		"rx.errs":  "network_receive_errors",
+		"rx.drop":  "network_receive_drops",
+		"tx.bytes": "network_transmit_bytes",
+		"tx.pkts":  "network_transmit_packets",
+		"tx.errs":  "network_transmit_errors",
+		"tx.drop":  "network_transmit_drops",
+	}
+)
+
+type libvirtCollector struct {
+	config Config
+	virsh  string
+	uri    string
+
+	// mu keeps overlapping updates from resetting each other's domains.
+	mu       sync.Mutex
+	gauges   map[string]*prometheus.GaugeVec
+	counters map[string]*prometheus.CounterVec
+}
+
+func init() {
+	registerCollector("libvirt", defaultDisabled, NewLibvirtCollector)
+}
+
+// NewLibvirtCollector returns a new Collector exposing state, CPU, memory,
+// block and network statistics of the domains of the local libvirt daemon.
+// It uses virsh, which talks to libvirtd over its local socket.
+func NewLibvirtCollector(config Config) (Collector, error) {
+	c := &libvirtCollector{
+		config:   config,
+		virsh:    defaultVirsh,
+		uri:      defaultLibvirtURI,
+		gauges:   map[string]*prometheus.GaugeVec{},
+		counters: map[string]*prometheus.CounterVec{},
+	}
+	if config.Config["libvirt_virsh_command"] != "" {
+		c.virsh = config.Config["libvirt_virsh_command"]
+	}
+	if config.Config["libvirt_uri"] != "" {
+		c.uri = config.Config["libvirt_uri"]
+	}
+	if _, err := exec.LookPath(c.virsh); err != nil {
+		return nil, NotApplicable("no %s: %s", c.virsh, err)
+	}
+
+	c.newGauge("state", "State of the domain as virDomainState, 1 is running.")
+	c.newGauge("vcpus", "Number of virtual CPUs of the domain.")
+	c.newGauge("memory_bytes", "Memory currently assigned to the domain in bytes.")
+	c.newGauge("memory_max_bytes", "Maximum memory of the domain in bytes.")
+	c.newCounter("cpu_seconds", "CPU time used by the domain in seconds.")
+	c.newCounter("vcpu_seconds", "CPU time used per virtual CPU in seconds.", "vcpu")
+	for _, name := range libvirtBlockStats {
+		c.newCounter(name, "Block device statistic of the domain.", "device")
+	}
+	for _, name := range libvirtNetStats {
+		c.newCounter(name, "Network interface statistic of the domain.", "interface")
+	}
+	return c, nil
+}
+
+func (c *libvirtCollector) newGauge(name, help string, labelNames ...string) {
+	c.gauges[name] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: libvirtSubsystem,
+			Name:      name,
+			Help:      help,
+		},
+		append([]string{"domain"}, labelNames...),
+	)
+}
+
+func (c *libvirtCollector) newCounter(name, help string, labelNames ...string) {
+	c.counters[name] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: libvirtSubsystem,
+			Name:      name,
+			Help:      help,
+		},
+		append([]string{"domain"}, labelNames...),
+	)
+}
+
+func (c *libvirtCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	cmd := exec.Command(c.virsh, "-c", c.uri, "domstats", "--raw")
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	wait, err := startCommand(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	domains, err := parseDomstats(pipe)
+	if err != nil {
+		// Wait doesn't return before the command exits, which it may not
+		// do while its output is left unread.
+		cmd.Process.Kill()
+		if werr := wait(); ctx.Err() != nil {
+			return werr
+		}
+		return err
+	}
+	if err := wait(); err != nil {
+		return fmt.Errorf("virsh domstats failed: %s", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Domains are started, stopped and migrated away all the time.
+	for _, m := range c.gauges {
+		m.Reset()
+	}
+	for _, m := range c.counters {
+		m.Reset()
+	}
+	for domain, stats := range domains {
+		c.updateDomain(domain, stats)
+	}
+	for _, m := range c.gauges {
+		m.Collect(ch)
+	}
+	for _, m := range c.counters {
+		m.Collect(ch)
+	}
+	return nil
+}
+
+func (c *libvirtCollector) updateDomain(domain string, stats map[string]string) {
+	value := func(key string) (float64, bool) {
+		v, err := strconv.ParseFloat(stats[key], 64)
+		return v, err == nil
+	}
+
+	if v, ok := value("state.state"); ok {
+		c.gauges["state"].WithLabelValues(domain).Set(v)
+	}
+	if v, ok := value("vcpu.current"); ok {
+		c.gauges["vcpus"].WithLabelValues(domain).Set(v)
+	}
+	// Balloon sizes are in KiB, CPU times in nanoseconds.
+	if v, ok := value("balloon.current"); ok {
+		c.gauges["memory_bytes"].WithLabelValues(domain).Set(v * 1024)
+	}
+	if v, ok := value("balloon.maximum"); ok {
+		c.gauges["memory_max_bytes"].WithLabelValues(domain).Set(v * 1024)
+	}
+	if v, ok := value("cpu.time"); ok {
+		c.counters["cpu_seconds"].WithLabelValues(domain).Set(v / 1e9)
+	}
+	if n, ok := value("vcpu.maximum"); ok {
+		for i := 0; i < int(n); i++ {
+			if v, ok := value(fmt.Sprintf("vcpu.%d.time", i)); ok {
+				c.counters["vcpu_seconds"].WithLabelValues(domain, strconv.Itoa(i)).Set(v / 1e9)
+			}
+		}
+	}
+	for prefix, devStats := range map[string]map[string]string{"block": libvirtBlockStats, "net": libvirtNetStats} {
+		n, _ := value(prefix + ".count")
+		for i := 0; i < int(n); i++ {
+			dev := stats[fmt.Sprintf("%s.%d.name", prefix, i)]
+			for key, name := range devStats {
+				if v, ok := value(fmt.Sprintf("%s.%d.%s", prefix, i, key)); ok {
+					c.counters[name].WithLabelValues(domain, dev).Set(v)
+				}
+			}
+		}
+	}
+}
+
+// parseDomstats parses the output of "virsh domstats --raw" into the
+// key=value statistics per domain.
+func parseDomstats(r io.Reader) (map[string]map[string]string, error) {
+	var (
+		domains = map[string]map[string]string{}
+		scanner = bufio.NewScanner(r)
+		current map[string]string
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "Domain: ") {
+			name := strings.Trim(strings.TrimPrefix(line, "Domain: "), "'")
+			current = map[string]string{}
+			domains[name] = current
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || current == nil {
+			return nil, fmt.Errorf("invalid line in domstats: %s", line)
+		}
+		current[parts[0]] = parts[1]
+	}
+	return domains, scanner.Err()
+}
@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+func TestDomstats(t *testing.T) {
+	file, err := os.Open("fixtures/domstats.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	domains, err := parseDomstats(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 2, len(domains); want != got {
+		t.Fatalf("want %d domains, got %d", want, got)
+	}
+	if want, got := "11494800565821", domains["web01"]["cpu.time"]; want != got {
+		t.Errorf("want web01 cpu.time %s, got %s", want, got)
+	}
+	if want, got := "vda", domains["web01"]["block.0.name"]; want != got {
+		t.Errorf("want web01 block device %s, got %s", want, got)
+	}
+	if want, got := "5", domains["build02"]["state.state"]; want != got {
+		t.Errorf("want build02 state %s, got %s", want, got)
+	}
+}
+
+func TestLibvirtInvalidOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "libvirt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// A virsh printing invalid lines until it is killed.
+	virsh := filepath.Join(dir, "virsh")
+	if err := ioutil.WriteFile(virsh, []byte("#!/bin/sh\nyes invalid\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewLibvirtCollector(Config{Config: map[string]string{"libvirt_virsh_command": virsh}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- c.Update(context.Background(), make(chan prometheus.Metric, 100)) }()
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("want error for invalid domstats")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("want update to end on invalid domstats")
+	}
+}
+
+func TestLibvirtCancelled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "libvirt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// A virsh waiting for a hung libvirtd.
+	virsh := filepath.Join(dir, "virsh")
+	if err := ioutil.WriteFile(virsh, []byte("#!/bin/sh\nexec sleep 10\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewLibvirtCollector(Config{Config: map[string]string{"libvirt_virsh_command": virsh}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	if err := c.Update(ctx, make(chan prometheus.Metric, 100)); err == nil {
+		t.Error("want error for cancelled update")
+	}
+	if d := time.Since(begin); d > 5*time.Second {
+		t.Errorf("want virsh killed at the deadline, took %s", d)
+	}
+}
@@ -0,0 +1,181 @@
+// +build !nolimits,linux
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector/procfs"
+	"golang.org/x/net/context"
+)
+
+const (
+	// Not all of these are defined by the syscall package.
+	rlimitMemlock = 8
+	rlimInfinity  = ^uint64(0)
+)
+
+type limitsCollector struct {
+	config       Config
+	limit, usage *prometheus.GaugeVec
+}
+
+func init() {
+	registerCollector("limits", defaultDisabled, NewLimitsCollector)
+}
+
+// NewLimitsCollector returns a new Collector exposing kernel-wide resource
+// limits next to their current usage, and the exporter's own locked memory
+// rlimit, for which there is no kernel-wide limit.
+func NewLimitsCollector(config Config) (Collector, error) {
+	return &limitsCollector{
+		config: config,
+		limit: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Name:      "resource_limit",
+				Help:      "Limit of a resource, +Inf if unlimited.",
+			},
+			[]string{"resource"},
+		),
+		usage: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Name:      "resource_usage",
+				Help:      "Current usage of a resource limited by node_resource_limit.",
+			},
+			[]string{"resource"},
+		),
+	}, nil
+}
+
+func (c *limitsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	pidMax, err := readUintFromFile(procFilePath("sys/kernel/pid_max"))
+	if err != nil {
+		return fmt.Errorf("couldn't get pid_max: %s", err)
+	}
+	threadsMax, err := readUintFromFile(procFilePath("sys/kernel/threads-max"))
+	if err != nil {
+		return fmt.Errorf("couldn't get threads-max: %s", err)
+	}
+	data, err := readProcFile(ctx, "loadavg")
+	if err != nil {
+		return fmt.Errorf("couldn't get thread count: %s", err)
+	}
+	threads, err := parseThreadCount(string(data))
+	if err != nil {
+		return err
+	}
+	data, err = procfs.ReadFile(procFilePath("sys/fs/file-nr"))
+	if err != nil {
+		return fmt.Errorf("couldn't get file-nr: %s", err)
+	}
+	files, filesMax, err := parseFileNr(string(data))
+	if err != nil {
+		return err
+	}
+	pids, err := allProcesses(*procPath)
+	if err != nil {
+		return fmt.Errorf("couldn't count processes: %s", err)
+	}
+	data, err = procfs.ReadFile(procFilePath("self/status"))
+	if err != nil {
+		return fmt.Errorf("couldn't get locked memory: %s", err)
+	}
+	locked, err := parseKB(bytes.NewReader(data), "VmLck:", procFilePath("self/status"))
+	if err != nil {
+		return err
+	}
+
+	// Every thread and process occupies a pid and a task, so both limits apply
+	// to the thread and process counts.
+	c.set("threads", float64(threadsMax), threads)
+	c.set("pids", float64(pidMax), threads)
+	c.set("processes", math.Min(float64(pidMax), float64(threadsMax)), float64(len(pids)))
+	c.set("open_files", float64(filesMax), files)
+
+	// RLIMIT_MEMLOCK limits each process, not the system: report the
+	// exporter's own, as a sample of the limit processes get by default.
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(rlimitMemlock, &rlimit); err != nil {
+		return fmt.Errorf("couldn't get RLIMIT_MEMLOCK: %s", err)
+	}
+	c.set("exporter_locked_memory_bytes", rlimitValue(rlimit.Cur), locked)
+
+	c.limit.Collect(ch)
+	c.usage.Collect(ch)
+	return nil
+}
+
+func (c *limitsCollector) set(resource string, limit, usage float64) {
+	c.limit.WithLabelValues(resource).Set(limit)
+	c.usage.WithLabelValues(resource).Set(usage)
+}
+
+func rlimitValue(v uint64) float64 {
+	if v == rlimInfinity {
+		return math.Inf(1)
+	}
+	return float64(v)
+}
+
+// parseThreadCount returns the number of existing threads from the
+// running/total field of /proc/loadavg.
+func parseThreadCount(data string) (float64, error) {
+	parts := strings.Fields(data)
+	if len(parts) < 4 {
+		return 0, fmt.Errorf("invalid line in %s: %s", procFilePath("loadavg"), data)
+	}
+	tasks := strings.Split(parts[3], "/")
+	if len(tasks) != 2 {
+		return 0, fmt.Errorf("invalid task field in %s: %s", procFilePath("loadavg"), parts[3])
+	}
+	threads, err := strconv.ParseFloat(tasks[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid thread count in %s: %s", procFilePath("loadavg"), err)
+	}
+	return threads, nil
+}
+
+// parseFileNr returns the number of used file handles and the file-max limit
+// from /proc/sys/fs/file-nr.
+func parseFileNr(data string) (used, max float64, err error) {
+	parts := strings.Fields(data)
+	if len(parts) != 3 {
+		return 0, 0, fmt.Errorf("invalid line in %s: %s", procFilePath("sys/fs/file-nr"), data)
+	}
+	values := make([]float64, len(parts))
+	for i, part := range parts {
+		values[i], err = strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid value in %s: %s", procFilePath("sys/fs/file-nr"), err)
+		}
+	}
+	// Allocated minus free handles, the latter being zero on modern kernels.
+	return values[0] - values[1], values[2], nil
+}
+
+// parseKB returns the value in bytes of the field of a kB value per line
+// file like /proc/meminfo or /proc/<pid>/status, or 0 if it is missing.
+func parseKB(r io.Reader, field, file string) (float64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 3 && parts[0] == field {
+			kb, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s value in %s: %s", strings.TrimSuffix(field, ":"), file, err)
+			}
+			return kb * 1024, nil
+		}
+	}
+	return 0, scanner.Err()
+}
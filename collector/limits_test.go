@@ -0,0 +1,48 @@
+// +build linux
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestThreadCount(t *testing.T) {
+	threads, err := parseThreadCount("0.21 0.37 0.39 1/719 19737")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := 719.0; want != threads {
+		t.Fatalf("want threads %f, got %f", want, threads)
+	}
+}
+
+func TestFileNr(t *testing.T) {
+	used, max, err := parseFileNr("6144\t0\t1620230\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := 6144.0; want != used {
+		t.Errorf("want used file handles %f, got %f", want, used)
+	}
+	if want := 1620230.0; want != max {
+		t.Errorf("want file-max %f, got %f", want, max)
+	}
+}
+
+func TestParseKB(t *testing.T) {
+	status := "Name:\tnode_exporter\nVmPeak:\t  449232 kB\nVmLck:\t      64 kB\nVmPin:\t       0 kB\n"
+	locked, err := parseKB(strings.NewReader(status), "VmLck:", "status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 65536.0; want != locked {
+		t.Errorf("want locked memory %f, got %f", want, locked)
+	}
+
+	if _, err := parseKB(strings.NewReader("VmLck:\tx kB\n"), "VmLck:", "status"); err == nil {
+		t.Error("want error for invalid value")
+	}
+}
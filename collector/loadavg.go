@@ -1,28 +1,23 @@
 // +build !noloadavg
+// +build linux freebsd dragonfly darwin openbsd netbsd solaris,cgo
 
 package collector
 
 import (
 	"fmt"
-	"io/ioutil"
-	"strconv"
-	"strings"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
-)
-
-const (
-	procLoad = "/proc/loadavg"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
 )
 
 type loadavgCollector struct {
 	config Config
-	metric prometheus.Gauge
+	metric typedDesc
 }
 
 func init() {
-	Factories["loadavg"] = NewLoadavgCollector
+	registerCollector("loadavg", defaultEnabled, NewLoadavgCollector)
 }
 
 // Takes a config struct and prometheus registry and returns a new Collector exposing
@@ -30,38 +25,16 @@ func init() {
 func NewLoadavgCollector(config Config) (Collector, error) {
 	return &loadavgCollector{
 		config: config,
-		metric: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace: Namespace,
-			Name:      "load1",
-			Help:      "1m load average.",
-		}),
+		metric: newTypedDesc("", "load1", "1m load average.", prometheus.GaugeValue),
 	}, nil
 }
 
-func (c *loadavgCollector) Update(ch chan<- prometheus.Metric) (err error) {
-	load, err := getLoad1()
+func (c *loadavgCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	load, err := getLoad1(ctx)
 	if err != nil {
 		return fmt.Errorf("Couldn't get load: %s", err)
 	}
-	glog.V(1).Infof("Set node_load: %f", load)
-	c.metric.Set(load)
-	c.metric.Collect(ch)
+	log.Debugf("Set node_load: %f", load)
+	ch <- c.metric.mustNewConstMetric(load)
 	return err
 }
-
-func getLoad1() (float64, error) {
-	data, err := ioutil.ReadFile(procLoad)
-	if err != nil {
-		return 0, err
-	}
-	return parseLoad(string(data))
-}
-
-func parseLoad(data string) (float64, error) {
-	parts := strings.Fields(data)
-	load, err := strconv.ParseFloat(parts[0], 64)
-	if err != nil {
-		return 0, fmt.Errorf("Could not parse load '%s': %s", parts[0], err)
-	}
-	return load, nil
-}
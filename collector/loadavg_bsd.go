@@ -0,0 +1,19 @@
+// +build !noloadavg
+// +build freebsd dragonfly darwin openbsd netbsd
+
+package collector
+
+import (
+	"strconv"
+
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+func getLoad1(ctx context.Context) (float64, error) {
+	data, err := unix.SysctlRaw("vm.loadavg")
+	if err != nil {
+		return 0, err
+	}
+	return parseSysctlLoadavg(data, strconv.IntSize/8, nativeEndian())
+}
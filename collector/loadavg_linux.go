@@ -0,0 +1,28 @@
+// +build !noloadavg,linux
+
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+func getLoad1(ctx context.Context) (float64, error) {
+	data, err := readProcFile(ctx, "loadavg")
+	if err != nil {
+		return 0, err
+	}
+	return parseLoad(string(data))
+}
+
+func parseLoad(data string) (float64, error) {
+	parts := strings.Fields(data)
+	load, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("Could not parse load '%s': %s", parts[0], err)
+	}
+	return load, nil
+}
@@ -0,0 +1,28 @@
+// +build !noloadavg,solaris,cgo
+
+package collector
+
+import (
+	"golang.org/x/net/context"
+)
+
+// kstatLoadScale is FSCALE, the fixed point scale of the load averages of
+// unix:0:system_misc.
+const kstatLoadScale = 256
+
+func getLoad1(ctx context.Context) (float64, error) {
+	kc, err := openKstat()
+	if err != nil {
+		return 0, err
+	}
+	defer kc.close()
+	misc, err := kc.lookup("unix", 0, "system_misc")
+	if err != nil {
+		return 0, err
+	}
+	load, err := misc.named("avenrun_1min")
+	if err != nil {
+		return 0, err
+	}
+	return load / kstatLoadScale, nil
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -27,7 +28,7 @@ type megaCliCollector struct {
 }
 
 func init() {
-	Factories["megacli"] = NewMegaCliCollector
+	registerCollector("megacli", defaultDisabled, NewMegaCliCollector)
 }
 
 // Takes a config struct and prometheus registry and returns a new Collector exposing
@@ -37,6 +38,9 @@ func NewMegaCliCollector(config Config) (Collector, error) {
 	if config.Config["megacli_command"] != "" {
 		cli = config.Config["megacli_command"]
 	}
+	if _, err := exec.LookPath(cli); err != nil {
+		return nil, NotApplicable("no %s: %s", cli, err)
+	}
 
 	return &megaCliCollector{
 		config: config,
@@ -59,7 +63,11 @@ func NewMegaCliCollector(config Config) (Collector, error) {
 	}, nil
 }
 
-func (c *megaCliCollector) Update(ch chan<- prometheus.Metric) (err error) {
+func (c *megaCliCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	// Drives are replaced, don't keep exporting removed ones.
+	c.driveTemperature.Reset()
+	c.driveCounters.Reset()
+	c.drivePresence.Reset()
 	err = c.updateAdapter()
 	if err != nil {
 		return err
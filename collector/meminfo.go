@@ -1,100 +1,45 @@
 // +build !nomeminfo
+// +build linux freebsd dragonfly darwin,cgo openbsd netbsd solaris,cgo
 
 package collector
 
 import (
-	"bufio"
 	"fmt"
-	"io"
-	"os"
-	"regexp"
-	"strconv"
-	"strings"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
 )
 
 const (
-	procMemInfo      = "/proc/meminfo"
 	memInfoSubsystem = "memory"
 )
 
 type meminfoCollector struct {
-	config  Config
-	metrics map[string]prometheus.Gauge
+	config Config
 }
 
 func init() {
-	Factories["meminfo"] = NewMeminfoCollector
+	registerCollector("meminfo", defaultEnabled, NewMeminfoCollector)
 }
 
 // Takes a config struct and prometheus registry and returns a new Collector exposing
 // memory stats.
 func NewMeminfoCollector(config Config) (Collector, error) {
 	return &meminfoCollector{
-		config:  config,
-		metrics: map[string]prometheus.Gauge{},
+		config: config,
 	}, nil
 }
 
-func (c *meminfoCollector) Update(ch chan<- prometheus.Metric) (err error) {
-	memInfo, err := getMemInfo()
+func (c *meminfoCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	memInfo, err := getMemInfo(ctx)
 	if err != nil {
 		return fmt.Errorf("Couldn't get meminfo: %s", err)
 	}
-	glog.V(1).Infof("Set node_mem: %#v", memInfo)
+	log.Debugf("Set node_mem: %#v", memInfo)
 	for k, v := range memInfo {
-		if _, ok := c.metrics[k]; !ok {
-			c.metrics[k] = prometheus.NewGauge(prometheus.GaugeOpts{
-				Namespace: Namespace,
-				Subsystem: memInfoSubsystem,
-				Name:      k,
-				Help:      k + " from /proc/meminfo.",
-			})
-		}
-		c.metrics[k].Set(v)
-		c.metrics[k].Collect(ch)
+		desc := newTypedDesc(memInfoSubsystem, k, k+" from "+memInfoSource+".", prometheus.GaugeValue)
+		ch <- desc.mustNewConstMetric(v)
 	}
 	return err
 }
-
-func getMemInfo() (map[string]float64, error) {
-	file, err := os.Open(procMemInfo)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	return parseMemInfo(file)
-}
-
-func parseMemInfo(r io.Reader) (map[string]float64, error) {
-	var (
-		memInfo = map[string]float64{}
-		scanner = bufio.NewScanner(r)
-		re      = regexp.MustCompile("\\((.*)\\)")
-	)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Fields(string(line))
-		fv, err := strconv.ParseFloat(parts[1], 64)
-		if err != nil {
-			return nil, fmt.Errorf("Invalid value in meminfo: %s", err)
-		}
-		switch len(parts) {
-		case 2: // no unit
-		case 3: // has unit, we presume kB
-			fv *= 1024
-		default:
-			return nil, fmt.Errorf("Invalid line in %s: %s", procMemInfo, line)
-		}
-		key := parts[0][:len(parts[0])-1] // remove trailing : from key
-		// Active(anon) -> Active_anon
-		key = re.ReplaceAllString(key, "_${1}")
-		memInfo[key] = fv
-	}
-
-	return memInfo, nil
-}
@@ -0,0 +1,59 @@
+// +build !nomeminfo
+// +build freebsd dragonfly
+
+package collector
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+// memInfoSource is where the memory stats come from, for the help strings.
+const memInfoSource = "sysctl"
+
+// memInfoPageCounts are the page counters of vm.stats.vm exported as bytes,
+// named like the /proc/meminfo fields of Linux where there is one.
+var memInfoPageCounts = map[string]string{
+	"MemFree":  "vm.stats.vm.v_free_count",
+	"Active":   "vm.stats.vm.v_active_count",
+	"Inactive": "vm.stats.vm.v_inactive_count",
+	"Wired":    "vm.stats.vm.v_wire_count",
+	// Removed in FreeBSD 12.
+	"Cached": "vm.stats.vm.v_cache_count",
+}
+
+func getMemInfo(ctx context.Context) (map[string]float64, error) {
+	pageSize, err := unix.SysctlUint32("hw.pagesize")
+	if err != nil {
+		return nil, err
+	}
+	memInfo := map[string]float64{}
+	for name, key := range memInfoPageCounts {
+		pages, err := unix.SysctlUint32(key)
+		if err == unix.ENOENT {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		memInfo[name] = float64(pages) * float64(pageSize)
+	}
+	for name, key := range map[string]string{"MemTotal": "hw.physmem", "Buffers": "vfs.bufspace"} {
+		data, err := unix.SysctlRaw(key)
+		if err != nil {
+			return nil, err
+		}
+		values, err := parseSysctlLongs(data, strconv.IntSize/8, nativeEndian())
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", key, err)
+		}
+		if len(values) != 1 {
+			return nil, fmt.Errorf("invalid %s of %d bytes", key, len(data))
+		}
+		memInfo[name] = float64(values[0])
+	}
+	return memInfo, nil
+}
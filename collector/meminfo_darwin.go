@@ -0,0 +1,57 @@
+// +build !nomeminfo,darwin,cgo
+
+package collector
+
+/*
+#include <mach/mach_host.h>
+#include <mach/mach_init.h>
+#include <mach/vm_statistics.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+// memInfoSource is where the memory stats come from, for the help strings.
+const memInfoSource = "host_statistics64"
+
+func getMemInfo(ctx context.Context) (map[string]float64, error) {
+	var vmstat C.vm_statistics64_data_t
+	count := C.mach_msg_type_number_t(C.HOST_VM_INFO64_COUNT)
+	status := C.host_statistics64(C.host_t(C.mach_host_self()), C.HOST_VM_INFO64, C.host_info64_t(unsafe.Pointer(&vmstat)), &count)
+	if status != C.KERN_SUCCESS {
+		return nil, fmt.Errorf("host_statistics64 failed: %d", status)
+	}
+	pageSize, err := unix.SysctlUint32("hw.pagesize")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get hw.pagesize: %s", err)
+	}
+	total, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get hw.memsize: %s", err)
+	}
+	// struct xsw_usage starts with the total and available swap in bytes.
+	swap, err := unix.SysctlRaw("vm.swapusage")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get vm.swapusage: %s", err)
+	}
+	if len(swap) < 16 {
+		return nil, fmt.Errorf("invalid vm.swapusage of %d bytes", len(swap))
+	}
+
+	ps := float64(pageSize)
+	return map[string]float64{
+		"MemTotal":  float64(total),
+		"MemFree":   float64(vmstat.free_count) * ps,
+		"Active":    float64(vmstat.active_count) * ps,
+		"Inactive":  float64(vmstat.inactive_count) * ps,
+		"Wired":     float64(vmstat.wire_count) * ps,
+		"SwapTotal": float64(nativeEndian().Uint64(swap)),
+		"SwapFree":  float64(nativeEndian().Uint64(swap[8:])),
+	}, nil
+}
@@ -0,0 +1,56 @@
+// +build !nomeminfo,linux
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// memInfoSource is where the memory stats come from, for the help strings.
+const memInfoSource = "/proc/meminfo"
+
+func getMemInfo(ctx context.Context) (map[string]float64, error) {
+	data, err := readProcFile(ctx, "meminfo")
+	if err != nil {
+		return nil, err
+	}
+	return parseMemInfo(bytes.NewReader(data))
+}
+
+func parseMemInfo(r io.Reader) (map[string]float64, error) {
+	var (
+		memInfo = map[string]float64{}
+		scanner = bufio.NewScanner(r)
+		re      = regexp.MustCompile("\\((.*)\\)")
+	)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		parts := strings.Fields(string(line))
+		v, err := parseUint(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value in meminfo: %s", err)
+		}
+		fv := float64(v)
+		switch len(parts) {
+		case 2: // no unit
+		case 3: // has unit, we presume kB
+			fv *= 1024
+		default:
+			return nil, fmt.Errorf("Invalid line in %s: %s", procFilePath("meminfo"), line)
+		}
+		key := parts[0][:len(parts[0])-1] // remove trailing : from key
+		// Active(anon) -> Active_anon
+		key = re.ReplaceAllString(key, "_${1}")
+		memInfo[key] = fv
+	}
+
+	return memInfo, nil
+}
@@ -0,0 +1,33 @@
+// +build !nomeminfo,netbsd
+
+package collector
+
+import (
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+// memInfoSource is where the memory stats come from, for the help strings.
+const memInfoSource = "uvmexp2"
+
+func getMemInfo(ctx context.Context) (map[string]float64, error) {
+	data, err := unix.SysctlRaw("vm.uvmexp2")
+	if err != nil {
+		return nil, err
+	}
+	uvm, err := parseSysctlUvmexp2(data, nativeEndian())
+	if err != nil {
+		return nil, err
+	}
+	ps := float64(uvm.pageSize)
+	return map[string]float64{
+		"MemTotal":  float64(uvm.pages) * ps,
+		"MemFree":   float64(uvm.free) * ps,
+		"Active":    float64(uvm.active) * ps,
+		"Inactive":  float64(uvm.inactive) * ps,
+		"Wired":     float64(uvm.wired) * ps,
+		"Cached":    float64(uvm.filePages) * ps,
+		"SwapTotal": float64(uvm.swapPages) * ps,
+		"SwapFree":  float64(uvm.swapPages-uvm.swapInUse) * ps,
+	}, nil
+}
@@ -0,0 +1,52 @@
+// +build !nomeminfo,solaris,cgo
+
+package collector
+
+/*
+#include <unistd.h>
+*/
+import "C"
+
+import (
+	"golang.org/x/net/context"
+)
+
+// memInfoSource is where the memory stats come from, for the help strings.
+const memInfoSource = "kstat"
+
+func getMemInfo(ctx context.Context) (map[string]float64, error) {
+	kc, err := openKstat()
+	if err != nil {
+		return nil, err
+	}
+	defer kc.close()
+	pages, err := kc.lookup("unix", 0, "system_pages")
+	if err != nil {
+		return nil, err
+	}
+	ps := float64(C.sysconf(C._SC_PAGESIZE))
+	memInfo := map[string]float64{}
+	for name, stat := range map[string]string{
+		"MemTotal":  "physmem",
+		"MemFree":   "freemem",
+		"Available": "availrmem",
+		"Kernel":    "pp_kernel",
+	} {
+		v, err := pages.named(stat)
+		if err != nil {
+			return nil, err
+		}
+		memInfo[name] = v * ps
+	}
+
+	// The ARC of ZFS is held by the kernel, but given back under memory
+	// pressure like the page cache of Linux.
+	if arc, err := kc.lookup("zfs", 0, "arcstats"); err == nil {
+		size, err := arc.named("size")
+		if err != nil {
+			return nil, err
+		}
+		memInfo["ZfsArcSize"] = size
+	}
+	return memInfo, nil
+}
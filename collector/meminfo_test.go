@@ -1,12 +1,15 @@
+// +build linux
+
 package collector
 
 import (
+	"bytes"
 	"os"
 	"testing"
 )
 
 func TestMemInfo(t *testing.T) {
-	file, err := os.Open("fixtures/meminfo")
+	file, err := os.Open("fixtures/proc/meminfo")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -25,3 +28,10 @@ func TestMemInfo(t *testing.T) {
 		t.Errorf("want memory directMap2M %f, got %f", want, got)
 	}
 }
+
+func BenchmarkParseMemInfo(b *testing.B) {
+	benchmarkParser(b, "fixtures/proc/meminfo", func(r *bytes.Reader) error {
+		_, err := parseMemInfo(r)
+		return err
+	})
+}
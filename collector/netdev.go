@@ -1,126 +1,103 @@
 // +build !nonetdev
+// +build linux darwin
 
 package collector
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
-	"io"
-	"os"
-	"strconv"
-	"strings"
+	"path"
+	"regexp"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
 )
 
 const (
-	procNetDev      = "/proc/net/dev"
 	netDevSubsystem = "network"
 )
 
+var (
+	netdevIgnoredDevices = flag.String("collector.netdev.ignored-devices", "^$", "Regexp of net devices to ignore for netdev collector.")
+	netdevNetlink        = flag.Bool("collector.netdev.netlink", true, "Use netlink to gather stats instead of /proc/net/dev, falling back to /proc/net/dev if netlink fails. Only used with the default -path.procfs, as netlink sees the network namespace of the exporter.")
+)
+
+// netDevStats holds the counters of network devices by direction, device and
+// counter name, named like the columns of /proc/net/dev.
+type netDevStats map[string]map[string]map[string]uint64
+
+func newNetDevStats() netDevStats {
+	return netDevStats{
+		"transmit": map[string]map[string]uint64{},
+		"receive":  map[string]map[string]uint64{},
+	}
+}
+
 type netDevCollector struct {
-	config  Config
-	metrics map[string]*prometheus.GaugeVec
+	config                Config
+	ignoredDevicesPattern *regexp.Regexp
+	netlink               bool
+	netlinkFailed         sync.Once
+	carrierChanges        typedDesc
+	removed               *removedEntities
 }
 
 func init() {
-	Factories["netdev"] = NewNetDevCollector
+	registerCollector("netdev", defaultEnabled, NewNetDevCollector)
 }
 
 // Takes a config struct and prometheus registry and returns a new Collector exposing
 // network device stats.
 func NewNetDevCollector(config Config) (Collector, error) {
+	pattern, err := regexp.Compile(*netdevIgnoredDevices)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignored net devices pattern: %s", err)
+	}
 	return &netDevCollector{
-		config:  config,
-		metrics: map[string]*prometheus.GaugeVec{},
+		config:                config,
+		ignoredDevicesPattern: pattern,
+		netlink:               *netdevNetlink && path.Clean(*procPath) == "/proc",
+		carrierChanges:        newTypedDesc(netDevSubsystem, "carrier_changes_total", "Number of times the carrier of the network device changed.", prometheus.CounterValue, "device"),
+		removed:               newRemovedEntities(netDevSubsystem, "Number of network devices that were removed."),
 	}, nil
 }
 
-func (c *netDevCollector) Update(ch chan<- prometheus.Metric) (err error) {
-	netDev, err := getNetDevStats()
-	if err != nil {
-		return fmt.Errorf("Couldn't get netstats: %s", err)
+func (c *netDevCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	var (
+		netDev         netDevStats
+		carrierChanges map[string]uint64
+	)
+	if c.netlink {
+		netDev, carrierChanges, err = getNetlinkNetDevStats(c.ignoredDevicesPattern)
+		if err != nil {
+			c.netlinkFailed.Do(func() {
+				log.Warnf("Couldn't get netstats via netlink, falling back to /proc/net/dev: %s", err)
+			})
+		}
+	}
+	if netDev == nil {
+		netDev, err = getNetDevStats(ctx, c.ignoredDevicesPattern)
+		if err != nil {
+			return fmt.Errorf("Couldn't get netstats: %s", err)
+		}
 	}
 	for direction, devStats := range netDev {
 		for dev, stats := range devStats {
 			for t, value := range stats {
-				key := direction + "_" + t
-				if _, ok := c.metrics[key]; !ok {
-					c.metrics[key] = prometheus.NewGaugeVec(
-						prometheus.GaugeOpts{
-							Namespace: Namespace,
-							Subsystem: netDevSubsystem,
-							Name:      key,
-							Help:      fmt.Sprintf("%s %s from /proc/net/dev.", t, direction),
-						},
-						[]string{"device"},
-					)
-				}
-				v, err := strconv.ParseFloat(value, 64)
-				if err != nil {
-					return fmt.Errorf("Invalid value %s in netstats: %s", value, err)
-				}
-				c.metrics[key].WithLabelValues(dev).Set(v)
+				desc := newTypedDesc(netDevSubsystem, direction+"_"+t, fmt.Sprintf("Network device statistic %s %s.", t, direction), prometheus.CounterValue, "device")
+				ch <- desc.mustNewConstMetric(float64(value), dev)
 			}
 		}
 	}
-	for _, m := range c.metrics {
-		m.Collect(ch)
+	for dev, value := range carrierChanges {
+		ch <- c.carrierChanges.mustNewConstMetric(float64(value), dev)
 	}
-	return err
-}
-
-func getNetDevStats() (map[string]map[string]map[string]string, error) {
-	file, err := os.Open(procNetDev)
-	if err != nil {
-		return nil, err
+	devices := make([]string, 0, len(netDev["receive"]))
+	for dev := range netDev["receive"] {
+		devices = append(devices, dev)
 	}
-	defer file.Close()
-
-	return parseNetDevStats(file)
-}
-
-func parseNetDevStats(r io.Reader) (map[string]map[string]map[string]string, error) {
-	netDev := map[string]map[string]map[string]string{}
-	netDev["transmit"] = map[string]map[string]string{}
-	netDev["receive"] = map[string]map[string]string{}
-
-	scanner := bufio.NewScanner(r)
-	scanner.Scan() // skip first header
-	scanner.Scan()
-	parts := strings.Split(string(scanner.Text()), "|")
-	if len(parts) != 3 { // interface + receive + transmit
-		return nil, fmt.Errorf("Invalid header line in %s: %s",
-			procNetDev, scanner.Text())
-	}
-	header := strings.Fields(parts[1])
-	for scanner.Scan() {
-		parts := strings.Fields(string(scanner.Text()))
-		if len(parts) != 2*len(header)+1 {
-			return nil, fmt.Errorf("Invalid line in %s: %s",
-				procNetDev, scanner.Text())
-		}
-
-		dev := parts[0][:len(parts[0])-1]
-		receive, err := parseNetDevLine(parts[1:len(header)+1], header)
-		if err != nil {
-			return nil, err
-		}
-
-		transmit, err := parseNetDevLine(parts[len(header)+1:], header)
-		if err != nil {
-			return nil, err
-		}
-		netDev["transmit"][dev] = transmit
-		netDev["receive"][dev] = receive
-	}
-	return netDev, nil
-}
-
-func parseNetDevLine(parts []string, header []string) (map[string]string, error) {
-	devStats := map[string]string{}
-	for i, v := range parts {
-		devStats[header[i]] = v
-	}
-	return devStats, nil
+	ch <- c.removed.update(devices)
+	return err
 }
@@ -0,0 +1,36 @@
+// +build !nonetdev,darwin
+
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"syscall"
+
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+// getNetDevStats reads the interface counters with the NET_RT_IFLIST2
+// routing sysctl, which unlike getifaddrs(3) has them in 64 bits.
+func getNetDevStats(ctx context.Context, ignore *regexp.Regexp) (netDevStats, error) {
+	data, err := syscall.RouteRIB(unix.NET_RT_IFLIST2, 0)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get NET_RT_IFLIST2: %s", err)
+	}
+	ifaces, err := parseSysctlIfList2(data, nativeEndian())
+	if err != nil {
+		return nil, err
+	}
+	netDev := newNetDevStats()
+	for _, iface := range ifaces {
+		if ignore.MatchString(iface.name) {
+			log.Debugf("Ignoring device: %s", iface.name)
+			continue
+		}
+		netDev["receive"][iface.name] = iface.receive
+		netDev["transmit"][iface.name] = iface.transmit
+	}
+	return netDev, nil
+}
@@ -0,0 +1,75 @@
+// +build !nonetdev,linux
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
+)
+
+func getNetDevStats(ctx context.Context, ignore *regexp.Regexp) (netDevStats, error) {
+	data, err := readProcFile(ctx, "net/dev")
+	if err != nil {
+		return nil, err
+	}
+	return parseNetDevStats(bytes.NewReader(data), ignore)
+}
+
+func parseNetDevStats(r io.Reader, ignore *regexp.Regexp) (netDevStats, error) {
+	netDev := newNetDevStats()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // skip first header
+	scanner.Scan()
+	parts := strings.Split(string(scanner.Text()), "|")
+	if len(parts) != 3 { // interface + receive + transmit
+		return nil, fmt.Errorf("Invalid header line in %s: %s",
+			procFilePath("net/dev"), scanner.Text())
+	}
+	receiveHeader := strings.Fields(parts[1])
+	transmitHeader := strings.Fields(parts[2])
+	for scanner.Scan() {
+		parts := strings.Fields(string(scanner.Text()))
+		if len(parts) != len(receiveHeader)+len(transmitHeader)+1 {
+			return nil, fmt.Errorf("Invalid line in %s: %s",
+				procFilePath("net/dev"), scanner.Text())
+		}
+
+		dev := parts[0][:len(parts[0])-1]
+		if ignore.MatchString(dev) {
+			log.Debugf("Ignoring device: %s", dev)
+			continue
+		}
+		receive, err := parseNetDevLine(parts[1:len(receiveHeader)+1], receiveHeader)
+		if err != nil {
+			return nil, err
+		}
+
+		transmit, err := parseNetDevLine(parts[len(receiveHeader)+1:], transmitHeader)
+		if err != nil {
+			return nil, err
+		}
+		netDev["transmit"][dev] = transmit
+		netDev["receive"][dev] = receive
+	}
+	return netDev, nil
+}
+
+func parseNetDevLine(parts []string, header []string) (map[string]uint64, error) {
+	devStats := map[string]uint64{}
+	for i, v := range parts {
+		value, err := parseUint(v)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value %s in netstats: %s", v, err)
+		}
+		devStats[header[i]] = value
+	}
+	return devStats, nil
+}
@@ -0,0 +1,138 @@
+// +build !nonetdev,linux
+
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"syscall"
+
+	"github.com/prometheus/node_exporter/log"
+)
+
+// Link attributes missing from package syscall, see
+// include/uapi/linux/if_link.h.
+const (
+	iflaStats64        = 23
+	iflaCarrierChanges = 35
+)
+
+// Counters of struct rtnl_link_stats64, in order.
+const (
+	linkRxPackets = iota
+	linkTxPackets
+	linkRxBytes
+	linkTxBytes
+	linkRxErrors
+	linkTxErrors
+	linkRxDropped
+	linkTxDropped
+	linkMulticast
+	linkCollisions
+	linkRxLengthErrors
+	linkRxOverErrors
+	linkRxCRCErrors
+	linkRxFrameErrors
+	linkRxFIFOErrors
+	linkRxMissedErrors
+	linkTxAbortedErrors
+	linkTxCarrierErrors
+	linkTxFIFOErrors
+	linkTxHeartbeatErrors
+	linkTxWindowErrors
+	linkRxCompressed
+	linkTxCompressed
+	linkStats64Len
+)
+
+// getNetlinkNetDevStats dumps the links of the network namespace of the
+// exporter with RTM_GETLINK, returning their 64-bit counters and carrier
+// changes. In a container, that is the namespace of the container, not of
+// the host mounted at -path.procfs.
+func getNetlinkNetDevStats(ignore *regexp.Regexp) (netDevStats, map[string]uint64, error) {
+	data, err := syscall.NetlinkRIB(syscall.RTM_GETLINK, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't dump links: %s", err)
+	}
+	return parseNetlinkNetDevStats(data, ignore)
+}
+
+// parseNetlinkNetDevStats parses a dump of RTM_NEWLINK messages. The counters
+// are summed up like the kernel does for /proc/net/dev, so that both sources
+// export the same metrics.
+func parseNetlinkNetDevStats(data []byte, ignore *regexp.Regexp) (netDevStats, map[string]uint64, error) {
+	msgs, err := syscall.ParseNetlinkMessage(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't parse link dump: %s", err)
+	}
+	netDev := newNetDevStats()
+	carrierChanges := map[string]uint64{}
+	order := nativeEndian()
+	for i := range msgs {
+		m := &msgs[i]
+		if m.Header.Type == syscall.NLMSG_DONE {
+			break
+		}
+		if m.Header.Type != syscall.RTM_NEWLINK {
+			continue
+		}
+		attrs, err := syscall.ParseNetlinkRouteAttr(m)
+		if err != nil {
+			return nil, nil, fmt.Errorf("couldn't parse link attributes: %s", err)
+		}
+		var (
+			dev        string
+			raw        []byte
+			carrier    uint64
+			hasCarrier bool
+		)
+		for _, a := range attrs {
+			switch a.Attr.Type {
+			case syscall.IFLA_IFNAME:
+				dev = string(bytes.TrimRight(a.Value, "\x00"))
+			case iflaStats64:
+				raw = a.Value
+			case iflaCarrierChanges:
+				if len(a.Value) >= 4 {
+					carrier, hasCarrier = uint64(order.Uint32(a.Value)), true
+				}
+			}
+		}
+		if dev == "" || len(raw) < linkStats64Len*8 {
+			continue
+		}
+		if ignore.MatchString(dev) {
+			log.Debugf("Ignoring device: %s", dev)
+			continue
+		}
+		var s [linkStats64Len]uint64
+		for j := range s {
+			s[j] = order.Uint64(raw[j*8:])
+		}
+		netDev["receive"][dev] = map[string]uint64{
+			"bytes":      s[linkRxBytes],
+			"packets":    s[linkRxPackets],
+			"errs":       s[linkRxErrors],
+			"drop":       s[linkRxDropped] + s[linkRxMissedErrors],
+			"fifo":       s[linkRxFIFOErrors],
+			"frame":      s[linkRxLengthErrors] + s[linkRxOverErrors] + s[linkRxCRCErrors] + s[linkRxFrameErrors],
+			"compressed": s[linkRxCompressed],
+			"multicast":  s[linkMulticast],
+		}
+		netDev["transmit"][dev] = map[string]uint64{
+			"bytes":      s[linkTxBytes],
+			"packets":    s[linkTxPackets],
+			"errs":       s[linkTxErrors],
+			"drop":       s[linkTxDropped],
+			"fifo":       s[linkTxFIFOErrors],
+			"colls":      s[linkCollisions],
+			"carrier":    s[linkTxCarrierErrors] + s[linkTxAbortedErrors] + s[linkTxWindowErrors] + s[linkTxHeartbeatErrors],
+			"compressed": s[linkTxCompressed],
+		}
+		if hasCarrier {
+			carrierChanges[dev] = carrier
+		}
+	}
+	return netDev, carrierChanges, nil
+}
@@ -0,0 +1,75 @@
+// +build linux
+
+package collector
+
+import (
+	"regexp"
+	"syscall"
+	"testing"
+)
+
+// newLinkMessage encodes a RTM_NEWLINK message of a dump.
+func newLinkMessage(name string, stats []uint64, carrierChanges uint32) []byte {
+	raw := make([]byte, 8*len(stats))
+	for i, v := range stats {
+		nativeEndian().PutUint64(raw[i*8:], v)
+	}
+	carrier := make([]byte, 4)
+	nativeEndian().PutUint32(carrier, carrierChanges)
+
+	payload := make([]byte, syscall.SizeofIfInfomsg)
+	payload = append(payload, netlinkAttr(syscall.IFLA_IFNAME, append([]byte(name), 0))...)
+	payload = append(payload, netlinkAttr(iflaStats64, raw)...)
+	payload = append(payload, netlinkAttr(iflaCarrierChanges, carrier)...)
+	return netlinkMessage(syscall.RTM_NEWLINK, payload)
+}
+
+func netlinkMessage(typ uint16, payload []byte) []byte {
+	return newNetlinkMessage(typ, syscall.NLM_F_MULTI, 0, payload)
+}
+
+func TestNetlinkNetDevStats(t *testing.T) {
+	stats := make([]uint64, linkStats64Len)
+	for i := range stats {
+		stats[i] = uint64(i + 1)
+	}
+	stats[linkRxBytes] = 1 << 40
+
+	var data []byte
+	data = append(data, newLinkMessage("eth0", stats, 3)...)
+	data = append(data, newLinkMessage("veth4B09XN", stats, 1)...)
+	data = append(data, netlinkMessage(syscall.NLMSG_DONE, make([]byte, 4))...)
+
+	netDev, carrierChanges, err := parseNetlinkNetDevStats(data, regexp.MustCompile("^veth"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		direction, name string
+		want            uint64
+	}{
+		{"receive", "bytes", 1 << 40},
+		{"receive", "packets", 1},
+		{"receive", "drop", 7 + 16},
+		{"receive", "frame", 11 + 12 + 13 + 14},
+		{"receive", "multicast", 9},
+		{"transmit", "bytes", 4},
+		{"transmit", "colls", 10},
+		{"transmit", "carrier", 18 + 17 + 21 + 20},
+		{"transmit", "compressed", 23},
+	} {
+		if got := netDev[tc.direction]["eth0"][tc.name]; got != tc.want {
+			t.Errorf("want eth0 %s %s %d, got %d", tc.direction, tc.name, tc.want, got)
+		}
+	}
+	if want, got := uint64(3), carrierChanges["eth0"]; want != got {
+		t.Errorf("want eth0 carrier changes %d, got %d", want, got)
+	}
+	if _, ok := netDev["receive"]["veth4B09XN"]; ok {
+		t.Error("want veth4B09XN ignored")
+	}
+	if _, ok := carrierChanges["veth4B09XN"]; ok {
+		t.Error("want veth4B09XN carrier changes ignored")
+	}
+}
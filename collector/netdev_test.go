@@ -1,27 +1,47 @@
+// +build linux
+
 package collector
 
 import (
+	"bytes"
 	"os"
+	"regexp"
 	"testing"
 )
 
 func TestNetDevStats(t *testing.T) {
-	file, err := os.Open("fixtures/net-dev")
+	file, err := os.Open("fixtures/proc/net/dev")
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer file.Close()
 
-	netStats, err := parseNetDevStats(file)
+	netStats, err := parseNetDevStats(file, regexp.MustCompile("^veth"))
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if want, got := "10437182923", netStats["receive"]["wlan0"]["bytes"]; want != got {
-		t.Errorf("want netstat wlan0 bytes %s, got %s", want, got)
+	if want, got := uint64(10437182923), netStats["receive"]["wlan0"]["bytes"]; want != got {
+		t.Errorf("want netstat wlan0 bytes %d, got %d", want, got)
+	}
+
+	if want, got := uint64(934), netStats["transmit"]["tun0"]["packets"]; want != got {
+		t.Errorf("want netstat tun0 packets %d, got %d", want, got)
 	}
 
-	if want, got := "934", netStats["transmit"]["tun0"]["packets"]; want != got {
-		t.Errorf("want netstat tun0 packets %s, got %s", want, got)
+	if _, ok := netStats["transmit"]["tun0"]["colls"]; !ok {
+		t.Error("want transmit counters named after the transmit header")
 	}
+
+	if _, ok := netStats["receive"]["veth4B09XN"]; ok {
+		t.Error("want veth4B09XN ignored")
+	}
+}
+
+func BenchmarkParseNetDevStats(b *testing.B) {
+	ignore := regexp.MustCompile("^$")
+	benchmarkParser(b, "fixtures/proc/net/dev", func(r *bytes.Reader) error {
+		_, err := parseNetDevStats(r, ignore)
+		return err
+	})
 }
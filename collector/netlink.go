@@ -0,0 +1,57 @@
+// +build linux
+
+package collector
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// newNetlinkMessage encodes a netlink message with the given header fields.
+func newNetlinkMessage(typ, flags uint16, seq uint32, payload []byte) []byte {
+	b := make([]byte, syscall.NLMSG_HDRLEN, syscall.NLMSG_HDRLEN+len(payload))
+	order := nativeEndian()
+	order.PutUint32(b[0:], uint32(syscall.NLMSG_HDRLEN+len(payload)))
+	order.PutUint16(b[4:], typ)
+	order.PutUint16(b[6:], flags)
+	order.PutUint32(b[8:], seq)
+	return append(b, payload...)
+}
+
+// netlinkAttr encodes an attribute, padded to the netlink alignment.
+func netlinkAttr(typ uint16, value []byte) []byte {
+	b := make([]byte, syscall.SizeofRtAttr, syscall.SizeofRtAttr+len(value)+syscall.RTA_ALIGNTO)
+	nativeEndian().PutUint16(b[0:], uint16(syscall.SizeofRtAttr+len(value)))
+	nativeEndian().PutUint16(b[2:], typ)
+	b = append(b, value...)
+	for len(b)%syscall.RTA_ALIGNTO != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// parseNetlinkAttrs parses the attributes in b. Unlike
+// syscall.ParseNetlinkRouteAttr it works on any message payload, e.g. those
+// of generic netlink or nested attributes.
+func parseNetlinkAttrs(b []byte) ([]syscall.NetlinkRouteAttr, error) {
+	var attrs []syscall.NetlinkRouteAttr
+	for len(b) >= syscall.SizeofRtAttr {
+		l := int(nativeEndian().Uint16(b[0:]))
+		if l < syscall.SizeofRtAttr || l > len(b) {
+			return nil, fmt.Errorf("invalid netlink attribute length %d", l)
+		}
+		attrs = append(attrs, syscall.NetlinkRouteAttr{
+			Attr: syscall.RtAttr{
+				Len:  uint16(l),
+				Type: nativeEndian().Uint16(b[2:]),
+			},
+			Value: b[syscall.SizeofRtAttr:l],
+		})
+		l = (l + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+		if l > len(b) {
+			break
+		}
+		b = b[l:]
+	}
+	return attrs, nil
+}
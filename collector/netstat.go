@@ -4,77 +4,61 @@ package collector
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
-	"os"
 	"strconv"
 	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
 )
 
 const (
-	procNetStat       = "/proc/net/netstat"
 	netStatsSubsystem = "netstat"
 )
 
 type netStatCollector struct {
-	config  Config
-	metrics map[string]prometheus.Gauge
+	config Config
 }
 
 func init() {
-	Factories["netstat"] = NewNetStatCollector
+	registerCollector("netstat", defaultEnabled, NewNetStatCollector)
 }
 
 // NewNetStatCollector takes a config struct and returns
 // a new Collector exposing network stats.
 func NewNetStatCollector(config Config) (Collector, error) {
 	return &netStatCollector{
-		config:  config,
-		metrics: map[string]prometheus.Gauge{},
+		config: config,
 	}, nil
 }
 
-func (c *netStatCollector) Update(ch chan<- prometheus.Metric) (err error) {
-	netStats, err := getNetStats()
+func (c *netStatCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	netStats, err := getNetStats(ctx)
 	if err != nil {
 		return fmt.Errorf("couldn't get netstats: %s", err)
 	}
 	for protocol, protocolStats := range netStats {
 		for name, value := range protocolStats {
-			key := protocol + "_" + name
-			if _, ok := c.metrics[key]; !ok {
-				c.metrics[key] = prometheus.NewGauge(
-					prometheus.GaugeOpts{
-						Namespace: Namespace,
-						Subsystem: netStatsSubsystem,
-						Name:      key,
-						Help:      fmt.Sprintf("%s %s from /proc/net/netstat.", protocol, name),
-					},
-				)
-			}
 			v, err := strconv.ParseFloat(value, 64)
 			if err != nil {
 				return fmt.Errorf("invalid value %s in netstats: %s", value, err)
 			}
-			c.metrics[key].Set(v)
+			// The file mixes counters and gauges without telling them apart.
+			desc := newTypedDesc(netStatsSubsystem, protocol+"_"+name, fmt.Sprintf("%s %s from /proc/net/netstat.", protocol, name), prometheus.UntypedValue)
+			ch <- desc.mustNewConstMetric(v)
 		}
 	}
-	for _, m := range c.metrics {
-		m.Collect(ch)
-	}
 	return err
 }
 
-func getNetStats() (map[string]map[string]string, error) {
-	file, err := os.Open(procNetStat)
+func getNetStats(ctx context.Context) (map[string]map[string]string, error) {
+	data, err := readProcFile(ctx, "net/netstat")
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	return parseNetStats(file)
+	return parseNetStats(bytes.NewReader(data))
 }
 
 func parseNetStats(r io.Reader) (map[string]map[string]string, error) {
@@ -92,7 +76,7 @@ func parseNetStats(r io.Reader) (map[string]map[string]string, error) {
 		netStats[protocol] = map[string]string{}
 		if len(nameParts) != len(valueParts) {
 			return nil, fmt.Errorf("mismatch field count mismatch in %s: %s",
-				procNetStat, protocol)
+				procFilePath("net/netstat"), protocol)
 		}
 		for i := 1; i < len(nameParts); i++ {
 			netStats[protocol][nameParts[i]] = valueParts[i]
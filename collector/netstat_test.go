@@ -6,7 +6,7 @@ import (
 )
 
 func TestNetStats(t *testing.T) {
-	file, err := os.Open("fixtures/netstat")
+	file, err := os.Open("fixtures/proc/net/netstat")
 	if err != nil {
 		t.Fatal(err)
 	}
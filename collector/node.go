@@ -0,0 +1,162 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
+)
+
+// DefaultCollectors are the sorted names of the collectors registered
+// defaultEnabled, which are enabled when none are given.
+var DefaultCollectors []string
+
+// NodeCollector runs a set of collectors as a single prometheus.Collector.
+// Each collector runs isolated from the others, see Update, and with its
+// timeout, and its duration and success are reported along its metrics.
+type NodeCollector struct {
+	collectors map[string]Collector
+	timeouts   map[string]time.Duration
+	duration   *prometheus.Desc
+	success    *prometheus.Desc
+}
+
+// NewNodeCollector returns a NodeCollector running the named collectors, or
+// DefaultCollectors if no names are given, on each Collect, with the
+// timeouts set by the <name>_timeout entries of config. It lets other
+// programs register the node metrics with their own registry:
+//
+//	c, err := collector.NewNodeCollector(collector.Config{}, "meminfo", "loadavg")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	prometheus.MustRegister(c)
+//
+// A failing collector is logged and doesn't keep the others from reporting.
+// Collectors read their flags when created, so flag.Parse must be called
+// before.
+func NewNodeCollector(config Config, names ...string) (*NodeCollector, error) {
+	if len(names) == 0 {
+		names = DefaultCollectors
+	}
+	collectors := map[string]Collector{}
+	timeouts := map[string]time.Duration{}
+	for _, name := range names {
+		fn, ok := Factories[name]
+		if !ok {
+			return nil, fmt.Errorf("collector %q not available, available are: %s", name, strings.Join(availableCollectors(), ", "))
+		}
+		c, err := fn(config)
+		if IsNotApplicable(err) {
+			log.Infof("Leaving out collector %s: %s", name, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create collector %s: %s", name, err)
+		}
+		collectors[name] = c
+		if timeouts[name], err = config.Timeout(name, 0); err != nil {
+			return nil, err
+		}
+	}
+	return NewNodeCollectorWith(collectors, timeouts), nil
+}
+
+// NewNodeCollectorWith returns a NodeCollector running collectors that are
+// already set up, by name, with the timeouts given for them.
+func NewNodeCollectorWith(collectors map[string]Collector, timeouts map[string]time.Duration) *NodeCollector {
+	return &NodeCollector{
+		collectors: collectors,
+		timeouts:   timeouts,
+		duration: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, "scrape", "collector_duration_seconds"),
+			"node_exporter: Duration of the last run of a collector.",
+			[]string{"collector"}, nil,
+		),
+		success: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, "scrape", "collector_success"),
+			"node_exporter: Whether the last run of a collector succeeded.",
+			[]string{"collector"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (n *NodeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- n.duration
+	ch <- n.success
+}
+
+// Collect implements prometheus.Collector.
+func (n *NodeCollector) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+	ctx := WithScrapeCache(context.Background())
+	for name := range n.collectors {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if _, err := n.Execute(ctx, name, ch, n.timeouts[name]); err != nil {
+				log.With("collector", name).Errorf("Collector failed: %s", err)
+			}
+		}(name)
+	}
+	wg.Wait()
+}
+
+// Execute runs the named collector with Update, passing its metrics on to
+// ch followed by its duration and success, and returns how long it took.
+// The timeout replaces the collector's own, see Timeout.
+func (n *NodeCollector) Execute(ctx context.Context, name string, ch chan<- prometheus.Metric, timeout time.Duration) (time.Duration, error) {
+	c, ok := n.collectors[name]
+	if !ok {
+		return 0, fmt.Errorf("collector %q not enabled", name)
+	}
+	begin := time.Now()
+	err := Update(ctx, c, ch, timeout)
+	duration := time.Since(begin)
+	success := 1.0
+	if err != nil {
+		success = 0
+	}
+	ch <- prometheus.MustNewConstMetric(n.duration, prometheus.GaugeValue, duration.Seconds(), name)
+	ch <- prometheus.MustNewConstMetric(n.success, prometheus.GaugeValue, success, name)
+	return duration, err
+}
+
+// Collectors returns the collectors by name.
+func (n *NodeCollector) Collectors() map[string]Collector {
+	collectors := make(map[string]Collector, len(n.collectors))
+	for name, c := range n.collectors {
+		collectors[name] = c
+	}
+	return collectors
+}
+
+// Names returns the sorted names of the collectors.
+func (n *NodeCollector) Names() []string {
+	names := make([]string, 0, len(n.collectors))
+	for name := range n.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Timeout returns the timeout of the named collector, 0 if it has none.
+func (n *NodeCollector) Timeout(name string) time.Duration {
+	return n.timeouts[name]
+}
+
+func availableCollectors() []string {
+	names := make([]string, 0, len(Factories))
+	for name := range Factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
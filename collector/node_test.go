@@ -0,0 +1,167 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+type testCollector struct {
+	err error
+}
+
+func (c testCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	if c.err != nil {
+		return c.err
+	}
+	desc := newTypedDesc("test", "value", "Test value.", prometheus.GaugeValue)
+	ch <- desc.mustNewConstMetric(42)
+	return nil
+}
+
+func TestNewNodeCollector(t *testing.T) {
+	Factories["test_ok"] = func(Config) (Collector, error) { return testCollector{}, nil }
+	Factories["test_failing"] = func(Config) (Collector, error) { return testCollector{err: errors.New("failed")}, nil }
+	defer delete(Factories, "test_ok")
+	defer delete(Factories, "test_failing")
+
+	if _, err := NewNodeCollector(Config{}, "test_ok", "missing"); err == nil {
+		t.Error("want error for unknown collector")
+	}
+
+	c, err := NewNodeCollector(Config{}, "test_ok", "test_failing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan prometheus.Metric, 10)
+	c.Collect(ch)
+	close(ch)
+
+	success := map[string]float64{}
+	values := 0
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		switch m.Desc() {
+		case c.success:
+			success[pb.GetLabel()[0].GetValue()] = pb.GetGauge().GetValue()
+		case c.duration:
+		default:
+			values++
+		}
+	}
+	if values != 1 {
+		t.Errorf("want 1 metric of the succeeding collector, got %d", values)
+	}
+	if success["test_ok"] != 1 || success["test_failing"] != 0 {
+		t.Errorf("want success 1 and 0, got %v", success)
+	}
+}
+
+func TestNodeCollectorExecute(t *testing.T) {
+	n := NewNodeCollectorWith(map[string]Collector{
+		"ok":      testCollector{},
+		"failing": testCollector{err: errors.New("failed")},
+	}, nil)
+
+	for name, success := range map[string]float64{"ok": 1, "failing": 0} {
+		ch := make(chan prometheus.Metric, 3)
+		if _, err := n.Execute(context.Background(), name, ch, 0); (err == nil) != (success == 1) {
+			t.Errorf("%s: want success %f, got error %v", name, success, err)
+		}
+		close(ch)
+		var metrics []dto.Metric
+		for m := range ch {
+			var pb dto.Metric
+			if err := m.Write(&pb); err != nil {
+				t.Fatal(err)
+			}
+			metrics = append(metrics, pb)
+		}
+		if len(metrics) < 2 {
+			t.Fatalf("%s: want duration and success metrics, got %v", name, metrics)
+		}
+		duration, s := metrics[len(metrics)-2], metrics[len(metrics)-1]
+		if got := duration.GetGauge().GetValue(); got < 0 {
+			t.Errorf("%s: want a duration, got %f", name, got)
+		}
+		if want, got := success, s.GetGauge().GetValue(); want != got {
+			t.Errorf("%s: want success %f, got %f", name, want, got)
+		}
+		if want, got := name, s.Label[0].GetValue(); want != got {
+			t.Errorf("want collector label %q, got %q", want, got)
+		}
+	}
+	if _, err := n.Execute(context.Background(), "missing", make(chan prometheus.Metric, 2), 0); err == nil {
+		t.Error("want error for collector not enabled")
+	}
+}
+
+func TestNodeCollectorTimeout(t *testing.T) {
+	Factories["test_slow"] = func(Config) (Collector, error) {
+		return sleepCollector{metric: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"}), sleep: time.Second}, nil
+	}
+	defer delete(Factories, "test_slow")
+
+	c, err := NewNodeCollector(Config{Config: map[string]string{"test_slow_timeout": "10ms"}}, "test_slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 10*time.Millisecond, c.Timeout("test_slow"); want != got {
+		t.Errorf("want timeout %s, got %s", want, got)
+	}
+	ch := make(chan prometheus.Metric, 10)
+	begin := time.Now()
+	c.Collect(ch)
+	if d := time.Since(begin); d >= time.Second {
+		t.Errorf("want collect to end at the timeout, took %s", d)
+	}
+}
+
+func TestNotApplicableCollector(t *testing.T) {
+	Factories["test_ok"] = func(Config) (Collector, error) { return testCollector{}, nil }
+	Factories["test_not_applicable"] = func(Config) (Collector, error) { return nil, NotApplicable("no test hardware") }
+	defer delete(Factories, "test_ok")
+	defer delete(Factories, "test_not_applicable")
+
+	c, err := NewNodeCollector(Config{}, "test_ok", "test_not_applicable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1, len(c.Names()); want != got {
+		t.Errorf("want %d collectors, got %d", want, got)
+	}
+
+	if !IsNotApplicable(NotApplicable("no %s", "drbd")) {
+		t.Error("want not applicable error recognized")
+	}
+	if IsNotApplicable(errors.New("failed")) || IsNotApplicable(nil) {
+		t.Error("want other errors not to be not applicable")
+	}
+}
+
+func TestRegisterCollector(t *testing.T) {
+	defer func(old []string) { DefaultCollectors = old }(DefaultCollectors)
+	registerCollector("aaa_test_default", defaultEnabled, func(Config) (Collector, error) { return testCollector{}, nil })
+	registerCollector("aaa_test_optional", defaultDisabled, func(Config) (Collector, error) { return testCollector{}, nil })
+	defer delete(Factories, "aaa_test_default")
+	defer delete(Factories, "aaa_test_optional")
+
+	if _, ok := Factories["aaa_test_optional"]; !ok {
+		t.Error("want optional collector registered")
+	}
+	if want, got := "aaa_test_default", DefaultCollectors[0]; want != got {
+		t.Errorf("want %s first of the default collectors, got %s", want, got)
+	}
+	for _, name := range DefaultCollectors {
+		if name == "aaa_test_optional" {
+			t.Error("want optional collector not enabled by default")
+		}
+	}
+}
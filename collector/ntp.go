@@ -8,8 +8,9 @@ import (
 	"time"
 
 	"github.com/beevik/ntp"
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -17,21 +18,24 @@ var (
 )
 
 type ntpCollector struct {
-	drift prometheus.Gauge
+	server string
+	drift  prometheus.Gauge
 }
 
 func init() {
-	Factories["ntp"] = NewNtpCollector
+	registerCollector("ntp", defaultDisabled, NewNtpCollector)
 }
 
 // Takes a config struct and prometheus registry and returns a new Collector exposing
 // the offset between ntp and the current system time.
 func NewNtpCollector(config Config) (Collector, error) {
-	if *ntpServer == "" {
-		return nil, fmt.Errorf("No NTP server specifies, see --ntpServer")
+	server := config.Options("ntp").String("server")
+	if server == "" {
+		return nil, fmt.Errorf("No NTP server specified, see --collector.ntp.server")
 	}
 
 	return &ntpCollector{
+		server: server,
 		drift: prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: Namespace,
 			Name:      "ntp_drift_seconds",
@@ -40,13 +44,13 @@ func NewNtpCollector(config Config) (Collector, error) {
 	}, nil
 }
 
-func (c *ntpCollector) Update(ch chan<- prometheus.Metric) (err error) {
-	t, err := ntp.Time(*ntpServer)
+func (c *ntpCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	t, err := ntp.Time(c.server)
 	if err != nil {
 		return fmt.Errorf("Couldn't get ntp drift: %s", err)
 	}
 	drift := t.Sub(time.Now())
-	glog.V(1).Infof("Set ntp_drift_seconds: %f", drift.Seconds())
+	log.Debugf("Set ntp_drift_seconds: %f", drift.Seconds())
 	c.drift.Set(drift.Seconds())
 	c.drift.Collect(ch)
 	return err
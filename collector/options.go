@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options holds the options of a collector, keyed like its flags without the
+// collector.<name>. prefix, e.g. server for -collector.ntp.server.
+type Options map[string]string
+
+// Options returns the options of the named collector. Defaults of its
+// collector.<name>.<option> flags are overridden by the <name>_<option>
+// entries of c.Config, then by c.Collectors[name], then by the flags set on
+// the command line or by the config file.
+func (c Config) Options(name string) Options {
+	prefix := "collector." + name + "."
+	options := Options{}
+	flag.VisitAll(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Name, prefix) {
+			options[strings.TrimPrefix(f.Name, prefix)] = f.DefValue
+		}
+	})
+	for k, v := range c.Config {
+		if strings.HasPrefix(k, name+"_") {
+			options[strings.TrimPrefix(k, name+"_")] = v
+		}
+	}
+	for k, v := range c.Collectors[name] {
+		options[k] = v
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Name, prefix) {
+			options[strings.TrimPrefix(f.Name, prefix)] = f.Value.String()
+		}
+	})
+	return options
+}
+
+// Timeout returns the timeout of the named collector set by the
+// <name>_timeout entry of c.Config, or def if there is none.
+func (c Config) Timeout(name string, def time.Duration) (time.Duration, error) {
+	s, ok := c.Config[name+"_timeout"]
+	if !ok {
+		return def, nil
+	}
+	timeout, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s_timeout: %s", name, err)
+	}
+	return timeout, nil
+}
+
+// String returns the option, or "" if it is not set.
+func (o Options) String(name string) string {
+	return o[name]
+}
+
+// Strings returns the comma-separated values of the option, leaving out
+// empty ones.
+func (o Options) Strings(name string) []string {
+	values := []string{}
+	for _, v := range strings.Split(o[name], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Bool returns the option as a bool, false if it is not set.
+func (o Options) Bool(name string) (bool, error) {
+	if o[name] == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(o[name])
+	if err != nil {
+		return false, fmt.Errorf("invalid value %q for option %s: %s", o[name], name, err)
+	}
+	return b, nil
+}
+
+// Int returns the option as an int, 0 if it is not set.
+func (o Options) Int(name string) (int, error) {
+	if o[name] == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(o[name])
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q for option %s: %s", o[name], name, err)
+	}
+	return i, nil
+}
+
+// Duration returns the option as a duration like 10s, 0 if it is not set.
+func (o Options) Duration(name string) (time.Duration, error) {
+	if o[name] == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(o[name])
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q for option %s: %s", o[name], name, err)
+	}
+	return d, nil
+}
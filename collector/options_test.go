@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOptions(t *testing.T) {
+	defer func(old string) { flag.Set("collector.exec.commands", old) }(*execCommands)
+
+	config := Config{
+		Config:     map[string]string{"exec_command-timeout": "1m", "exec_uptime": "uptime"},
+		Collectors: map[string]Options{"exec": {"command-timeout": "5s", "max-concurrency": "x"}},
+	}
+	options := config.Options("exec")
+	if want, got := "x", options.String("max-concurrency"); want != got {
+		t.Errorf("want max-concurrency %q from config, got %q", want, got)
+	}
+	if d, err := options.Duration("command-timeout"); err != nil || d != 5*time.Second {
+		t.Errorf("want command-timeout 5s, got %s, %v", d, err)
+	}
+	if _, err := options.Int("max-concurrency"); err == nil {
+		t.Error("want error for invalid max-concurrency")
+	}
+	if want, got := "uptime", options.String("uptime"); want != got {
+		t.Errorf("want uptime %q, got %q", want, got)
+	}
+
+	flag.Set("collector.exec.commands", "uptime, ,true")
+	if want, got := []string{"uptime", "true"}, config.Options("exec").Strings("commands"); !reflect.DeepEqual(want, got) {
+		t.Errorf("want commands %v from the flag, got %v", want, got)
+	}
+	if want, got := []string{}, (Options{}).Strings("commands"); !reflect.DeepEqual(want, got) {
+		t.Errorf("want no commands, got %v", got)
+	}
+}
+
+func TestConfigTimeout(t *testing.T) {
+	config := Config{Config: map[string]string{"filesystem_timeout": "30s", "stat_timeout": "x"}}
+	for name, want := range map[string]time.Duration{
+		"filesystem": 30 * time.Second,
+		"meminfo":    time.Minute,
+	} {
+		got, err := config.Timeout(name, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want != got {
+			t.Errorf("%s: want timeout %s, got %s", name, want, got)
+		}
+	}
+	if _, err := config.Timeout("stat", time.Minute); err == nil {
+		t.Error("want error for invalid timeout")
+	}
+}
@@ -0,0 +1,136 @@
+// +build !nopathsize
+
+package collector
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
+)
+
+const pathSubsystem = "path"
+
+var (
+	sizePaths = flag.String("collector.pathsize.paths", "", "Comma-separated list of files and directories to export the total size of.")
+)
+
+type pathSizeCollector struct {
+	config                   Config
+	paths                    []string
+	size, files, newestMtime *prometheus.GaugeVec
+}
+
+type pathStats struct {
+	size        int64
+	files       int
+	newestMtime float64
+}
+
+func init() {
+	registerCollector("pathsize", defaultDisabled, NewPathSizeCollector)
+}
+
+// NewPathSizeCollector returns a new Collector exposing the total size, the
+// number of files and the newest mtime below each configured path. Paths are
+// taken from --collector.pathsize.paths and the pathsize_paths config entry.
+func NewPathSizeCollector(config Config) (Collector, error) {
+	paths := []string{}
+	for _, list := range []string{*sizePaths, config.Config["pathsize_paths"]} {
+		for _, p := range strings.Split(list, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				paths = append(paths, p)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("No paths specified, see --collector.pathsize.paths")
+	}
+
+	var pathLabelNames = []string{"path"}
+
+	return &pathSizeCollector{
+		config: config,
+		paths:  paths,
+		size: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: pathSubsystem,
+				Name:      "size_bytes",
+				Help:      "Total size of all regular files below a path in bytes.",
+			},
+			pathLabelNames,
+		),
+		files: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: pathSubsystem,
+				Name:      "files",
+				Help:      "Number of regular files below a path.",
+			},
+			pathLabelNames,
+		),
+		newestMtime: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: pathSubsystem,
+				Name:      "newest_mtime",
+				Help:      "Unixtime mtime of the most recently modified file below a path.",
+			},
+			pathLabelNames,
+		),
+	}, nil
+}
+
+func (c *pathSizeCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	for _, p := range c.paths {
+		stats, err := walkPath(ctx, p)
+		if err != nil {
+			return fmt.Errorf("couldn't get size of %s: %s", p, err)
+		}
+		c.size.WithLabelValues(p).Set(float64(stats.size))
+		c.files.WithLabelValues(p).Set(float64(stats.files))
+		c.newestMtime.WithLabelValues(p).Set(stats.newestMtime)
+	}
+	c.size.Collect(ch)
+	c.files.Collect(ch)
+	c.newestMtime.Collect(ch)
+	return nil
+}
+
+// walkPath sums up the regular files below root, which may be a single file.
+// Files vanishing or unreadable during the walk are skipped. The walk is
+// aborted with the error of ctx once it is done.
+func walkPath(ctx context.Context, root string) (pathStats, error) {
+	stats := pathStats{}
+	if _, err := os.Lstat(root); err != nil {
+		return stats, err
+	}
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			log.Debugf("Skipping %s: %s", p, err)
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		stats.size += info.Size()
+		stats.files++
+		if mtime := float64(info.ModTime().UnixNano()) / 1e9; mtime > stats.newestMtime {
+			stats.newestMtime = mtime
+		}
+		return nil
+	})
+	return stats, err
+}
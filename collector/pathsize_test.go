@@ -0,0 +1,44 @@
+package collector
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestWalkPath(t *testing.T) {
+	stats, err := walkPath(context.Background(), "fixtures/pathsize")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := int64(20), stats.size; want != got {
+		t.Errorf("want path size %d, got %d", want, got)
+	}
+	if want, got := 3, stats.files; want != got {
+		t.Errorf("want path files %d, got %d", want, got)
+	}
+	if stats.newestMtime == 0 {
+		t.Error("want newest mtime to be set")
+	}
+
+	stats, err = walkPath(context.Background(), "fixtures/pathsize/spool/queued")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := int64(11), stats.size; want != got {
+		t.Errorf("want file size %d, got %d", want, got)
+	}
+
+	if _, err := walkPath(context.Background(), "fixtures/pathsize/missing"); err == nil {
+		t.Error("want error for missing path")
+	}
+}
+
+func TestWalkPathCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := walkPath(ctx, "fixtures/pathsize"); err != context.Canceled {
+		t.Errorf("want error %s, got %v", context.Canceled, err)
+	}
+}
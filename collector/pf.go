@@ -0,0 +1,250 @@
+// +build !nopf
+// +build openbsd freebsd
+
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	defaultPfctl = "pfctl"
+	pfDevice     = "/dev/pf"
+	pfSubsystem  = "pf"
+)
+
+var (
+	diocGetStatus = pfIOWR(21, pfStatus.size)
+	// DIOCGETLIMIT takes a struct pfioc_limit of the int index of the pool
+	// and the uint limit.
+	diocGetLimit = pfIOWR(39, 8)
+)
+
+// pfOperations are the table sections of "pfctl -s info" with searches,
+// inserts and removals, by the prefix of the metrics of their entries.
+var pfOperations = map[string]string{
+	"state":    "State Table",
+	"src_node": "Source Tracking Table",
+}
+
+type pfCollector struct {
+	config Config
+	// pfctl is empty if there is none.
+	pfctl string
+
+	running             typedDesc
+	states              typedDesc
+	statesHalfOpen      typedDesc
+	srcNodes            typedDesc
+	operations          map[string]typedDesc
+	counter             typedDesc
+	limitCounter        typedDesc
+	limit               typedDesc
+	ifaceBytes          typedDesc
+	ifacePackets        typedDesc
+	queuePackets        typedDesc
+	queueBytes          typedDesc
+	queueDroppedPackets typedDesc
+	queueDroppedBytes   typedDesc
+	queueLength         typedDesc
+	queueLimit          typedDesc
+}
+
+func init() {
+	registerCollector("pf", defaultDisabled, NewPfCollector)
+}
+
+// NewPfCollector returns a new Collector exposing the state table, counters
+// and limits of the pf firewall from the DIOCGETSTATUS and DIOCGETLIMIT
+// ioctls of /dev/pf, and its queues through pfctl. pfctl is the fallback
+// where /dev/pf can't be opened or the kernel rejects the ioctls, as the
+// request encodes the size of struct pf_status, which changes between
+// releases.
+func NewPfCollector(config Config) (Collector, error) {
+	pfctl := defaultPfctl
+	if config.Config["pf_pfctl_command"] != "" {
+		pfctl = config.Config["pf_pfctl_command"]
+	}
+	if _, err := exec.LookPath(pfctl); err != nil {
+		f, derr := os.Open(pfDevice)
+		if derr != nil {
+			return nil, NotApplicable("no %s: %s, and no %s: %s", pfDevice, derr, pfctl, err)
+		}
+		f.Close()
+		pfctl = ""
+	}
+
+	operations := map[string]typedDesc{}
+	for prefix, section := range pfOperations {
+		operations[prefix] = newTypedDesc(pfSubsystem, prefix+"_operations", fmt.Sprintf("Searches, inserts and removals of the %s.", strings.ToLower(section)), prometheus.CounterValue, "operation")
+	}
+	queueLabels := []string{"queue", "interface"}
+	return &pfCollector{
+		config:              config,
+		pfctl:               pfctl,
+		running:             newTypedDesc(pfSubsystem, "running", "Whether pf is enabled, 1 or 0.", prometheus.GaugeValue),
+		states:              newTypedDesc(pfSubsystem, "states", "Number of entries of the state table.", prometheus.GaugeValue),
+		statesHalfOpen:      newTypedDesc(pfSubsystem, "states_half_open", "Number of half-open TCP entries of the state table.", prometheus.GaugeValue),
+		srcNodes:            newTypedDesc(pfSubsystem, "src_nodes", "Number of entries of the source tracking table.", prometheus.GaugeValue),
+		operations:          operations,
+		counter:             newTypedDesc(pfSubsystem, "counter", "Packets counted by pf: match for those matching a rule, the others by reason of being dropped.", prometheus.CounterValue, "counter"),
+		limitCounter:        newTypedDesc(pfSubsystem, "limit_counter", "Times limits of rules were hit and syncookies were used.", prometheus.CounterValue, "counter"),
+		limit:               newTypedDesc(pfSubsystem, "limit", "Hard limits of the memory pools of pf, like states for the state table.", prometheus.GaugeValue, "pool"),
+		ifaceBytes:          newTypedDesc(pfSubsystem, "interface_bytes", "Bytes of the loginterface by direction and address family.", prometheus.CounterValue, "interface", "direction", "family"),
+		ifacePackets:        newTypedDesc(pfSubsystem, "interface_packets", "Packets of the loginterface passed or blocked by direction and address family.", prometheus.CounterValue, "interface", "direction", "action", "family"),
+		queuePackets:        newTypedDesc(pfSubsystem, "queue_packets", "Packets sent through the queue.", prometheus.CounterValue, queueLabels...),
+		queueBytes:          newTypedDesc(pfSubsystem, "queue_bytes", "Bytes sent through the queue.", prometheus.CounterValue, queueLabels...),
+		queueDroppedPackets: newTypedDesc(pfSubsystem, "queue_dropped_packets", "Packets dropped by the queue.", prometheus.CounterValue, queueLabels...),
+		queueDroppedBytes:   newTypedDesc(pfSubsystem, "queue_dropped_bytes", "Bytes dropped by the queue.", prometheus.CounterValue, queueLabels...),
+		queueLength:         newTypedDesc(pfSubsystem, "queue_length", "Number of packets in the queue.", prometheus.GaugeValue, queueLabels...),
+		queueLimit:          newTypedDesc(pfSubsystem, "queue_limit", "Maximum number of packets in the queue.", prometheus.GaugeValue, queueLabels...),
+	}, nil
+}
+
+func (c *pfCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	info, limits, err := c.read()
+	if err != nil {
+		return err
+	}
+
+	running := 0.0
+	if info.running {
+		running = 1
+	}
+	ch <- c.running.mustNewConstMetric(running)
+	ch <- c.states.mustNewConstMetric(info.sections["State Table"]["current entries"])
+	// Only tracked by OpenBSD.
+	if halfOpen, ok := info.sections["State Table"]["half-open tcp"]; ok {
+		ch <- c.statesHalfOpen.mustNewConstMetric(halfOpen)
+	}
+	ch <- c.srcNodes.mustNewConstMetric(info.sections["Source Tracking Table"]["current entries"])
+	for prefix, section := range pfOperations {
+		for name, value := range info.sections[section] {
+			if name != "current entries" && name != "half-open tcp" {
+				ch <- c.operations[prefix].mustNewConstMetric(value, name)
+			}
+		}
+	}
+	for name, value := range info.sections["Counters"] {
+		ch <- c.counter.mustNewConstMetric(value, name)
+	}
+	for name, value := range info.sections["Limit Counters"] {
+		ch <- c.limitCounter.mustNewConstMetric(value, name)
+	}
+	for pool, limit := range limits {
+		ch <- c.limit.mustNewConstMetric(limit, pool)
+	}
+	for name, values := range info.ifaceStats {
+		fields := strings.Fields(strings.ToLower(name))
+		for i, family := range []string{"inet", "inet6"} {
+			switch {
+			case len(fields) == 2 && fields[0] == "bytes":
+				ch <- c.ifaceBytes.mustNewConstMetric(values[i], info.iface, fields[1], family)
+			case len(fields) == 3 && fields[0] == "packets":
+				ch <- c.ifacePackets.mustNewConstMetric(values[i], info.iface, fields[1], fields[2], family)
+			}
+		}
+	}
+
+	if c.pfctl == "" {
+		return nil
+	}
+	// Without queues configured, or without ALTQ in the kernel of
+	// FreeBSD, listing them fails.
+	out, err := c.run("-s", "queue", "-v")
+	if err != nil {
+		log.Debugf("Couldn't list pf queues: %s", err)
+		return nil
+	}
+	queues, err := parsePfctlQueues(bytes.NewReader(out))
+	if err != nil {
+		return err
+	}
+	for _, q := range queues {
+		ch <- c.queuePackets.mustNewConstMetric(q.packets, q.name, q.iface)
+		ch <- c.queueBytes.mustNewConstMetric(q.bytes, q.name, q.iface)
+		ch <- c.queueDroppedPackets.mustNewConstMetric(q.droppedPackets, q.name, q.iface)
+		ch <- c.queueDroppedBytes.mustNewConstMetric(q.droppedBytes, q.name, q.iface)
+		ch <- c.queueLength.mustNewConstMetric(q.length, q.name, q.iface)
+		ch <- c.queueLimit.mustNewConstMetric(q.limit, q.name, q.iface)
+	}
+	return nil
+}
+
+// read returns the status and the limits by pool of /dev/pf, or of pfctl if
+// reading /dev/pf fails.
+func (c *pfCollector) read() (pfInfo, map[string]float64, error) {
+	info, limits, err := readPfDevice()
+	if err == nil || c.pfctl == "" {
+		return info, limits, err
+	}
+	log.Debugf("Couldn't read %s, running pfctl: %s", pfDevice, err)
+
+	out, err := c.run("-s", "info", "-v")
+	if err != nil {
+		return pfInfo{}, nil, err
+	}
+	if info, err = parsePfctlInfo(bytes.NewReader(out)); err != nil {
+		return pfInfo{}, nil, err
+	}
+	if out, err = c.run("-s", "memory"); err != nil {
+		return pfInfo{}, nil, err
+	}
+	if limits, err = parsePfctlMemory(bytes.NewReader(out)); err != nil {
+		return pfInfo{}, nil, err
+	}
+	return info, limits, nil
+}
+
+func readPfDevice() (pfInfo, map[string]float64, error) {
+	f, err := os.Open(pfDevice)
+	if err != nil {
+		return pfInfo{}, nil, err
+	}
+	defer f.Close()
+
+	status := make([]byte, pfStatus.size)
+	if err := pfIoctl(f, diocGetStatus, status); err != nil {
+		return pfInfo{}, nil, fmt.Errorf("couldn't get pf status: %s", err)
+	}
+	info, err := parsePfStatus(status, pfStatus, nativeEndian())
+	if err != nil {
+		return pfInfo{}, nil, err
+	}
+	limits := make(map[string]float64, len(pfStatus.limits))
+	for i, pool := range pfStatus.limits {
+		limit := make([]byte, 8)
+		nativeEndian().PutUint32(limit, uint32(i))
+		if err := pfIoctl(f, diocGetLimit, limit); err != nil {
+			return pfInfo{}, nil, fmt.Errorf("couldn't get pf limit of %s: %s", pool, err)
+		}
+		limits[pool] = float64(nativeEndian().Uint32(limit[4:]))
+	}
+	return info, limits, nil
+}
+
+func pfIoctl(f *os.File, req uintptr, data []byte) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), req, uintptr(unsafe.Pointer(&data[0])))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func (c *pfCollector) run(args ...string) ([]byte, error) {
+	out, err := exec.Command(c.pfctl, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't run pfctl %s: %s", strings.Join(args, " "), err)
+	}
+	return out, nil
+}
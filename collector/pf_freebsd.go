@@ -0,0 +1,5 @@
+// +build !nopf,freebsd
+
+package collector
+
+var pfStatus = pfStatusFreeBSD
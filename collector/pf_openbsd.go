@@ -0,0 +1,5 @@
+// +build !nopf,openbsd
+
+package collector
+
+var pfStatus = pfStatusOpenBSD
@@ -0,0 +1,310 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The pf collector reads the DIOCGETSTATUS and DIOCGETLIMIT ioctls of
+// /dev/pf, and pfctl(8) for the queues and where the ioctls fail. The
+// parsers below are untagged so that they are tested on any platform.
+
+// pfInfo holds the output of "pfctl -s info -v".
+type pfInfo struct {
+	running bool
+	// sections holds the totals of the tables like "State Table" and
+	// "Counters" by section and name.
+	sections map[string]map[string]float64
+	// iface is the loginterface, whose IPv4 and IPv6 stats are held by
+	// names like "Bytes In" and "Packets In Blocked".
+	iface      string
+	ifaceStats map[string][2]float64
+}
+
+// pfInterfaceSection is the section of the loginterface stats in pfInfo.
+const pfInterfaceSection = "Interface Stats"
+
+// parsePfctlInfo parses the output of "pfctl -s info -v". Sections start
+// unindented, their entries are indented names followed by the total and
+// the rate.
+func parsePfctlInfo(r io.Reader) (pfInfo, error) {
+	var (
+		info = pfInfo{
+			sections:   map[string]map[string]float64{},
+			ifaceStats: map[string][2]float64{},
+		}
+		scanner = bufio.NewScanner(r)
+		section string
+		group   string
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "Status: ") {
+			info.running = strings.HasPrefix(line, "Status: Enabled")
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			section, group = strings.SplitN(line, "  ", 2)[0], ""
+			if strings.HasPrefix(section, "Interface Stats for ") {
+				info.iface = strings.Fields(section)[3]
+				section = pfInterfaceSection
+			}
+			continue
+		}
+		name, values := splitPfctlEntry(line)
+		if section == pfInterfaceSection {
+			if len(values) == 0 {
+				group = name
+				continue
+			}
+			if len(values) != 2 {
+				return pfInfo{}, fmt.Errorf("invalid interface stats line: %s", line)
+			}
+			if strings.HasPrefix(line, "    ") {
+				name = group + " " + name
+			}
+			info.ifaceStats[name] = [2]float64{values[0], values[1]}
+			continue
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if info.sections[section] == nil {
+			info.sections[section] = map[string]float64{}
+		}
+		info.sections[section][name] = values[0]
+	}
+	return info, scanner.Err()
+}
+
+// splitPfctlEntry splits a line like "  current entries   9874" into the
+// name up to the first number and the numbers after it. Rates like "3.9/s"
+// are left out.
+func splitPfctlEntry(line string) (string, []float64) {
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		if _, err := strconv.ParseFloat(f, 64); err != nil {
+			continue
+		}
+		var values []float64
+		for _, f := range fields[i:] {
+			if v, err := strconv.ParseFloat(f, 64); err == nil {
+				values = append(values, v)
+			}
+		}
+		return strings.Join(fields[:i], " "), values
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// parsePfctlMemory parses the hard limits of the memory pools of
+// "pfctl -s memory".
+func parsePfctlMemory(r io.Reader) (map[string]float64, error) {
+	limits := map[string]float64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 4 || fields[1] != "hard" || fields[2] != "limit" {
+			return nil, fmt.Errorf("invalid pfctl memory line: %s", scanner.Text())
+		}
+		limit, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit of %s: %s", fields[0], err)
+		}
+		limits[fields[0]] = limit
+	}
+	return limits, scanner.Err()
+}
+
+// pfQueue holds the stats of a queue of "pfctl -s queue -v".
+type pfQueue struct {
+	name, iface                  string
+	packets, bytes               float64
+	droppedPackets, droppedBytes float64
+	length, limit                float64
+}
+
+var (
+	pfQueueCountersRE = regexp.MustCompile(`pkts:\s*(\d+)\s+bytes:\s*(\d+)\s+dropped pkts:\s*(\d+)\s+bytes:\s*(\d+)`)
+	pfQueueLengthRE   = regexp.MustCompile(`qlength:\s*(\d+)/\s*(\d+)`)
+)
+
+// parsePfctlQueues parses "pfctl -s queue -v", a "queue <name> on
+// <interface>" line per queue followed by bracketed stats lines, in the same
+// format for the queues of OpenBSD and the ALTQ of FreeBSD.
+func parsePfctlQueues(r io.Reader) ([]pfQueue, error) {
+	var (
+		queues  []pfQueue
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "queue" {
+			if len(fields) < 4 || fields[2] != "on" {
+				return nil, fmt.Errorf("invalid pfctl queue line: %s", line)
+			}
+			queues = append(queues, pfQueue{name: fields[1], iface: fields[3]})
+			continue
+		}
+		if len(queues) == 0 {
+			return nil, fmt.Errorf("pfctl queue stats without queue: %s", line)
+		}
+		q := &queues[len(queues)-1]
+		if m := pfQueueCountersRE.FindStringSubmatch(line); m != nil {
+			q.packets, _ = strconv.ParseFloat(m[1], 64)
+			q.bytes, _ = strconv.ParseFloat(m[2], 64)
+			q.droppedPackets, _ = strconv.ParseFloat(m[3], 64)
+			q.droppedBytes, _ = strconv.ParseFloat(m[4], 64)
+		}
+		if m := pfQueueLengthRE.FindStringSubmatch(line); m != nil {
+			q.length, _ = strconv.ParseFloat(m[1], 64)
+			q.limit, _ = strconv.ParseFloat(m[2], 64)
+		}
+	}
+	return queues, scanner.Err()
+}
+
+// pfStatusLayout describes struct pf_status, which differs between OpenBSD
+// and FreeBSD: the uint64 counters, limit counters, state table and source
+// tracking table counters, then the packet counters by address family,
+// direction and pass or drop and the byte counters by address family and
+// direction, followed by the offsets of the uint32 fields and the
+// loginterface. Entries are named like pfctl does.
+type pfStatusLayout struct {
+	size                      int
+	counters, limitCounters   []string
+	running, states, srcNodes int
+	statesHalfOpen            int // -1 if not tracked
+	ifname                    int
+	limits                    []string // memory pools by PF_LIMIT_* index
+}
+
+var (
+	pfTableCounters = []string{"searches", "inserts", "removals"}
+
+	// pfStatusOpenBSD is struct pf_status of OpenBSD 6.7 and later, with the
+	// syncookie counters.
+	pfStatusOpenBSD = pfStatusLayout{
+		size: 488,
+		counters: []string{
+			"match", "bad-offset", "fragment", "short", "normalize", "memory",
+			"bad-timestamp", "congestion", "ip-option", "proto-cksum",
+			"state-mismatch", "state-insert", "state-limit", "src-limit",
+			"synproxy", "translate", "no-route",
+		},
+		limitCounters: []string{
+			"max states per rule", "max-src-states", "max-src-nodes",
+			"max-src-conn", "max-src-conn-rate", "overload table insertion",
+			"overload flush states", "synfloods detected", "syncookies sent",
+			"syncookies validated",
+		},
+		running:        424,
+		states:         428,
+		statesHalfOpen: 432,
+		srcNodes:       436,
+		ifname:         456,
+		limits:         []string{"states", "src-nodes", "frags", "tables", "table-entries", "pktdelay-pkts", "anchors"},
+	}
+
+	// pfStatusFreeBSD is struct pf_status of FreeBSD, which DIOCGETSTATUS
+	// still returns next to DIOCGETSTATUSNV.
+	pfStatusFreeBSD = pfStatusLayout{
+		size: 416,
+		counters: []string{
+			"match", "bad-offset", "fragment", "short", "normalize", "memory",
+			"bad-timestamp", "congestion", "ip-option", "proto-cksum",
+			"state-mismatch", "state-insert", "state-limit", "src-limit",
+			"synproxy", "map-failed",
+		},
+		limitCounters: []string{
+			"max states per rule", "max-src-states", "max-src-nodes",
+			"max-src-conn", "max-src-conn-rate", "overload table insertion",
+			"overload flush states",
+		},
+		running:        360,
+		states:         364,
+		statesHalfOpen: -1,
+		srcNodes:       368,
+		ifname:         384,
+		limits:         []string{"states", "src-nodes", "frags", "table-entries"},
+	}
+)
+
+// pfIOWR returns the request of an ioctl of /dev/pf reading and writing size
+// bytes, _IOWR('D', num, size) of the BSDs.
+func pfIOWR(num, size int) uintptr {
+	return 0xc0000000 | uintptr(size&0x1fff)<<16 | 'D'<<8 | uintptr(num)
+}
+
+// parsePfStatus returns the status of a struct pf_status of the layout in
+// the sections of "pfctl -s info -v".
+func parsePfStatus(data []byte, layout pfStatusLayout, order binary.ByteOrder) (pfInfo, error) {
+	if len(data) != layout.size {
+		return pfInfo{}, fmt.Errorf("invalid length %d of pf status, expected %d", len(data), layout.size)
+	}
+	offset := 0
+	uint64s := func(names []string) map[string]float64 {
+		values := make(map[string]float64, len(names))
+		for _, name := range names {
+			values[name] = float64(order.Uint64(data[offset:]))
+			offset += 8
+		}
+		return values
+	}
+	info := pfInfo{
+		running: order.Uint32(data[layout.running:]) != 0,
+		sections: map[string]map[string]float64{
+			"Counters":              uint64s(layout.counters),
+			"Limit Counters":        uint64s(layout.limitCounters),
+			"State Table":           uint64s(pfTableCounters),
+			"Source Tracking Table": uint64s(pfTableCounters),
+		},
+		ifaceStats: map[string][2]float64{},
+	}
+	info.sections["State Table"]["current entries"] = float64(order.Uint32(data[layout.states:]))
+	if layout.statesHalfOpen >= 0 {
+		info.sections["State Table"]["half-open tcp"] = float64(order.Uint32(data[layout.statesHalfOpen:]))
+	}
+	info.sections["Source Tracking Table"]["current entries"] = float64(order.Uint32(data[layout.srcNodes:]))
+
+	iface := data[layout.ifname : layout.ifname+16]
+	if i := bytes.IndexByte(iface, 0); i >= 0 {
+		iface = iface[:i]
+	}
+	if len(iface) == 0 {
+		return info, nil
+	}
+	info.iface = string(iface)
+	// pcounters[family][direction][pass, drop, scrub] and
+	// bcounters[family][direction].
+	packets := func(dir, action int) [2]float64 {
+		return [2]float64{
+			float64(order.Uint64(data[offset+8*(3*dir+action):])),
+			float64(order.Uint64(data[offset+8*(6+3*dir+action):])),
+		}
+	}
+	info.ifaceStats["Packets In Passed"] = packets(0, 0)
+	info.ifaceStats["Packets In Blocked"] = packets(0, 1)
+	info.ifaceStats["Packets Out Passed"] = packets(1, 0)
+	info.ifaceStats["Packets Out Blocked"] = packets(1, 1)
+	offset += 12 * 8
+	info.ifaceStats["Bytes In"] = [2]float64{float64(order.Uint64(data[offset:])), float64(order.Uint64(data[offset+16:]))}
+	info.ifaceStats["Bytes Out"] = [2]float64{float64(order.Uint64(data[offset+8:])), float64(order.Uint64(data[offset+24:]))}
+	return info, nil
+}
@@ -0,0 +1,156 @@
+package collector
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+)
+
+func TestParsePfctlInfo(t *testing.T) {
+	file, err := os.Open("fixtures/pfctl_info.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	info, err := parsePfctlInfo(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.running {
+		t.Error("want pf running")
+	}
+	for _, tt := range []struct {
+		section, name string
+		want          float64
+	}{
+		{"State Table", "current entries", 9874},
+		{"State Table", "searches", 468939193},
+		{"Source Tracking Table", "inserts", 0},
+		{"Counters", "state-mismatch", 410},
+		{"Limit Counters", "max states per rule", 0},
+	} {
+		got, ok := info.sections[tt.section][tt.name]
+		if !ok || got != tt.want {
+			t.Errorf("want %s %s %f, got %f", tt.section, tt.name, tt.want, got)
+		}
+	}
+	if want, got := "em0", info.iface; want != got {
+		t.Errorf("want loginterface %s, got %s", want, got)
+	}
+	if want, got := [2]float64{10113, 42}, info.ifaceStats["Packets In Blocked"]; want != got {
+		t.Errorf("want blocked packets in %v, got %v", want, got)
+	}
+	if want, got := [2]float64{191851544, 0}, info.ifaceStats["Bytes Out"]; want != got {
+		t.Errorf("want bytes out %v, got %v", want, got)
+	}
+}
+
+func TestParsePfctlMemory(t *testing.T) {
+	file, err := os.Open("fixtures/pfctl_memory.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	limits, err := parsePfctlMemory(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 7, len(limits); want != got {
+		t.Fatalf("want %d limits, got %d", want, got)
+	}
+	if want, got := 100000.0, limits["states"]; want != got {
+		t.Errorf("want states limit %f, got %f", want, got)
+	}
+}
+
+func TestParsePfctlQueues(t *testing.T) {
+	file, err := os.Open("fixtures/pfctl_queue.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	queues, err := parsePfctlQueues(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 3, len(queues); want != got {
+		t.Fatalf("want %d queues, got %d", want, got)
+	}
+	want := pfQueue{name: "std", iface: "em0", packets: 532104, bytes: 412370118, droppedPackets: 17, droppedBytes: 25432, length: 3, limit: 50}
+	if got := queues[1]; want != got {
+		t.Errorf("want queue %+v, got %+v", want, got)
+	}
+}
+
+func TestParsePfStatus(t *testing.T) {
+	layout := pfStatusOpenBSD
+	data := make([]byte, layout.size)
+	order := binary.LittleEndian
+	// The counters: match, then the limit, state table and source tracking
+	// table counters, then the packet and byte counters.
+	order.PutUint64(data, 4596651)
+	fcounters := 8 * (len(layout.counters) + len(layout.limitCounters))
+	order.PutUint64(data[fcounters:], 468939193)
+	pcounters := fcounters + 6*8
+	order.PutUint64(data[pcounters+8*1:], 10113)        // inet, in, drop
+	order.PutUint64(data[pcounters+8*7:], 42)           // inet6, in, drop
+	order.PutUint64(data[pcounters+8*12+8:], 191851544) // inet, out
+	order.PutUint32(data[layout.running:], 1)
+	order.PutUint32(data[layout.states:], 9874)
+	order.PutUint32(data[layout.statesHalfOpen:], 112)
+	copy(data[layout.ifname:], "em0")
+
+	info, err := parsePfStatus(data, layout, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.running {
+		t.Error("want pf running")
+	}
+	for _, tt := range []struct {
+		section, name string
+		want          float64
+	}{
+		{"State Table", "current entries", 9874},
+		{"State Table", "half-open tcp", 112},
+		{"State Table", "searches", 468939193},
+		{"Source Tracking Table", "inserts", 0},
+		{"Counters", "match", 4596651},
+		{"Counters", "no-route", 0},
+		{"Limit Counters", "syncookies validated", 0},
+	} {
+		got, ok := info.sections[tt.section][tt.name]
+		if !ok || got != tt.want {
+			t.Errorf("want %s %s %f, got %f", tt.section, tt.name, tt.want, got)
+		}
+	}
+	if want, got := "em0", info.iface; want != got {
+		t.Errorf("want loginterface %s, got %s", want, got)
+	}
+	if want, got := [2]float64{10113, 42}, info.ifaceStats["Packets In Blocked"]; want != got {
+		t.Errorf("want blocked packets in %v, got %v", want, got)
+	}
+	if want, got := [2]float64{191851544, 0}, info.ifaceStats["Bytes Out"]; want != got {
+		t.Errorf("want bytes out %v, got %v", want, got)
+	}
+
+	if _, err := parsePfStatus(data[:pfStatusFreeBSD.size], layout, order); err == nil {
+		t.Error("want error for pf status of another layout")
+	}
+	info, err = parsePfStatus(data[:pfStatusFreeBSD.size], pfStatusFreeBSD, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := info.sections["State Table"]["half-open tcp"]; ok {
+		t.Error("want no half-open tcp on FreeBSD")
+	}
+}
+
+func TestPfIOWR(t *testing.T) {
+	if want, got := uintptr(0xc0084427), pfIOWR(39, 8); want != got {
+		t.Errorf("want DIOCGETLIMIT %#x, got %#x", want, got)
+	}
+}
@@ -0,0 +1,210 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/prometheus/node_exporter/collector/procfs"
+)
+
+// process holds the parts of /proc/[pid] the process based collectors use.
+type process struct {
+	pid     int
+	uid     uint32
+	comm    string
+	cmdline string
+
+	// CPU times in seconds.
+	utime, stime float64
+	threads      int
+	rssBytes     float64
+	fds          int
+}
+
+// allProcesses returns the pids of all processes below the given proc root.
+func allProcesses(root string) ([]int, error) {
+	dir, err := os.Open(root)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	pids := []int{}
+	for _, name := range names {
+		if pid, err := strconv.Atoi(name); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// exitedCounters keeps counters summed up over the current processes of
+// groups, like their CPU times, from going down when processes exit: the
+// counters last seen of the processes gone are added to their group.
+// Processes starting and exiting between two updates are missed.
+type exitedCounters struct {
+	n int
+	// last holds the counters of the processes by group and pid as of the
+	// last update, exited the summed counters of the exited ones by group.
+	last   map[string]map[int][]float64
+	exited map[string][]float64
+}
+
+// newExitedCounters returns exitedCounters for n counters per process.
+func newExitedCounters(n int) *exitedCounters {
+	return &exitedCounters{n: n, last: map[string]map[int][]float64{}, exited: map[string][]float64{}}
+}
+
+// update takes the counters of the current processes by group and pid and
+// returns those of each group, including its exited processes. A process
+// with counters lower than last seen is taken for a new one reusing the pid.
+func (e *exitedCounters) update(current map[string]map[int][]float64) map[string][]float64 {
+	for group, last := range e.last {
+		for pid, old := range last {
+			if now, ok := current[group][pid]; ok && !countersDecreased(old, now) {
+				continue
+			}
+			if e.exited[group] == nil {
+				e.exited[group] = make([]float64, e.n)
+			}
+			addCounters(e.exited[group], old)
+		}
+	}
+	e.last = current
+
+	totals := map[string][]float64{}
+	for group, processes := range current {
+		total := make([]float64, e.n)
+		if exited, ok := e.exited[group]; ok {
+			addCounters(total, exited)
+		}
+		for _, counters := range processes {
+			addCounters(total, counters)
+		}
+		totals[group] = total
+	}
+	return totals
+}
+
+func addCounters(sum, counters []float64) {
+	for i, v := range counters {
+		sum[i] += v
+	}
+}
+
+func countersDecreased(old, now []float64) bool {
+	for i := range old {
+		if now[i] < old[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProcessGroups parses a comma-separated list of name=regexp process
+// groups, adding the groups of the config entries starting with prefix.
+func parseProcessGroups(list string, config Config, prefix string) (map[string]*regexp.Regexp, error) {
+	definitions := map[string]string{}
+	for _, group := range strings.Split(list, ",") {
+		if group = strings.TrimSpace(group); group == "" {
+			continue
+		}
+		parts := strings.SplitN(group, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid process group %q, want name=regexp", group)
+		}
+		definitions[parts[0]] = parts[1]
+	}
+	for k, v := range config.Config {
+		if strings.HasPrefix(k, prefix) {
+			definitions[strings.TrimPrefix(k, prefix)] = v
+		}
+	}
+
+	groups := map[string]*regexp.Regexp{}
+	for name, re := range definitions {
+		pattern, err := regexp.Compile(re)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp for process group %s: %s", name, err)
+		}
+		groups[name] = pattern
+	}
+	return groups, nil
+}
+
+// readProcess reads stat, cmdline and fd count of a single process. Processes
+// can exit at any time, so callers should skip those returning an error.
+func readProcess(root string, pid int) (process, error) {
+	p := process{pid: pid}
+	dir := path.Join(root, strconv.Itoa(pid))
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return p, err
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		p.uid = stat.Uid
+	}
+
+	data, err := procfs.ReadFile(path.Join(dir, "stat"))
+	if err != nil {
+		return p, err
+	}
+	if err := parseProcessStat(string(data), &p); err != nil {
+		return p, err
+	}
+
+	cmdline, err := procfs.ReadFile(path.Join(dir, "cmdline"))
+	if err != nil {
+		return p, err
+	}
+	p.cmdline = strings.TrimSpace(strings.Replace(string(cmdline), "\x00", " ", -1))
+
+	// Reading fds of other users' processes requires privileges, leave
+	// the count at zero in that case.
+	if fd, err := os.Open(path.Join(dir, "fd")); err == nil {
+		names, _ := fd.Readdirnames(-1)
+		fd.Close()
+		p.fds = len(names)
+	}
+	return p, nil
+}
+
+// parseProcessStat parses the contents of /proc/[pid]/stat into p. The comm
+// field may contain spaces and parentheses, so the fields are located after
+// its last closing parenthesis.
+func parseProcessStat(data string, p *process) error {
+	start, end := strings.Index(data, "("), strings.LastIndex(data, ")")
+	if start < 0 || end < start {
+		return fmt.Errorf("invalid process stat: %s", data)
+	}
+	p.comm = data[start+1 : end]
+
+	// Fields after comm, starting with the state (field 3 in proc(5)).
+	fields := strings.Fields(data[end+1:])
+	if len(fields) < 22 {
+		return fmt.Errorf("invalid process stat, only %d fields: %s", len(fields), data)
+	}
+	values := map[int]float64{}
+	for _, i := range []int{11, 12, 17, 21} {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return fmt.Errorf("invalid value %s in process stat: %s", fields[i], err)
+		}
+		values[i] = v
+	}
+	p.utime = values[11] / userHZ
+	p.stime = values[12] / userHZ
+	p.threads = int(values[17])
+	p.rssBytes = values[21] * float64(os.Getpagesize())
+	return nil
+}
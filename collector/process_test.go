@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"os"
+	"testing"
+)
+
+func TestProcessStat(t *testing.T) {
+	var p process
+	err := parseProcessStat("7281 (tmux: server (1)) S 1 7281 7281 0 -1 4194368 3466 171 0 0 2500 1300 0 0 20 0 4 0 19010 30105600 1024 18446744073709551615 1 1 0 0 0 0 0 3674112 134433283 0 0 0 17 2 0 0 0 0 0", &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "tmux: server (1)", p.comm; want != got {
+		t.Errorf("want comm %q, got %q", want, got)
+	}
+	if want, got := 4, p.threads; want != got {
+		t.Errorf("want threads %d, got %d", want, got)
+	}
+	if want, got := 1024*float64(os.Getpagesize()), p.rssBytes; want != got {
+		t.Errorf("want rss %f, got %f", want, got)
+	}
+	if p.utime <= p.stime {
+		t.Errorf("want utime %f to be larger than stime %f", p.utime, p.stime)
+	}
+
+	if err := parseProcessStat("7281 (short) S 1 2 3", &p); err == nil {
+		t.Error("want error for truncated stat")
+	}
+}
+
+func TestParseProcessGroups(t *testing.T) {
+	config := Config{Config: map[string]string{"taskstats_group_db": "^postgres"}}
+	groups, err := parseProcessGroups("web=nginx|apache, cron=^/usr/sbin/cron", config, "taskstats_group_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 3, len(groups); want != got {
+		t.Fatalf("want %d groups, got %d", want, got)
+	}
+	if !groups["web"].MatchString("nginx: worker process") || !groups["db"].MatchString("postgres: writer") {
+		t.Errorf("want web and db groups to match, got %v", groups)
+	}
+
+	// The other options of a collector in the config file are no groups.
+	config = Config{Config: map[string]string{"procgroup_enabled": "true", "procgroup_groups": "web=nginx", "procgroup_timeout": "10s", "procgroup_group_db": "^postgres"}}
+	groups, err = parseProcessGroups("", config, "procgroup_group_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups["db"] == nil {
+		t.Errorf("want only the db group, got %v", groups)
+	}
+
+	if _, err := parseProcessGroups("web", Config{}, "taskstats_group_"); err == nil {
+		t.Error("want error for group without regexp")
+	}
+}
+
+func TestExitedCounters(t *testing.T) {
+	e := newExitedCounters(2)
+	for _, test := range []struct {
+		current map[string]map[int][]float64
+		want    map[string][]float64
+	}{
+		{
+			map[string]map[int][]float64{"web": {10: {5, 1}, 11: {3, 2}}, "db": {}},
+			map[string][]float64{"web": {8, 3}, "db": {0, 0}},
+		},
+		// 11 exited, 12 started.
+		{
+			map[string]map[int][]float64{"web": {10: {6, 1}, 12: {1, 0}}, "db": {}},
+			map[string][]float64{"web": {10, 3}, "db": {0, 0}},
+		},
+		// 10 exited and its pid was reused.
+		{
+			map[string]map[int][]float64{"web": {10: {2, 0}, 12: {1, 1}}},
+			map[string][]float64{"web": {12, 4}},
+		},
+	} {
+		got := e.update(test.current)
+		if len(got) != len(test.want) {
+			t.Errorf("want %d groups, got %v", len(test.want), got)
+		}
+		for group, want := range test.want {
+			for i := range want {
+				if want[i] != got[group][i] {
+					t.Errorf("want %s counters %v, got %v", group, want, got[group])
+					break
+				}
+			}
+		}
+	}
+}
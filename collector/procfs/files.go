@@ -0,0 +1,58 @@
+package procfs
+
+import (
+	"os"
+	"sync"
+)
+
+// FileContents is the result of reading one of the files of ReadFiles.
+type FileContents struct {
+	Data []byte
+	Err  error
+}
+
+// ReadFiles reads the files with the given names below dir, with up to
+// workers reads at a time, and returns their contents in the order of names.
+// It is meant for the hundreds of small attribute files in /sys, which take
+// long to read one by one as some drivers are slow to answer. The files are
+// opened relative to dir where supported, sparing the lookup of dir for every
+// file. Errors opening dir are returned, those of single files, which may
+// vanish or be unreadable, are in their FileContents.
+func ReadFiles(dir string, names []string, workers int) ([]FileContents, error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(names) {
+		workers = len(names)
+	}
+	dirfd := d.Fd()
+	results := make([]FileContents, len(names))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, attributeBufferSize)
+			for j := range next {
+				results[j].Data, results[j].Err = readFileAt(dirfd, dir, names[j], &buf)
+			}
+		}()
+	}
+	for i := range names {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results, nil
+}
+
+// attributeBufferSize fits the attribute files of /sys, which are at most a
+// page.
+const attributeBufferSize = 4096
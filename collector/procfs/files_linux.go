@@ -0,0 +1,47 @@
+// +build linux
+
+package procfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"syscall"
+)
+
+// readFileAt reads the file name relative to the open directory dirfd into
+// buf, growing it if needed, and returns a copy of the contents.
+func readFileAt(dirfd uintptr, dir, name string, buf *[]byte) ([]byte, error) {
+	fd, err := syscall.Openat(int(dirfd), name, syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path.Join(dir, name), Err: err}
+	}
+	defer syscall.Close(fd)
+
+	n, err := readAll(fdReader(fd), buf)
+	if err != nil {
+		return nil, &os.PathError{Op: "read", Path: path.Join(dir, name), Err: err}
+	}
+	data := make([]byte, n)
+	copy(data, *buf)
+	return data, nil
+}
+
+// fdReader reads a file descriptor, retrying interrupted reads.
+type fdReader int
+
+func (fd fdReader) Read(b []byte) (int, error) {
+	for {
+		n, err := syscall.Read(int(fd), b)
+		if err == syscall.EINTR {
+			continue
+		}
+		if n < 0 {
+			n = 0
+		}
+		if err == nil && n == 0 && len(b) > 0 {
+			return 0, io.EOF
+		}
+		return n, err
+	}
+}
@@ -0,0 +1,13 @@
+// +build !linux
+
+package procfs
+
+import (
+	"path"
+)
+
+// readFileAt reads the file name below dir. Without openat in package syscall
+// on all platforms, the file is opened by its full path.
+func readFileAt(dirfd uintptr, dir, name string, buf *[]byte) ([]byte, error) {
+	return ReadFile(path.Join(dir, name))
+}
@@ -0,0 +1,62 @@
+package procfs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "procfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "hwmon0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]byte{
+		"large": bytes.Repeat([]byte("x"), 3*attributeBufferSize+1),
+		"empty": {},
+	}
+	var names []string
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("hwmon0/temp%d_input", i)
+		want[name] = []byte(fmt.Sprintf("%d000\n", i))
+		names = append(names, name)
+	}
+	for name, data := range want {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names = append(names, "large", "empty", "missing")
+
+	results, err := ReadFiles(dir, names, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(names) {
+		t.Fatalf("want %d results, got %d", len(names), len(results))
+	}
+	for i, name := range names[:len(names)-1] {
+		if results[i].Err != nil {
+			t.Errorf("%s: %s", name, results[i].Err)
+			continue
+		}
+		if !bytes.Equal(want[name], results[i].Data) {
+			t.Errorf("%s: want %d bytes, got %d", name, len(want[name]), len(results[i].Data))
+		}
+	}
+	if err := results[len(names)-1].Err; !os.IsNotExist(err) {
+		t.Errorf("want not exist error for missing file, got %v", err)
+	}
+
+	if _, err := ReadFiles(filepath.Join(dir, "missing"), names, 4); !os.IsNotExist(err) {
+		t.Errorf("want not exist error for missing dir, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package procfs
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// readBufferSize fits all but the largest files of /proc, like
+// /proc/interrupts of hosts with hundreds of CPUs.
+const readBufferSize = 64 * 1024
+
+var readBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, readBufferSize)
+		return &b
+	},
+}
+
+// ReadFile returns the contents of the named file. Files of /proc have no
+// size, so ioutil.ReadFile reads them in growing chunks starting small, and
+// bufio reads them in chunks of 4096 bytes. ReadFile reads them into a
+// pooled buffer, which small files fill with a single read.
+func ReadFile(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	bp := readBuffers.Get().(*[]byte)
+	defer readBuffers.Put(bp)
+	n, err := readAll(f, bp)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, n)
+	copy(data, *bp)
+	return data, nil
+}
+
+// readAll reads r into buf until the end of the file, growing buf if
+// needed, and returns the number of bytes read. Files backed by seq_file,
+// like /proc/interrupts and /proc/net/dev, return about a page per read, so
+// a short read is not the end.
+func readAll(r io.Reader, buf *[]byte) (int, error) {
+	b, n := *buf, 0
+	for {
+		if n == len(b) {
+			b = append(b, make([]byte, len(b)+512)...)
+			*buf = b
+		}
+		m, err := r.Read(b[n:])
+		n += m
+		if err == io.EOF || (m == 0 && err == nil) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
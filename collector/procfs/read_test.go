@@ -0,0 +1,84 @@
+package procfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "procfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, size := range []int{0, 11, readBufferSize - 1, readBufferSize, 3*readBufferSize + 7} {
+		want := bytes.Repeat([]byte("0123456789\n"), size/11+1)[:size]
+		name := filepath.Join(dir, "file")
+		if err := ioutil.WriteFile(name, want, 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(want, got) {
+			t.Errorf("file of %d bytes: got %d bytes", size, len(got))
+		}
+	}
+
+	if _, err := ReadFile(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("want not exist error, got %v", err)
+	}
+}
+
+// chunkReader returns at most size bytes per read, like the ~4096 bytes of
+// seq_file backed files of /proc.
+type chunkReader struct {
+	r    *bytes.Reader
+	size int
+}
+
+func (c chunkReader) Read(b []byte) (int, error) {
+	if len(b) > c.size {
+		b = b[:c.size]
+	}
+	return c.r.Read(b)
+}
+
+func TestReadAllShortReads(t *testing.T) {
+	want := bytes.Repeat([]byte("0123456789\n"), 3000)
+	for _, bufSize := range []int{0, 100, len(want)} {
+		buf := make([]byte, bufSize)
+		n, err := readAll(chunkReader{bytes.NewReader(want), 4037}, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(want, buf[:n]) {
+			t.Errorf("buffer of %d bytes: want %d bytes, got %d", bufSize, len(want), n)
+		}
+	}
+}
+
+func BenchmarkReadFile(b *testing.B) {
+	benchmarkRead(b, ReadFile)
+}
+
+func BenchmarkIoutilReadFile(b *testing.B) {
+	benchmarkRead(b, ioutil.ReadFile)
+}
+
+func benchmarkRead(b *testing.B, read func(string) ([]byte, error)) {
+	if _, err := os.Stat("/proc/stat"); err != nil {
+		b.Skip("no /proc/stat")
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := read("/proc/stat"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package procfs parses files of /proc into typed structs, leaving the
+// collectors to turn them into metrics. Parsers take the file contents, so
+// that they are tested against fixtures captured on different kernels.
+package procfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+)
+
+// CPUModes are the modes reported per CPU in /proc/stat, in the order of
+// the columns.
+var CPUModes = []string{"user", "nice", "system", "idle", "iowait", "irq", "softirq", "steal", "guest", "guest_nice"}
+
+// SoftIRQTypes are the types of softirqs counted in the softirq line of
+// /proc/stat, in the order of the columns. Kernels before 4.1 call irq_poll
+// block_iopoll.
+var SoftIRQTypes = []string{"hi", "timer", "net_tx", "net_rx", "block", "irq_poll", "tasklet", "sched", "hrtimer", "rcu"}
+
+// CPUStat is a cpu line of /proc/stat.
+type CPUStat struct {
+	// Name is cpu for the sum over all CPUs, cpu<N> for a single one.
+	Name string
+	// Ticks holds the USER_HZ ticks spent in each of CPUModes. Older kernels
+	// and OpenVZ guests report fewer modes, Ticks has as many entries as
+	// reported.
+	Ticks []float64
+}
+
+// Stat is the contents of /proc/stat.
+type Stat struct {
+	// Total sums up the time of all CPUs.
+	Total CPUStat
+	CPUs  []CPUStat
+	// Interrupts is the total number of interrupts serviced.
+	Interrupts float64
+	// ContextSwitches is the total number of context switches.
+	ContextSwitches float64
+	// Processes is the number of forks since boot.
+	Processes float64
+	// BootTime is the boot time in seconds since the epoch.
+	BootTime     float64
+	ProcsRunning float64
+	ProcsBlocked float64
+	// SoftIRQs holds the number of softirqs serviced of each of
+	// SoftIRQTypes, as many as reported. Kernels before 2.6.31 have none.
+	SoftIRQs []float64
+}
+
+// ParseStat parses r in the format of /proc/stat. Unknown lines are skipped.
+func ParseStat(r io.Reader) (Stat, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Stat{}, err
+	}
+	var stat Stat
+	if err := stat.Parse(data); err != nil {
+		return Stat{}, err
+	}
+	return stat, nil
+}
+
+// Parse parses data in the format of /proc/stat into s. It reuses the CPU
+// slices of s, so that parsing the file of each scrape into the same Stat
+// doesn't allocate once the CPUs are known.
+func (s *Stat) Parse(data []byte) error {
+	total, cpus, softIRQs := s.Total, s.CPUs[:0], s.SoftIRQs[:0]
+	*s = Stat{}
+	for len(data) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			line, data = data, nil
+		}
+		key, rest := nextField(line)
+		var value *float64
+		switch string(key) {
+		case "":
+			continue
+		case "intr":
+			value = &s.Interrupts
+		case "ctxt":
+			value = &s.ContextSwitches
+		case "processes":
+			value = &s.Processes
+		case "btime":
+			value = &s.BootTime
+		case "procs_running":
+			value = &s.ProcsRunning
+		case "procs_blocked":
+			value = &s.ProcsBlocked
+		case "softirq":
+			var err error
+			if softIRQs, err = parseSoftIRQs(softIRQs, rest); err != nil {
+				return err
+			}
+			continue
+		default:
+			if !bytes.HasPrefix(key, cpuPrefix) {
+				continue
+			}
+			cpu := &total
+			if len(key) > len(cpuPrefix) {
+				if len(cpus) < cap(cpus) {
+					cpus = cpus[:len(cpus)+1]
+				} else {
+					cpus = append(cpus, CPUStat{})
+				}
+				cpu = &cpus[len(cpus)-1]
+			}
+			if err := cpu.parse(key, rest); err != nil {
+				return err
+			}
+			continue
+		}
+		// Only the overall number is kept of intr, the interrupts
+		// collector has the details.
+		field, _ := nextField(rest)
+		v, err := parseNumber(field)
+		if err != nil {
+			return fmt.Errorf("invalid %s value in stat: %s", key, err)
+		}
+		*value = v
+	}
+	s.Total, s.CPUs, s.SoftIRQs = total, cpus, softIRQs
+	return nil
+}
+
+// parseSoftIRQs appends the counts by type of the softirq line to counts,
+// skipping the total in the first field.
+func parseSoftIRQs(counts []float64, fields []byte) ([]float64, error) {
+	_, fields = nextField(fields)
+	for len(counts) < len(SoftIRQTypes) {
+		var field []byte
+		field, fields = nextField(fields)
+		if len(field) == 0 {
+			break
+		}
+		v, err := parseNumber(field)
+		if err != nil {
+			return counts, fmt.Errorf("invalid softirq %s value in stat: %s", SoftIRQTypes[len(counts)], err)
+		}
+		counts = append(counts, v)
+	}
+	return counts, nil
+}
+
+var cpuPrefix = []byte("cpu")
+
+// parse parses the fields of the cpu line called name into c, reusing its
+// name and ticks.
+func (c *CPUStat) parse(name, fields []byte) error {
+	if c.Name != string(name) {
+		c.Name = string(name)
+	}
+	c.Ticks = c.Ticks[:0]
+	for len(c.Ticks) < len(CPUModes) {
+		var field []byte
+		field, fields = nextField(fields)
+		if len(field) == 0 {
+			break
+		}
+		v, err := parseNumber(field)
+		if err != nil {
+			return fmt.Errorf("invalid %s %s value in stat: %s", c.Name, CPUModes[len(c.Ticks)], err)
+		}
+		c.Ticks = append(c.Ticks, v)
+	}
+	return nil
+}
+
+// nextField returns the first space separated field of b and the rest.
+func nextField(b []byte) (field, rest []byte) {
+	i := 0
+	for i < len(b) && (b[i] == ' ' || b[i] == '\t') {
+		i++
+	}
+	j := i
+	for j < len(b) && b[j] != ' ' && b[j] != '\t' {
+		j++
+	}
+	return b[i:j], b[j:]
+}
+
+// parseNumber parses the decimal counters of /proc without allocating,
+// falling back to strconv for anything else.
+func parseNumber(b []byte) (float64, error) {
+	if len(b) == 0 || len(b) > 19 {
+		return strconv.ParseFloat(string(b), 64)
+	}
+	var n uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return strconv.ParseFloat(string(b), 64)
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	return float64(n), nil
+}
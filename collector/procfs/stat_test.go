@@ -0,0 +1,136 @@
+package procfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStat(t *testing.T) {
+	for _, test := range []struct {
+		fixture         string
+		total, cpu1     []float64
+		cpus            int
+		contextSwitches float64
+		bootTime        float64
+		procsBlocked    float64
+		softIRQs        []float64
+	}{
+		{
+			fixture:         "stat-linux-4.4",
+			total:           []float64{301854, 612, 111922, 8979004, 3552, 2, 3944, 0, 44, 36},
+			cpu1:            []float64{47869, 23, 16474, 1110787, 591, 0, 46, 0, 22, 18},
+			cpus:            2,
+			contextSwitches: 38014093,
+			bootTime:        1418183276,
+			procsBlocked:    1,
+			softIRQs:        []float64{250191, 1481983, 1647, 211099, 186066, 0, 1783454, 622196, 12499, 510444},
+		},
+		{
+			// Kernel 2.6.9 lacks steal and guest time.
+			fixture:         "stat-rhel4",
+			total:           []float64{2255, 34, 2290, 22625563, 6290, 127, 456},
+			cpu1:            []float64{1123, 0, 849, 11313845, 2614, 0, 18},
+			cpus:            2,
+			contextSwitches: 1990473,
+			bootTime:        1062191376,
+		},
+		{
+			// OpenVZ guests lack guest time and have a swap line.
+			fixture:         "stat-openvz",
+			total:           []float64{1393280, 32966, 572056, 13343292, 6130, 0, 17875, 0},
+			cpus:            1,
+			contextSwitches: 207450277,
+			bootTime:        1420027429,
+		},
+	} {
+		file, err := os.Open("fixtures/" + test.fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stat, err := ParseStat(file)
+		file.Close()
+		if err != nil {
+			t.Errorf("%s: %s", test.fixture, err)
+			continue
+		}
+
+		if want, got := test.total, stat.Total.Ticks; !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: want total %v, got %v", test.fixture, want, got)
+		}
+		if want, got := test.cpus, len(stat.CPUs); want != got {
+			t.Errorf("%s: want %d cpus, got %d", test.fixture, want, got)
+			continue
+		}
+		if test.cpu1 != nil && !reflect.DeepEqual(test.cpu1, stat.CPUs[1].Ticks) {
+			t.Errorf("%s: want cpu1 %v, got %v", test.fixture, test.cpu1, stat.CPUs[1].Ticks)
+		}
+		if want, got := "cpu0", stat.CPUs[0].Name; want != got {
+			t.Errorf("%s: want first cpu %s, got %s", test.fixture, want, got)
+		}
+		if want, got := test.contextSwitches, stat.ContextSwitches; want != got {
+			t.Errorf("%s: want %f context switches, got %f", test.fixture, want, got)
+		}
+		if want, got := test.bootTime, stat.BootTime; want != got {
+			t.Errorf("%s: want boot time %f, got %f", test.fixture, want, got)
+		}
+		if want, got := test.procsBlocked, stat.ProcsBlocked; want != got {
+			t.Errorf("%s: want %f blocked processes, got %f", test.fixture, want, got)
+		}
+		if want, got := test.softIRQs, stat.SoftIRQs; len(want)+len(got) > 0 && !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: want softirqs %v, got %v", test.fixture, want, got)
+		}
+	}
+}
+
+func TestParseStatLongIntr(t *testing.T) {
+	intr := "intr 42" + strings.Repeat(" 0", 100000) + "\n"
+	stat, err := ParseStat(strings.NewReader(intr + "ctxt 7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Interrupts != 42 || stat.ContextSwitches != 7 {
+		t.Errorf("want 42 interrupts and 7 context switches, got %f and %f", stat.Interrupts, stat.ContextSwitches)
+	}
+}
+
+func TestParseStatInvalid(t *testing.T) {
+	if _, err := ParseStat(strings.NewReader("cpu0 1 x 3\n")); err == nil {
+		t.Error("want error for invalid cpu value")
+	}
+	if _, err := ParseStat(strings.NewReader("softirq 10 4 x\n")); err == nil {
+		t.Error("want error for invalid softirq value")
+	}
+}
+
+func BenchmarkParseStat(b *testing.B) {
+	data, err := ioutil.ReadFile("fixtures/stat-linux-4.4")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseStat(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStatParse(b *testing.B) {
+	data, err := ioutil.ReadFile("fixtures/stat-linux-4.4")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var stat Stat
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := stat.Parse(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// +build !noprocgroup
+
+package collector
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const procGroupSubsystem = "procgroup"
+
+var (
+	procGroups = flag.String("collector.procgroup.groups", "", "Comma-separated list of name=regexp process groups, matched against /proc/[pid]/cmdline.")
+)
+
+type procGroupCollector struct {
+	config Config
+	groups map[string]*regexp.Regexp
+
+	// mu guards the state reused by updates, which run concurrently when a
+	// timed out update is still running: the CPU times of the processes and
+	// the metric vectors.
+	mu                           sync.Mutex
+	cpuTimes                     *exitedCounters
+	cpu                          *prometheus.CounterVec
+	rss, fds, threads, processes *prometheus.GaugeVec
+}
+
+type procGroupStats struct {
+	rss                 float64
+	fds, threads, procs int
+}
+
+func init() {
+	registerCollector("procgroup", defaultDisabled, NewProcGroupCollector)
+}
+
+// NewProcGroupCollector returns a new Collector exposing resource usage
+// summed up over named groups of processes. Groups are taken from
+// --collector.procgroup.groups and from procgroup_group_<name> config entries.
+func NewProcGroupCollector(config Config) (Collector, error) {
+	groups, err := parseProcessGroups(*procGroups, config, "procgroup_group_")
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("No process groups specified, see --collector.procgroup.groups")
+	}
+
+	return &procGroupCollector{
+		config:   config,
+		groups:   groups,
+		cpuTimes: newExitedCounters(2),
+		cpu: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: procGroupSubsystem,
+				Name:      "cpu_seconds",
+				Help:      "Seconds the processes of a group spent in each mode, including processes that exited since the exporter started.",
+			},
+			[]string{"group", "mode"},
+		),
+		rss:       newProcGroupGauge("memory_rss_bytes", "Resident memory of the processes of a group in bytes."),
+		fds:       newProcGroupGauge("open_fds", "Number of open file descriptors of the processes of a group."),
+		threads:   newProcGroupGauge("threads", "Number of threads of the processes of a group."),
+		processes: newProcGroupGauge("processes", "Number of processes in a group."),
+	}, nil
+}
+
+func newProcGroupGauge(name, help string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: procGroupSubsystem,
+			Name:      name,
+			Help:      help,
+		},
+		[]string{"group"},
+	)
+}
+
+func (c *procGroupCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	pids, err := allProcesses(*procPath)
+	if err != nil {
+		return fmt.Errorf("couldn't list processes: %s", err)
+	}
+
+	stats := map[string]*procGroupStats{}
+	cpuTimes := map[string]map[int][]float64{}
+	for name := range c.groups {
+		// Export empty groups as well, so that absence can be alerted on.
+		stats[name] = &procGroupStats{}
+		cpuTimes[name] = map[int][]float64{}
+	}
+	for _, pid := range pids {
+		p, err := readProcess(*procPath, pid)
+		if err != nil || p.cmdline == "" { // exited or kernel thread
+			continue
+		}
+		for name, pattern := range c.groups {
+			if !pattern.MatchString(p.cmdline) {
+				continue
+			}
+			s := stats[name]
+			cpuTimes[name][pid] = []float64{p.utime, p.stime}
+			s.rss += p.rssBytes
+			s.fds += p.fds
+			s.threads += p.threads
+			s.procs++
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cpu := c.cpuTimes.update(cpuTimes)
+	for name, s := range stats {
+		c.cpu.WithLabelValues(name, "user").Set(cpu[name][0])
+		c.cpu.WithLabelValues(name, "system").Set(cpu[name][1])
+		c.rss.WithLabelValues(name).Set(s.rss)
+		c.fds.WithLabelValues(name).Set(float64(s.fds))
+		c.threads.WithLabelValues(name).Set(float64(s.threads))
+		c.processes.WithLabelValues(name).Set(float64(s.procs))
+	}
+	c.cpu.Collect(ch)
+	c.rss.Collect(ch)
+	c.fds.Collect(ch)
+	c.threads.Collect(ch)
+	c.processes.Collect(ch)
+	return nil
+}
@@ -0,0 +1,120 @@
+// +build !noraspberrypi
+
+package collector
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultVcgencmd      = "vcgencmd"
+	raspberryPiSubsystem = "raspberrypi"
+)
+
+// Bits of "vcgencmd get_throttled" for the current conditions. The same
+// conditions having occurred since boot are 16 bits higher.
+var raspberryPiThrottledBits = []struct {
+	bit       uint
+	condition string
+}{
+	{0, "under_voltage"},
+	{1, "frequency_capped"},
+	{2, "throttled"},
+	{3, "soft_temp_limit"},
+}
+
+type raspberryPiCollector struct {
+	config Config
+	cli    string
+
+	temperature       typedDesc
+	coreVolts         typedDesc
+	throttled         typedDesc
+	throttledOccurred typedDesc
+}
+
+func init() {
+	registerCollector("raspberrypi", defaultDisabled, NewRaspberryPiCollector)
+}
+
+// NewRaspberryPiCollector returns a new Collector exposing the SoC
+// temperature, core voltage and throttling status of the VideoCore firmware
+// of Raspberry Pis through vcgencmd.
+func NewRaspberryPiCollector(config Config) (Collector, error) {
+	cli := defaultVcgencmd
+	if config.Config["raspberrypi_vcgencmd_command"] != "" {
+		cli = config.Config["raspberrypi_vcgencmd_command"]
+	}
+	if _, err := exec.LookPath(cli); err != nil {
+		return nil, NotApplicable("no %s: %s", cli, err)
+	}
+
+	return &raspberryPiCollector{
+		config:            config,
+		cli:               cli,
+		temperature:       newTypedDesc(raspberryPiSubsystem, "temperature_celsius", "Temperature of the SoC in degrees celsius.", prometheus.GaugeValue),
+		coreVolts:         newTypedDesc(raspberryPiSubsystem, "core_volts", "Voltage of the VideoCore core in volts.", prometheus.GaugeValue),
+		throttled:         newTypedDesc(raspberryPiSubsystem, "throttled", "Whether the firmware currently reports the condition, 1 or 0.", prometheus.GaugeValue, "condition"),
+		throttledOccurred: newTypedDesc(raspberryPiSubsystem, "throttled_occurred", "Whether the firmware reported the condition since boot, 1 or 0.", prometheus.GaugeValue, "condition"),
+	}, nil
+}
+
+func (c *raspberryPiCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	temp, err := c.vcgencmd("temp", "measure_temp")
+	if err != nil {
+		return err
+	}
+	temperature, err := strconv.ParseFloat(strings.TrimSuffix(temp, "'C"), 64)
+	if err != nil {
+		return fmt.Errorf("couldn't parse temperature %q: %s", temp, err)
+	}
+	volt, err := c.vcgencmd("volt", "measure_volts", "core")
+	if err != nil {
+		return err
+	}
+	coreVolts, err := strconv.ParseFloat(strings.TrimSuffix(volt, "V"), 64)
+	if err != nil {
+		return fmt.Errorf("couldn't parse core voltage %q: %s", volt, err)
+	}
+	flags, err := c.vcgencmd("throttled", "get_throttled")
+	if err != nil {
+		return err
+	}
+	throttled, err := strconv.ParseUint(flags, 0, 32)
+	if err != nil {
+		return fmt.Errorf("couldn't parse throttled flags %q: %s", flags, err)
+	}
+
+	ch <- c.temperature.mustNewConstMetric(temperature)
+	ch <- c.coreVolts.mustNewConstMetric(coreVolts)
+	for _, b := range raspberryPiThrottledBits {
+		ch <- c.throttled.mustNewConstMetric(float64(throttled>>b.bit&1), b.condition)
+		ch <- c.throttledOccurred.mustNewConstMetric(float64(throttled>>(b.bit+16)&1), b.condition)
+	}
+	return nil
+}
+
+// vcgencmd runs vcgencmd with args and returns the value of its output
+// "key=value".
+func (c *raspberryPiCollector) vcgencmd(key string, args ...string) (string, error) {
+	out, err := exec.Command(c.cli, args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("couldn't run vcgencmd %s: %s", strings.Join(args, " "), err)
+	}
+	return parseVcgencmd(string(out), key)
+}
+
+// parseVcgencmd returns the value of vcgencmd output like "temp=48.3'C".
+func parseVcgencmd(out, key string) (string, error) {
+	parts := strings.SplitN(strings.TrimSpace(out), "=", 2)
+	if len(parts) != 2 || parts[0] != key {
+		return "", fmt.Errorf("unexpected vcgencmd output %q", out)
+	}
+	return parts[1], nil
+}
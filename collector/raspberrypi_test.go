@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVcgencmd(t *testing.T) {
+	for out, want := range map[string]string{
+		"temp=48.3'C\n":       "48.3'C",
+		"volt=1.2000V\n":      "1.2000V",
+		"throttled=0x50005\n": "0x50005",
+	} {
+		key := out[:strings.Index(out, "=")]
+		got, err := parseVcgencmd(out, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want != got {
+			t.Errorf("want %q of %q, got %q", want, out, got)
+		}
+	}
+
+	if _, err := parseVcgencmd("error=1 error_msg=\"Command not registered\"\n", "temp"); err == nil {
+		t.Error("want error for unexpected output")
+	}
+}
@@ -3,9 +3,10 @@
 package collector
 
 import (
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
 	"github.com/soundcloud/go-runit/runit"
+	"golang.org/x/net/context"
 )
 
 type runitCollector struct {
@@ -15,7 +16,7 @@ type runitCollector struct {
 }
 
 func init() {
-	Factories["runit"] = NewRunitCollector
+	registerCollector("runit", defaultDisabled, NewRunitCollector)
 }
 
 func NewRunitCollector(config Config) (Collector, error) {
@@ -60,20 +61,24 @@ func NewRunitCollector(config Config) (Collector, error) {
 	}, nil
 }
 
-func (c *runitCollector) Update(ch chan<- prometheus.Metric) error {
+func (c *runitCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
 	services, err := runit.GetServices("/etc/service")
 	if err != nil {
 		return err
 	}
 
+	// Services come and go, don't keep exporting removed ones.
+	c.state.Reset()
+	c.stateDesired.Reset()
+	c.stateNormal.Reset()
 	for _, service := range services {
 		status, err := service.Status()
 		if err != nil {
-			glog.V(1).Infof("Couldn't get status for %s: %s, skipping...", service.Name, err)
+			log.Debugf("Couldn't get status for %s: %s, skipping...", service.Name, err)
 			continue
 		}
 
-		glog.V(1).Infof("%s is %d on pid %d for %d seconds", service.Name, status.State, status.Pid, status.Duration)
+		log.Debugf("%s is %d on pid %d for %d seconds", service.Name, status.State, status.Pid, status.Duration)
 		c.state.WithLabelValues(service.Name).Set(float64(status.State))
 		c.stateDesired.WithLabelValues(service.Name).Set(float64(status.Want))
 		if status.NormallyUp {
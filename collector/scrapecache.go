@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"sync"
+
+	"github.com/prometheus/node_exporter/collector/procfs"
+	"golang.org/x/net/context"
+)
+
+type scrapeCacheKey struct{}
+
+// scrapeCache holds what the collectors of a scrape read and parsed, so that
+// files read by several collectors are read once per scrape and all of them
+// see the same contents.
+type scrapeCache struct {
+	mu      sync.Mutex
+	entries map[string]*scrapeCacheEntry
+}
+
+type scrapeCacheEntry struct {
+	once  sync.Once
+	value interface{}
+	err   error
+}
+
+// WithScrapeCache returns a context sharing the files read by the collectors
+// updated with it. It is meant to span a single scrape.
+func WithScrapeCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scrapeCacheKey{}, &scrapeCache{entries: map[string]*scrapeCacheEntry{}})
+}
+
+// cached returns the result of parse for key, calling parse once for all
+// collectors sharing the scrape cache of ctx, or every time without a cache.
+// The value is shared and must not be modified.
+func cached(ctx context.Context, key string, parse func() (interface{}, error)) (interface{}, error) {
+	c, ok := ctx.Value(scrapeCacheKey{}).(*scrapeCache)
+	if !ok {
+		return parse()
+	}
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &scrapeCacheEntry{}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+	e.once.Do(func() { e.value, e.err = parse() })
+	return e.value, e.err
+}
+
+// readProcFile returns the contents of name below -path.procfs, read once
+// per scrape.
+func readProcFile(ctx context.Context, name string) ([]byte, error) {
+	data, err := cached(ctx, "file:"+name, func() (interface{}, error) {
+		return procfs.ReadFile(procFilePath(name))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data.([]byte), nil
+}
@@ -0,0 +1,68 @@
+// +build linux
+
+package collector
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestScrapeCache(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	parse := func() (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return calls, nil
+	}
+
+	ctx := WithScrapeCache(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := cached(ctx, "test", parse); err != nil || v.(int) != 1 {
+				t.Errorf("want first result, got %v, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("want parse called once per scrape, got %d calls", calls)
+	}
+
+	cached(context.Background(), "test", parse)
+	cached(context.Background(), "test", parse)
+	if calls != 3 {
+		t.Errorf("want parse called on every update without cache, got %d calls", calls)
+	}
+}
+
+func TestReadProcFile(t *testing.T) {
+	defer func(old string) { *procPath = old }(*procPath)
+	*procPath = "fixtures/proc"
+
+	ctx := WithScrapeCache(context.Background())
+	memInfo, err := getMemInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := readProcFile(ctx, "meminfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked, err := parseKB(bytes.NewReader(data), "Mlocked:", "meminfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := memInfo["Mlocked"], locked; want != got {
+		t.Errorf("want Mlocked %f from meminfo, got %f", want, got)
+	}
+}
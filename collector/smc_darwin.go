@@ -0,0 +1,210 @@
+// +build !nosmc,darwin,cgo
+
+package collector
+
+/*
+#cgo LDFLAGS: -framework IOKit
+#include <IOKit/IOKitLib.h>
+#include <string.h>
+
+// The struct of the SMC user client, see smcFanControl.
+typedef struct {
+	uint32_t key;
+	struct {
+		char major, minor, build, reserved;
+		uint16_t release;
+	} vers;
+	struct {
+		uint16_t version, length;
+		uint32_t cpuPLimit, gpuPLimit, memPLimit;
+	} pLimitData;
+	struct {
+		uint32_t dataSize, dataType;
+		char dataAttributes;
+	} keyInfo;
+	char result, status, data8;
+	uint32_t data32;
+	uint8_t bytes[32];
+} smcKeyData;
+
+enum {
+	kernelIndexSMC    = 2,
+	smcCmdReadBytes   = 5,
+	smcCmdReadKeyInfo = 9,
+};
+
+static kern_return_t smcOpen(io_connect_t *conn) {
+	io_service_t service = IOServiceGetMatchingService(MACH_PORT_NULL, IOServiceMatching("AppleSMC"));
+	if (!service) {
+		return kIOReturnNotFound;
+	}
+	kern_return_t kr = IOServiceOpen(service, mach_task_self(), 0, conn);
+	IOObjectRelease(service);
+	return kr;
+}
+
+static kern_return_t smcCall(io_connect_t conn, smcKeyData *in, smcKeyData *out) {
+	size_t size = sizeof(*out);
+	memset(out, 0, sizeof(*out));
+	return IOConnectCallStructMethod(conn, kernelIndexSMC, in, sizeof(*in), out, &size);
+}
+
+// smcRead reads the data type and up to 32 bytes of the value of key, and
+// sets found to 0 if the SMC does not have it.
+static kern_return_t smcRead(io_connect_t conn, uint32_t key, int *found, uint32_t *dataType, uint32_t *dataSize, uint8_t *bytes) {
+	smcKeyData in, out;
+	memset(&in, 0, sizeof(in));
+	in.key = key;
+	in.data8 = smcCmdReadKeyInfo;
+	kern_return_t kr = smcCall(conn, &in, &out);
+	if (kr != KERN_SUCCESS) {
+		return kr;
+	}
+	*found = out.result == 0;
+	if (!*found) {
+		return KERN_SUCCESS;
+	}
+	*dataType = out.keyInfo.dataType;
+	*dataSize = out.keyInfo.dataSize;
+	in.keyInfo.dataSize = out.keyInfo.dataSize;
+	in.data8 = smcCmdReadBytes;
+	kr = smcCall(conn, &in, &out);
+	if (kr != KERN_SUCCESS) {
+		return kr;
+	}
+	if (out.result != 0) {
+		return kIOReturnError;
+	}
+	memcpy(bytes, out.bytes, sizeof(out.bytes));
+	return KERN_SUCCESS;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+// smcChip is the chip label of the sensors, the name of the hwmon driver of
+// the SMC on Linux.
+const smcChip = "applesmc"
+
+var (
+	// smcTemperatureKeys are the temperature sensors of the CPU, GPU,
+	// memory, battery and ambient air of Intel Macs, then the CPU cores of
+	// Apple silicon. Those a model lacks are left out.
+	smcTemperatureKeys = []string{
+		"TC0P", "TC0D", "TC0E", "TC0F", "TCXC", "TG0P", "TG0D", "Tm0P", "TB0T", "TA0P",
+		"Tp01", "Tp05", "Tp09", "Tp0D", "Tp0H", "Tp0L", "Tp0P", "Tp0T", "Tp0X", "Tp0b",
+	}
+	// smcPowerKeys are the power draws of the CPU package, CPU cores, GPU
+	// and the whole system.
+	smcPowerKeys = []string{"PCPC", "PCPT", "PC0C", "PCPG", "PSTR"}
+)
+
+type smcCollector struct {
+	temp   typedDesc
+	fan    typedDesc
+	fanMin typedDesc
+	fanMax typedDesc
+	power  typedDesc
+}
+
+func init() {
+	registerCollector("smc", defaultDisabled, NewSMCCollector)
+}
+
+// NewSMCCollector returns a new Collector exposing the temperatures, fan
+// speeds and power draw read from the SMC of Macs, under the metric names
+// of the hwmon sensors of Linux.
+func NewSMCCollector(config Config) (Collector, error) {
+	var conn C.io_connect_t
+	if kr := C.smcOpen(&conn); kr != C.KERN_SUCCESS {
+		return nil, NotApplicable("couldn't open AppleSMC: %#x", uint32(kr))
+	}
+	C.IOServiceClose(conn)
+
+	labels := []string{"chip", "sensor"}
+	return &smcCollector{
+		temp:   newTypedDesc("hwmon", "temp_celsius", "Hardware monitor for temperature (input)", prometheus.GaugeValue, labels...),
+		fan:    newTypedDesc("hwmon", "fan_rpm", "Hardware monitor for fan revolutions per minute (input)", prometheus.GaugeValue, labels...),
+		fanMin: newTypedDesc("hwmon", "fan_min_rpm", "Hardware monitor for fan revolutions per minute (min)", prometheus.GaugeValue, labels...),
+		fanMax: newTypedDesc("hwmon", "fan_max_rpm", "Hardware monitor for fan revolutions per minute (max)", prometheus.GaugeValue, labels...),
+		power:  newTypedDesc("hwmon", "power_watt", "Hardware monitor for power usage in watts (input)", prometheus.GaugeValue, labels...),
+	}, nil
+}
+
+func (c *smcCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	var conn C.io_connect_t
+	if kr := C.smcOpen(&conn); kr != C.KERN_SUCCESS {
+		return fmt.Errorf("couldn't open AppleSMC: %#x", uint32(kr))
+	}
+	defer C.IOServiceClose(conn)
+
+	for _, key := range smcTemperatureKeys {
+		value, ok, err := smcRead(conn, key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ch <- c.temp.mustNewConstMetric(value, smcChip, key)
+		}
+	}
+	for _, key := range smcPowerKeys {
+		value, ok, err := smcRead(conn, key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			ch <- c.power.mustNewConstMetric(value, smcChip, key)
+		}
+	}
+
+	fans, ok, err := smcRead(conn, "FNum")
+	if err != nil || !ok {
+		return err
+	}
+	for i := 0; i < int(fans); i++ {
+		// Numbered from 1 like fan1_input of hwmon.
+		sensor := "fan" + strconv.Itoa(i+1)
+		for suffix, desc := range map[string]typedDesc{"Ac": c.fan, "Mn": c.fanMin, "Mx": c.fanMax} {
+			value, ok, err := smcRead(conn, fmt.Sprintf("F%d%s", i, suffix))
+			if err != nil {
+				return err
+			}
+			if ok {
+				ch <- desc.mustNewConstMetric(value, smcChip, sensor)
+			}
+		}
+	}
+	return nil
+}
+
+// smcRead returns the value of key, and whether the SMC has it.
+func smcRead(conn C.io_connect_t, key string) (float64, bool, error) {
+	var (
+		found              C.int
+		dataType, dataSize C.uint32_t
+		bytes              [32]byte
+	)
+	kr := C.smcRead(conn, C.uint32_t(smcFourCC(key)), &found, &dataType, &dataSize, (*C.uint8_t)(unsafe.Pointer(&bytes[0])))
+	if kr != C.KERN_SUCCESS {
+		return 0, false, fmt.Errorf("couldn't read SMC key %s: %#x", key, uint32(kr))
+	}
+	if found == 0 {
+		return 0, false, nil
+	}
+	if dataSize > C.uint32_t(len(bytes)) {
+		dataSize = C.uint32_t(len(bytes))
+	}
+	value, err := parseSMCValue(smcFourCCString(uint32(dataType)), bytes[:dataSize])
+	if err != nil {
+		return 0, false, fmt.Errorf("couldn't decode SMC key %s: %s", key, err)
+	}
+	return value, true, nil
+}
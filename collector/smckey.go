@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// The SMC of Macs names its keys and data types with four characters, and
+// returns the values as big endian fixed point numbers or integers, except
+// the floats of Apple silicon. The decoders below are untagged so that they
+// are tested on any platform.
+
+// smcFourCC returns the code of a key or data type like "TC0P".
+func smcFourCC(s string) uint32 {
+	var b [4]byte
+	copy(b[:], s)
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// smcFourCCString returns the key or data type of a code.
+func smcFourCCString(code uint32) string {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], code)
+	return string(b[:])
+}
+
+// parseSMCValue decodes a value of the SMC by its data type: "spXY" fixed
+// point numbers with a sign, X integer and Y fraction bits in hex, like
+// sp78 for temperatures, unsigned "fpXY" ones like fpe2 for fan speeds,
+// "ui8 " to "ui32" integers and little endian "flt " floats.
+func parseSMCValue(dataType string, data []byte) (float64, error) {
+	switch dataType {
+	case "ui8 ":
+		if len(data) >= 1 {
+			return float64(data[0]), nil
+		}
+	case "ui16":
+		if len(data) >= 2 {
+			return float64(binary.BigEndian.Uint16(data)), nil
+		}
+	case "ui32":
+		if len(data) >= 4 {
+			return float64(binary.BigEndian.Uint32(data)), nil
+		}
+	case "flt ":
+		if len(data) >= 4 {
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(data))), nil
+		}
+	default:
+		if len(dataType) != 4 || (dataType[:2] != "sp" && dataType[:2] != "fp") {
+			return 0, fmt.Errorf("unknown SMC data type %q", dataType)
+		}
+		frac, err := strconv.ParseUint(dataType[3:], 16, 8)
+		if err != nil {
+			return 0, fmt.Errorf("unknown SMC data type %q", dataType)
+		}
+		if len(data) < 2 {
+			break
+		}
+		raw := binary.BigEndian.Uint16(data)
+		if dataType[:2] == "sp" {
+			return math.Ldexp(float64(int16(raw)), -int(frac)), nil
+		}
+		return math.Ldexp(float64(raw), -int(frac)), nil
+	}
+	return 0, fmt.Errorf("invalid SMC %q value of %d bytes", dataType, len(data))
+}
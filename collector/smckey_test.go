@@ -0,0 +1,44 @@
+package collector
+
+import (
+	"testing"
+)
+
+func TestSMCFourCC(t *testing.T) {
+	if want, got := uint32(0x54433050), smcFourCC("TC0P"); want != got {
+		t.Errorf("want code %#x, got %#x", want, got)
+	}
+	if want, got := "flt ", smcFourCCString(smcFourCC("flt ")); want != got {
+		t.Errorf("want data type %q, got %q", want, got)
+	}
+}
+
+func TestParseSMCValue(t *testing.T) {
+	for _, tt := range []struct {
+		dataType string
+		data     []byte
+		want     float64
+	}{
+		{"sp78", []byte{0x30, 0x80}, 48.5},
+		{"sp78", []byte{0xff, 0x00}, -1},
+		{"fpe2", []byte{0x1f, 0x40}, 2000},
+		{"ui8 ", []byte{2}, 2},
+		{"ui16", []byte{0x01, 0x00}, 256},
+		{"flt ", []byte{0x00, 0x00, 0x48, 0x42}, 50},
+	} {
+		got, err := parseSMCValue(tt.dataType, tt.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.want != got {
+			t.Errorf("want %s value %f, got %f", tt.dataType, tt.want, got)
+		}
+	}
+
+	if _, err := parseSMCValue("ch8*", []byte("abc")); err == nil {
+		t.Error("want error for unknown data type")
+	}
+	if _, err := parseSMCValue("sp78", []byte{0x30}); err == nil {
+		t.Error("want error for truncated value")
+	}
+}
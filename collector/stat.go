@@ -1,165 +1,130 @@
-// +build !nostat
+// +build !nostat,linux
 
 package collector
 
 import (
-	"bufio"
-	"os"
-	"strconv"
-	"strings"
+	"flag"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector/procfs"
+	"golang.org/x/net/context"
 )
 
-// #include <unistd.h>
-import "C"
-
-const (
-	procStat = "/proc/stat"
-)
+var statCPUTotal = flag.Bool("collector.stat.cpu-total", false, "Also export the time of all cpus summed up, as node_cpu_total.")
 
 type statCollector struct {
 	config       Config
-	cpu          *prometheus.CounterVec
-	intr         prometheus.Counter
-	ctxt         prometheus.Counter
-	forks        prometheus.Counter
-	btime        prometheus.Gauge
-	procsRunning prometheus.Gauge
-	procsBlocked prometheus.Gauge
+	cpu          typedDesc
+	cpuTotal     typedDesc
+	intr         typedDesc
+	ctxt         typedDesc
+	forks        typedDesc
+	btime        typedDesc
+	procsRunning typedDesc
+	procsBlocked typedDesc
+	softIRQs     typedDesc
+	removedCPUs  *removedEntities
+
+	// mu guards the state reused by updates: the parsed stat, the cpu
+	// names, and the label values of the cpu metrics by cpu and mode.
+	mu        sync.Mutex
+	stat      procfs.Stat
+	cpus      []string
+	cpuLabels map[string][][]string
+	// modeLabels holds the label values of the cpu total metrics by mode.
+	modeLabels [][]string
+	// softIRQLabels holds the label values of the softirq metrics by type.
+	softIRQLabels [][]string
 }
 
 func init() {
-	Factories["stat"] = NewStatCollector
+	registerCollector("stat", defaultEnabled, NewStatCollector)
 }
 
 // Takes a config struct and prometheus registry and returns a new Collector exposing
 // network device stats.
 func NewStatCollector(config Config) (Collector, error) {
+	modeLabels := make([][]string, len(procfs.CPUModes))
+	for i, mode := range procfs.CPUModes {
+		modeLabels[i] = []string{mode}
+	}
+	softIRQLabels := make([][]string, len(procfs.SoftIRQTypes))
+	for i, t := range procfs.SoftIRQTypes {
+		softIRQLabels[i] = []string{t}
+	}
 	return &statCollector{
-		config: config,
-		cpu: prometheus.NewCounterVec(
-			prometheus.CounterOpts{
-				Namespace: Namespace,
-				Name:      "cpu",
-				Help:      "Seconds the cpus spent in each mode.",
-			},
-			[]string{"cpu", "mode"},
-		),
-		intr: prometheus.NewCounter(prometheus.CounterOpts{
-			Namespace: Namespace,
-			Name:      "intr",
-			Help:      "Total number of interrupts serviced.",
-		}),
-		ctxt: prometheus.NewCounter(prometheus.CounterOpts{
-			Namespace: Namespace,
-			Name:      "context_switches",
-			Help:      "Total number of context switches.",
-		}),
-		forks: prometheus.NewCounter(prometheus.CounterOpts{
-			Namespace: Namespace,
-			Name:      "forks",
-			Help:      "Total number of forks.",
-		}),
-		btime: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace: Namespace,
-			Name:      "boot_time",
-			Help:      "Node boot time, in unixtime.",
-		}),
-		procsRunning: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace: Namespace,
-			Name:      "procs_running",
-			Help:      "Number of processes in runnable state.",
-		}),
-		procsBlocked: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace: Namespace,
-			Name:      "procs_blocked",
-			Help:      "Number of processes blocked waiting for I/O to complete.",
-		}),
+		config:        config,
+		cpu:           newTypedDesc("", "cpu", "Seconds the cpus spent in each mode.", prometheus.CounterValue, "cpu", "mode"),
+		cpuTotal:      newTypedDesc("", "cpu_total", "Seconds all cpus spent in each mode, summed up.", prometheus.CounterValue, "mode"),
+		intr:          newTypedDesc("", "intr", "Total number of interrupts serviced.", prometheus.CounterValue),
+		ctxt:          newTypedDesc("", "context_switches", "Total number of context switches.", prometheus.CounterValue),
+		forks:         newTypedDesc("", "forks", "Total number of forks.", prometheus.CounterValue),
+		btime:         newTypedDesc("", "boot_time", "Node boot time, in unixtime.", prometheus.GaugeValue),
+		procsRunning:  newTypedDesc("", "procs_running", "Number of processes in runnable state.", prometheus.GaugeValue),
+		procsBlocked:  newTypedDesc("", "procs_blocked", "Number of processes blocked waiting for I/O to complete.", prometheus.GaugeValue),
+		softIRQs:      newTypedDesc("", "softirqs", "Number of softirqs serviced, by type.", prometheus.CounterValue, "type"),
+		removedCPUs:   newRemovedEntities("cpu", "Number of cpus that went offline."),
+		cpuLabels:     map[string][][]string{},
+		modeLabels:    modeLabels,
+		softIRQLabels: softIRQLabels,
 	}, nil
 }
 
 // Expose a variety of stats from /proc/stats.
-func (c *statCollector) Update(ch chan<- prometheus.Metric) (err error) {
-	file, err := os.Open(procStat)
+func (c *statCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	data, err := readProcFile(ctx, "stat")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stat := &c.stat
+	if err := stat.Parse(data); err != nil {
+		return err
+	}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		parts := strings.Fields(scanner.Text())
-		if len(parts) == 0 {
-			continue
+	// Export per-cpu stats, they can be aggregated up in prometheus, and
+	// the sum on request for hosts with many cpus. The sum has a metric of
+	// its own, so that summing up node_cpu doesn't count it.
+	if *statCPUTotal {
+		for i, ticks := range stat.Total.Ticks {
+			ch <- c.cpuTotal.mustNewConstMetric(ticks/userHZ, c.modeLabels[i]...)
 		}
-		switch {
-		case strings.HasPrefix(parts[0], "cpu"):
-			// Export only per-cpu stats, it can be aggregated up in prometheus.
-			if parts[0] == "cpu" {
-				break
-			}
-			// Only some of these may be present, depending on kernel version.
-			cpuFields := []string{"user", "nice", "system", "idle", "iowait", "irq", "softirq", "steal", "guest"}
-			// OpenVZ guests lack the "guest" CPU field, which needs to be ignored.
-			expectedFieldNum := len(cpuFields)+1
-			if expectedFieldNum > len(parts) {
-				expectedFieldNum = len(parts)
-			}
-			for i, v := range parts[1 : expectedFieldNum] {
-				value, err := strconv.ParseFloat(v, 64)
-				if err != nil {
-					return err
-				}
-				// Convert from ticks to seconds
-				value /= float64(C.sysconf(C._SC_CLK_TCK))
-				c.cpu.With(prometheus.Labels{"cpu": parts[0], "mode": cpuFields[i]}).Set(value)
-			}
-		case parts[0] == "intr":
-			// Only expose the overall number, use the 'interrupts' collector for more detail.
-			value, err := strconv.ParseFloat(parts[1], 64)
-			if err != nil {
-				return err
-			}
-			c.intr.Set(value)
-		case parts[0] == "ctxt":
-			value, err := strconv.ParseFloat(parts[1], 64)
-			if err != nil {
-				return err
-			}
-			c.ctxt.Set(value)
-		case parts[0] == "processes":
-			value, err := strconv.ParseFloat(parts[1], 64)
-			if err != nil {
-				return err
-			}
-			c.forks.Set(value)
-		case parts[0] == "btime":
-			value, err := strconv.ParseFloat(parts[1], 64)
-			if err != nil {
-				return err
-			}
-			c.btime.Set(value)
-		case parts[0] == "procs_running":
-			value, err := strconv.ParseFloat(parts[1], 64)
-			if err != nil {
-				return err
-			}
-			c.procsRunning.Set(value)
-		case parts[0] == "procs_blocked":
-			value, err := strconv.ParseFloat(parts[1], 64)
-			if err != nil {
-				return err
-			}
-			c.procsBlocked.Set(value)
+	}
+	c.cpus = c.cpus[:0]
+	for _, cpu := range stat.CPUs {
+		c.cpus = append(c.cpus, cpu.Name)
+		labels := c.labels(cpu.Name)
+		for i, ticks := range cpu.Ticks {
+			// Convert from ticks to seconds
+			ch <- c.cpu.mustNewConstMetric(ticks/userHZ, labels[i]...)
+		}
+	}
+	ch <- c.removedCPUs.update(c.cpus)
+
+	// Only expose the overall number, use the 'interrupts' collector for more detail.
+	ch <- c.intr.mustNewConstMetric(stat.Interrupts)
+	ch <- c.ctxt.mustNewConstMetric(stat.ContextSwitches)
+	ch <- c.forks.mustNewConstMetric(stat.Processes)
+	ch <- c.btime.mustNewConstMetric(stat.BootTime)
+	ch <- c.procsRunning.mustNewConstMetric(stat.ProcsRunning)
+	ch <- c.procsBlocked.mustNewConstMetric(stat.ProcsBlocked)
+	for i, count := range stat.SoftIRQs {
+		ch <- c.softIRQs.mustNewConstMetric(count, c.softIRQLabels[i]...)
+	}
+	return nil
+}
+
+// labels returns the label values of the cpu metrics of cpu, by mode.
+func (c *statCollector) labels(cpu string) [][]string {
+	labels, ok := c.cpuLabels[cpu]
+	if !ok {
+		labels = make([][]string, len(procfs.CPUModes))
+		for i, mode := range procfs.CPUModes {
+			labels[i] = []string{cpu, mode}
 		}
+		c.cpuLabels[cpu] = labels
 	}
-	c.cpu.Collect(ch)
-	c.ctxt.Collect(ch)
-	c.intr.Collect(ch)
-	c.forks.Collect(ch)
-	c.btime.Collect(ch)
-	c.procsRunning.Collect(ch)
-	c.procsBlocked.Collect(ch)
-	return err
+	return labels
 }
@@ -0,0 +1,18 @@
+// +build !nostat
+// +build freebsd openbsd netbsd
+
+package collector
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// statHZ returns the frequency of the statistics clock counting the CPU
+// times.
+func statHZ() (float64, error) {
+	data, err := unix.SysctlRaw("kern.clockrate")
+	if err != nil {
+		return 0, err
+	}
+	return parseSysctlClockinfo(data, nativeEndian())
+}
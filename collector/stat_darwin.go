@@ -0,0 +1,83 @@
+// +build !nostat,darwin,cgo
+
+package collector
+
+/*
+#include <mach/mach_host.h>
+#include <mach/mach_init.h>
+#include <mach/processor_info.h>
+#include <mach/vm_map.h>
+#include <unistd.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+// cpuStateModes are the modes of struct processor_cpu_load_info, named
+// like those of Linux.
+var cpuStateModes = map[string]int{
+	"user":   C.CPU_STATE_USER,
+	"system": C.CPU_STATE_SYSTEM,
+	"idle":   C.CPU_STATE_IDLE,
+	"nice":   C.CPU_STATE_NICE,
+}
+
+type statCollector struct {
+	cpu   typedDesc
+	btime typedDesc
+}
+
+func init() {
+	registerCollector("stat", defaultEnabled, NewStatCollector)
+}
+
+// NewStatCollector returns a new Collector exposing the CPU times of
+// host_processor_info and the boot time of sysctl under the metric names of
+// Linux.
+func NewStatCollector(config Config) (Collector, error) {
+	return &statCollector{
+		cpu:   newTypedDesc("", "cpu", "Seconds the cpus spent in each mode.", prometheus.CounterValue, "cpu", "mode"),
+		btime: newTypedDesc("", "boot_time", "Node boot time, in unixtime.", prometheus.GaugeValue),
+	}, nil
+}
+
+func (c *statCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	var (
+		ncpu  C.natural_t
+		info  C.processor_info_array_t
+		count C.mach_msg_type_number_t
+	)
+	status := C.host_processor_info(C.host_t(C.mach_host_self()), C.PROCESSOR_CPU_LOAD_INFO, &ncpu, &info, &count)
+	if status != C.KERN_SUCCESS {
+		return fmt.Errorf("host_processor_info failed: %d", status)
+	}
+	defer C.vm_deallocate(C.vm_map_t(C.mach_task_self_), C.vm_address_t(uintptr(unsafe.Pointer(info))), C.vm_size_t(uintptr(count)*unsafe.Sizeof(C.integer_t(0))))
+
+	hz := float64(C.sysconf(C._SC_CLK_TCK))
+	loads := (*[1 << 16]C.processor_cpu_load_info_data_t)(unsafe.Pointer(info))[:ncpu:ncpu]
+	for i, load := range loads {
+		cpu := "cpu" + strconv.Itoa(i)
+		for mode, state := range cpuStateModes {
+			ch <- c.cpu.mustNewConstMetric(float64(load.cpu_ticks[state])/hz, cpu, mode)
+		}
+	}
+
+	data, err := unix.SysctlRaw("kern.boottime")
+	if err != nil {
+		return fmt.Errorf("couldn't get kern.boottime: %s", err)
+	}
+	btime, err := parseSysctlTimeval(data, nativeEndian())
+	if err != nil {
+		return fmt.Errorf("invalid kern.boottime: %s", err)
+	}
+	ch <- c.btime.mustNewConstMetric(btime)
+	return nil
+}
@@ -0,0 +1,90 @@
+// +build !nostat,dragonfly
+
+package collector
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+type statCollector struct {
+	cpu   typedDesc
+	intr  typedDesc
+	ctxt  typedDesc
+	forks typedDesc
+	btime typedDesc
+}
+
+func init() {
+	registerCollector("stat", defaultEnabled, NewStatCollector)
+}
+
+// NewStatCollector returns a new Collector exposing the CPU times,
+// interrupts, context switches, forks and boot time of the kernel, read with
+// sysctl under the metric names of Linux.
+func NewStatCollector(config Config) (Collector, error) {
+	return &statCollector{
+		cpu:   newTypedDesc("", "cpu", "Seconds the cpus spent in each mode.", prometheus.CounterValue, "cpu", "mode"),
+		intr:  newTypedDesc("", "intr", "Total number of interrupts serviced.", prometheus.CounterValue),
+		ctxt:  newTypedDesc("", "context_switches", "Total number of context switches.", prometheus.CounterValue),
+		forks: newTypedDesc("", "forks", "Total number of forks.", prometheus.CounterValue),
+		btime: newTypedDesc("", "boot_time", "Node boot time, in unixtime.", prometheus.GaugeValue),
+	}, nil
+}
+
+func (c *statCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	ncpu, err := unix.SysctlUint32("hw.ncpu")
+	if err != nil {
+		return fmt.Errorf("couldn't get hw.ncpu: %s", err)
+	}
+	data, err := unix.SysctlRaw("kern.cputime")
+	if err != nil {
+		return fmt.Errorf("couldn't get kern.cputime: %s", err)
+	}
+	times, err := parseSysctlCputime(data, int(ncpu), nativeEndian())
+	if err != nil {
+		return err
+	}
+	modes, _ := cpTimeModes(5)
+	for i, cpuTimes := range times {
+		cpu := "cpu" + strconv.Itoa(i)
+		for j, mode := range modes {
+			ch <- c.cpu.mustNewConstMetric(float64(cpuTimes[j])/1e6, cpu, mode)
+		}
+	}
+
+	var forks float64
+	for _, key := range []string{"vm.stats.vm.v_forks", "vm.stats.vm.v_vforks", "vm.stats.vm.v_rforks"} {
+		n, err := unix.SysctlUint32(key)
+		if err != nil {
+			return fmt.Errorf("couldn't get %s: %s", key, err)
+		}
+		forks += float64(n)
+	}
+	intr, err := unix.SysctlUint32("vm.stats.sys.v_intr")
+	if err != nil {
+		return fmt.Errorf("couldn't get vm.stats.sys.v_intr: %s", err)
+	}
+	ctxt, err := unix.SysctlUint32("vm.stats.sys.v_swtch")
+	if err != nil {
+		return fmt.Errorf("couldn't get vm.stats.sys.v_swtch: %s", err)
+	}
+	data, err = unix.SysctlRaw("kern.boottime")
+	if err != nil {
+		return fmt.Errorf("couldn't get kern.boottime: %s", err)
+	}
+	btime, err := parseSysctlTimeval(data, nativeEndian())
+	if err != nil {
+		return fmt.Errorf("invalid kern.boottime: %s", err)
+	}
+
+	ch <- c.intr.mustNewConstMetric(float64(intr))
+	ch <- c.ctxt.mustNewConstMetric(float64(ctxt))
+	ch <- c.forks.mustNewConstMetric(forks)
+	ch <- c.btime.mustNewConstMetric(btime)
+	return nil
+}
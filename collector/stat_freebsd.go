@@ -0,0 +1,96 @@
+// +build !nostat,freebsd
+
+package collector
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+// cpTimesModes are the modes of kern.cp_times, in the order of CP_USER,
+// CP_NICE, CP_SYS, CP_INTR and CP_IDLE, named like those of Linux.
+var cpTimesModes = []string{"user", "nice", "system", "irq", "idle"}
+
+type statCollector struct {
+	cpu   typedDesc
+	intr  typedDesc
+	ctxt  typedDesc
+	forks typedDesc
+	btime typedDesc
+}
+
+func init() {
+	registerCollector("stat", defaultEnabled, NewStatCollector)
+}
+
+// NewStatCollector returns a new Collector exposing the CPU times,
+// interrupts, context switches, forks and boot time of the kernel, read with
+// sysctl under the metric names of Linux.
+func NewStatCollector(config Config) (Collector, error) {
+	return &statCollector{
+		cpu:   newTypedDesc("", "cpu", "Seconds the cpus spent in each mode.", prometheus.CounterValue, "cpu", "mode"),
+		intr:  newTypedDesc("", "intr", "Total number of interrupts serviced.", prometheus.CounterValue),
+		ctxt:  newTypedDesc("", "context_switches", "Total number of context switches.", prometheus.CounterValue),
+		forks: newTypedDesc("", "forks", "Total number of forks.", prometheus.CounterValue),
+		btime: newTypedDesc("", "boot_time", "Node boot time, in unixtime.", prometheus.GaugeValue),
+	}, nil
+}
+
+func (c *statCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	hz, err := statHZ()
+	if err != nil {
+		return fmt.Errorf("couldn't get kern.clockrate: %s", err)
+	}
+	data, err := unix.SysctlRaw("kern.cp_times")
+	if err != nil {
+		return fmt.Errorf("couldn't get kern.cp_times: %s", err)
+	}
+	times, err := parseSysctlLongs(data, strconv.IntSize/8, nativeEndian())
+	if err != nil {
+		return fmt.Errorf("invalid kern.cp_times: %s", err)
+	}
+	if len(times)%len(cpTimesModes) != 0 {
+		return fmt.Errorf("invalid kern.cp_times of %d values", len(times))
+	}
+	for i := 0; i < len(times); i += len(cpTimesModes) {
+		cpu := "cpu" + strconv.Itoa(i/len(cpTimesModes))
+		for j, mode := range cpTimesModes {
+			ch <- c.cpu.mustNewConstMetric(float64(times[i+j])/hz, cpu, mode)
+		}
+	}
+
+	var forks float64
+	for _, key := range []string{"vm.stats.vm.v_forks", "vm.stats.vm.v_vforks", "vm.stats.vm.v_rforks"} {
+		n, err := unix.SysctlUint32(key)
+		if err != nil {
+			return fmt.Errorf("couldn't get %s: %s", key, err)
+		}
+		forks += float64(n)
+	}
+	intr, err := unix.SysctlUint32("vm.stats.sys.v_intr")
+	if err != nil {
+		return fmt.Errorf("couldn't get vm.stats.sys.v_intr: %s", err)
+	}
+	ctxt, err := unix.SysctlUint32("vm.stats.sys.v_swtch")
+	if err != nil {
+		return fmt.Errorf("couldn't get vm.stats.sys.v_swtch: %s", err)
+	}
+	data, err = unix.SysctlRaw("kern.boottime")
+	if err != nil {
+		return fmt.Errorf("couldn't get kern.boottime: %s", err)
+	}
+	btime, err := parseSysctlTimeval(data, nativeEndian())
+	if err != nil {
+		return fmt.Errorf("invalid kern.boottime: %s", err)
+	}
+
+	ch <- c.intr.mustNewConstMetric(float64(intr))
+	ch <- c.ctxt.mustNewConstMetric(float64(ctxt))
+	ch <- c.forks.mustNewConstMetric(forks)
+	ch <- c.btime.mustNewConstMetric(btime)
+	return nil
+}
@@ -0,0 +1,90 @@
+// +build !nostat,netbsd
+
+package collector
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+type statCollector struct {
+	cpu   typedDesc
+	intr  typedDesc
+	ctxt  typedDesc
+	forks typedDesc
+	btime typedDesc
+}
+
+func init() {
+	registerCollector("stat", defaultEnabled, NewStatCollector)
+}
+
+// NewStatCollector returns a new Collector exposing the CPU times of
+// kern.cp_time, the interrupts, context switches and forks of uvmexp2 and
+// the boot time of the kernel under the metric names of Linux.
+func NewStatCollector(config Config) (Collector, error) {
+	return &statCollector{
+		cpu:   newTypedDesc("", "cpu", "Seconds the cpus spent in each mode.", prometheus.CounterValue, "cpu", "mode"),
+		intr:  newTypedDesc("", "intr", "Total number of interrupts serviced.", prometheus.CounterValue),
+		ctxt:  newTypedDesc("", "context_switches", "Total number of context switches.", prometheus.CounterValue),
+		forks: newTypedDesc("", "forks", "Total number of forks.", prometheus.CounterValue),
+		btime: newTypedDesc("", "boot_time", "Node boot time, in unixtime.", prometheus.GaugeValue),
+	}, nil
+}
+
+func (c *statCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	hz, err := statHZ()
+	if err != nil {
+		return fmt.Errorf("couldn't get kern.clockrate: %s", err)
+	}
+	ncpu, err := unix.SysctlUint32("hw.ncpu")
+	if err != nil {
+		return fmt.Errorf("couldn't get hw.ncpu: %s", err)
+	}
+	for i := 0; i < int(ncpu); i++ {
+		// Without the cpu index, kern.cp_time is the sum over all CPUs.
+		data, err := unix.SysctlRaw("kern.cp_time", i)
+		if err != nil {
+			return fmt.Errorf("couldn't get kern.cp_time of cpu %d: %s", i, err)
+		}
+		times, err := parseSysctlLongs(data, 8, nativeEndian())
+		if err != nil {
+			return fmt.Errorf("invalid kern.cp_time: %s", err)
+		}
+		modes, err := cpTimeModes(len(times))
+		if err != nil {
+			return err
+		}
+		cpu := "cpu" + strconv.Itoa(i)
+		for j, mode := range modes {
+			ch <- c.cpu.mustNewConstMetric(float64(times[j])/hz, cpu, mode)
+		}
+	}
+
+	data, err := unix.SysctlRaw("vm.uvmexp2")
+	if err != nil {
+		return fmt.Errorf("couldn't get vm.uvmexp2: %s", err)
+	}
+	uvm, err := parseSysctlUvmexp2(data, nativeEndian())
+	if err != nil {
+		return err
+	}
+	data, err = unix.SysctlRaw("kern.boottime")
+	if err != nil {
+		return fmt.Errorf("couldn't get kern.boottime: %s", err)
+	}
+	btime, err := parseSysctlTimeval(data, nativeEndian())
+	if err != nil {
+		return fmt.Errorf("invalid kern.boottime: %s", err)
+	}
+
+	ch <- c.intr.mustNewConstMetric(float64(uvm.interrupts))
+	ch <- c.ctxt.mustNewConstMetric(float64(uvm.contextSwitches))
+	ch <- c.forks.mustNewConstMetric(float64(uvm.forks))
+	ch <- c.btime.mustNewConstMetric(btime)
+	return nil
+}
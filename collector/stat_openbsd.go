@@ -0,0 +1,89 @@
+// +build !nostat,openbsd
+
+package collector
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+)
+
+type statCollector struct {
+	cpu   typedDesc
+	intr  typedDesc
+	ctxt  typedDesc
+	btime typedDesc
+}
+
+func init() {
+	registerCollector("stat", defaultEnabled, NewStatCollector)
+}
+
+// NewStatCollector returns a new Collector exposing the CPU times of
+// kern.cp_time2, the interrupts and context switches of uvmexp and the boot
+// time of the kernel under the metric names of Linux.
+func NewStatCollector(config Config) (Collector, error) {
+	return &statCollector{
+		cpu:   newTypedDesc("", "cpu", "Seconds the cpus spent in each mode.", prometheus.CounterValue, "cpu", "mode"),
+		intr:  newTypedDesc("", "intr", "Total number of interrupts serviced.", prometheus.CounterValue),
+		ctxt:  newTypedDesc("", "context_switches", "Total number of context switches.", prometheus.CounterValue),
+		btime: newTypedDesc("", "boot_time", "Node boot time, in unixtime.", prometheus.GaugeValue),
+	}, nil
+}
+
+func (c *statCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	hz, err := statHZ()
+	if err != nil {
+		return fmt.Errorf("couldn't get kern.clockrate: %s", err)
+	}
+	ncpu, err := unix.SysctlUint32("hw.ncpu")
+	if err != nil {
+		return fmt.Errorf("couldn't get hw.ncpu: %s", err)
+	}
+	for i := 0; i < int(ncpu); i++ {
+		data, err := unix.SysctlRaw("kern.cp_time2", i)
+		if err == unix.ENODEV { // offline
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't get kern.cp_time2 of cpu %d: %s", i, err)
+		}
+		times, err := parseSysctlLongs(data, 8, nativeEndian())
+		if err != nil {
+			return fmt.Errorf("invalid kern.cp_time2: %s", err)
+		}
+		modes, err := cpTimeModes(len(times))
+		if err != nil {
+			return err
+		}
+		cpu := "cpu" + strconv.Itoa(i)
+		for j, mode := range modes {
+			ch <- c.cpu.mustNewConstMetric(float64(times[j])/hz, cpu, mode)
+		}
+	}
+
+	data, err := unix.SysctlRaw("vm.uvmexp")
+	if err != nil {
+		return fmt.Errorf("couldn't get vm.uvmexp: %s", err)
+	}
+	uvm, err := parseSysctlUvmexp(data, nativeEndian())
+	if err != nil {
+		return err
+	}
+	data, err = unix.SysctlRaw("kern.boottime")
+	if err != nil {
+		return fmt.Errorf("couldn't get kern.boottime: %s", err)
+	}
+	btime, err := parseSysctlTimeval(data, nativeEndian())
+	if err != nil {
+		return fmt.Errorf("invalid kern.boottime: %s", err)
+	}
+
+	ch <- c.intr.mustNewConstMetric(float64(uvm.interrupts))
+	ch <- c.ctxt.mustNewConstMetric(float64(uvm.contextSwitches))
+	ch <- c.btime.mustNewConstMetric(btime)
+	return nil
+}
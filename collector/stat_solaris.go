@@ -0,0 +1,89 @@
+// +build !nostat,solaris,cgo
+
+package collector
+
+/*
+#include <sys/sysinfo.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+// cpuStatModes are the CPU_STATES of struct cpu_sysinfo, named like the
+// modes of Linux.
+var cpuStatModes = map[string]int{
+	"idle":   C.CPU_IDLE,
+	"user":   C.CPU_USER,
+	"system": C.CPU_KERNEL,
+	"iowait": C.CPU_WAIT,
+}
+
+type statCollector struct {
+	cpu   typedDesc
+	intr  typedDesc
+	ctxt  typedDesc
+	forks typedDesc
+	btime typedDesc
+}
+
+func init() {
+	registerCollector("stat", defaultEnabled, NewStatCollector)
+}
+
+// NewStatCollector returns a new Collector exposing the CPU times,
+// interrupts, context switches and forks of the cpu_stat kstats and the boot
+// time of unix:0:system_misc under the metric names of Linux.
+func NewStatCollector(config Config) (Collector, error) {
+	return &statCollector{
+		cpu:   newTypedDesc("", "cpu", "Seconds the cpus spent in each mode.", prometheus.CounterValue, "cpu", "mode"),
+		intr:  newTypedDesc("", "intr", "Total number of interrupts serviced.", prometheus.CounterValue),
+		ctxt:  newTypedDesc("", "context_switches", "Total number of context switches.", prometheus.CounterValue),
+		forks: newTypedDesc("", "forks", "Total number of forks.", prometheus.CounterValue),
+		btime: newTypedDesc("", "boot_time", "Node boot time, in unixtime.", prometheus.GaugeValue),
+	}, nil
+}
+
+func (c *statCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	kc, err := openKstat()
+	if err != nil {
+		return err
+	}
+	defer kc.close()
+
+	cpus, err := kc.module("cpu_stat")
+	if err != nil {
+		return err
+	}
+	var intr, ctxt, forks float64
+	for _, ks := range cpus {
+		info := (*C.cpu_stat_t)(ks.data()).cpu_sysinfo
+		cpu := "cpu" + strconv.Itoa(ks.instance())
+		for mode, state := range cpuStatModes {
+			ch <- c.cpu.mustNewConstMetric(float64(info.cpu[state])/userHZ, cpu, mode)
+		}
+		intr += float64(info.intr)
+		ctxt += float64(info.pswitch)
+		forks += float64(info.sysfork) + float64(info.sysvfork)
+	}
+
+	misc, err := kc.lookup("unix", 0, "system_misc")
+	if err != nil {
+		return err
+	}
+	btime, err := misc.named("boot_time")
+	if err != nil {
+		return fmt.Errorf("couldn't get boot time: %s", err)
+	}
+
+	ch <- c.intr.mustNewConstMetric(intr)
+	ch <- c.ctxt.mustNewConstMetric(ctxt)
+	ch <- c.forks.mustNewConstMetric(forks)
+	ch <- c.btime.mustNewConstMetric(btime)
+	return nil
+}
@@ -0,0 +1,99 @@
+// +build linux
+
+package collector
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+// statContext returns a scrape context holding data as /proc/stat.
+func statContext(data []byte) context.Context {
+	ctx := WithScrapeCache(context.Background())
+	cached(ctx, "file:stat", func() (interface{}, error) { return data, nil })
+	return ctx
+}
+
+func TestStatCollector(t *testing.T) {
+	data, err := ioutil.ReadFile("procfs/fixtures/stat-linux-4.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewStatCollector(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan prometheus.Metric, 100)
+	if err := c.Update(statContext(data), ch); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+	// 2 cpus with 10 modes, the removed cpu counter, 6 totals and 10
+	// softirq types.
+	if want, got := 2*10+1+6+10, len(ch); want != got {
+		t.Errorf("want %d metrics, got %d", want, got)
+	}
+}
+
+func TestStatCollectorCPUTotal(t *testing.T) {
+	defer func(old bool) { *statCPUTotal = old }(*statCPUTotal)
+	*statCPUTotal = true
+	data, err := ioutil.ReadFile("procfs/fixtures/stat-linux-4.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewStatCollector(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan prometheus.Metric, 100)
+	if err := c.Update(statContext(data), ch); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+	total := <-ch
+	if desc := total.Desc().String(); !strings.Contains(desc, `"node_cpu_total"`) {
+		t.Errorf("want node_cpu_total first, got %s", desc)
+	}
+	var m dto.Metric
+	if err := total.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	labels := map[string]string{}
+	for _, l := range m.GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+	if _, ok := labels["cpu"]; ok || labels["mode"] != "user" || m.GetCounter().GetValue() != 3018.54 {
+		t.Errorf("want total user time 3018.54 without cpu label, got %v %f", labels, m.GetCounter().GetValue())
+	}
+	if want, got := 3*10+1+6+10, len(ch)+1; want != got {
+		t.Errorf("want %d metrics, got %d", want, got)
+	}
+}
+
+func BenchmarkStatCollector(b *testing.B) {
+	data, err := ioutil.ReadFile("procfs/fixtures/stat-linux-4.4")
+	if err != nil {
+		b.Fatal(err)
+	}
+	c, err := NewStatCollector(Config{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	ch := make(chan prometheus.Metric, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := c.Update(statContext(data), ch); err != nil {
+			b.Fatal(err)
+		}
+		for len(ch) > 0 {
+			<-ch
+		}
+	}
+}
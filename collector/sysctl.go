@@ -0,0 +1,119 @@
+// +build !nosysctl
+
+package collector
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
+)
+
+var (
+	sysctlKeys = flag.String("collector.sysctl.keys", "", "Comma-separated list of sysctl keys to export, e.g. net.core.somaxconn,vm.swappiness.")
+)
+
+type sysctlCollector struct {
+	config Config
+	keys   []string
+
+	// mu guards metric, which is reset on every update.
+	mu     sync.Mutex
+	metric *prometheus.GaugeVec
+}
+
+func init() {
+	registerCollector("sysctl", defaultDisabled, NewSysctlCollector)
+}
+
+// NewSysctlCollector returns a new Collector exposing the numeric values of
+// the configured sysctl keys. Keys are taken from --collector.sysctl.keys
+// and the sysctl_keys config entry.
+func NewSysctlCollector(config Config) (Collector, error) {
+	keys := []string{}
+	for _, list := range []string{*sysctlKeys, config.Config["sysctl_keys"]} {
+		for _, key := range strings.Split(list, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("No sysctl keys specified, see --collector.sysctl.keys")
+	}
+
+	return &sysctlCollector{
+		config: config,
+		keys:   keys,
+		metric: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Name:      "sysctl",
+				Help:      "Value of a sysctl key. Keys with several values, like net.ipv4.tcp_mem, are exported once per index.",
+			},
+			[]string{"key", "index"},
+		),
+	}, nil
+}
+
+func (c *sysctlCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Keys missing on this kernel or not numeric don't hold up the others.
+	c.metric.Reset()
+	for _, key := range c.keys {
+		values, err := readSysctl(key)
+		if err != nil {
+			log.Debugf("Couldn't get sysctl %s: %s", key, err)
+			continue
+		}
+		for i, v := range values {
+			c.metric.WithLabelValues(key, strconv.Itoa(i)).Set(v)
+		}
+	}
+	c.metric.Collect(ch)
+	return nil
+}
+
+func readSysctl(key string) ([]float64, error) {
+	data, err := ioutil.ReadFile(sysctlPath(key))
+	if err != nil {
+		return nil, err
+	}
+	return parseSysctl(string(data))
+}
+
+// sysctlPath maps a sysctl key like net.core.somaxconn to its file below
+// /proc/sys. Keys with slashes, like net/ipv4/conf/eth0.100/forwarding, are
+// taken as paths, so that the dots of interface names are kept, like
+// sysctl(8) does.
+func sysctlPath(key string) string {
+	if !strings.Contains(key, "/") {
+		key = strings.Replace(key, ".", "/", -1)
+	}
+	return procFilePath(path.Join("sys", key))
+}
+
+func parseSysctl(data string) ([]float64, error) {
+	parts := strings.Fields(data)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	values := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not numeric", part)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+func TestSysctlPath(t *testing.T) {
+	for key, want := range map[string]string{
+		"net.core.somaxconn":                "/proc/sys/net/core/somaxconn",
+		"net/ipv4/conf/eth0.100/forwarding": "/proc/sys/net/ipv4/conf/eth0.100/forwarding",
+	} {
+		if got := sysctlPath(key); want != got {
+			t.Errorf("want sysctl path %s for %s, got %s", want, key, got)
+		}
+	}
+}
+
+func TestSysctl(t *testing.T) {
+	values, err := parseSysctl("188289\t251054\t376578\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 3, len(values); want != got {
+		t.Fatalf("want %d sysctl values, got %d", want, got)
+	}
+	if want, got := 251054.0, values[1]; want != got {
+		t.Errorf("want sysctl value %f, got %f", want, got)
+	}
+
+	if _, err := parseSysctl("cubic\n"); err == nil {
+		t.Error("want error for non-numeric sysctl value")
+	}
+}
+
+func TestSysctlMissingKey(t *testing.T) {
+	defer func(old string) { *procPath = old }(*procPath)
+	*procPath = "fixtures/proc"
+
+	c, err := NewSysctlCollector(Config{Config: map[string]string{"sysctl_keys": "vm.nonexistent,vm.swappiness"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan prometheus.Metric, 10)
+	if err := c.Update(context.Background(), ch); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	got := map[string]float64{}
+	for m := range ch {
+		pb := &dto.Metric{}
+		if err := m.Write(pb); err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range pb.Label {
+			if l.GetName() == "key" {
+				got[l.GetValue()] = pb.Gauge.GetValue()
+			}
+		}
+	}
+	if want := map[string]float64{"vm.swappiness": 60}; len(got) != len(want) || got["vm.swappiness"] != want["vm.swappiness"] {
+		t.Errorf("want sysctl values %v, got %v", want, got)
+	}
+}
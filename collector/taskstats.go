@@ -0,0 +1,321 @@
+// +build !notaskstats,linux
+
+package collector
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector/procfs"
+	"golang.org/x/net/context"
+)
+
+const taskstatsSubsystem = "taskstats"
+
+var (
+	taskstatsGroups = flag.String("collector.taskstats.groups", "", "Comma-separated list of name=regexp process groups, matched against /proc/[pid]/cmdline, to export the delays of.")
+)
+
+// Generic netlink and taskstats constants, see include/uapi/linux/genetlink.h
+// and include/uapi/linux/taskstats.h.
+const (
+	genlIDCtrl         = 0x10
+	genlHdrLen         = 4
+	ctrlCmdGetFamily   = 3
+	ctrlVersion        = 1
+	ctrlAttrFamilyID   = 1
+	ctrlAttrFamilyName = 2
+
+	taskstatsFamilyName   = "TASKSTATS"
+	taskstatsVersion      = 1
+	taskstatsCmdGet       = 1
+	taskstatsCmdAttrTGID  = 2
+	taskstatsTypeStats    = 3
+	taskstatsTypeAggrTGID = 5
+
+	// Offset of cpu_count in struct taskstats, followed by the delay
+	// accounting counters.
+	taskstatsDelaysOffset = 16
+	taskstatsDelaysLen    = 6 * 8
+
+	taskstatsTimeout = 5 * time.Second
+)
+
+// taskstatsDelayKinds are the delays accounted by the kernel, in the order of
+// struct taskstats.
+var taskstatsDelayKinds = []struct {
+	name, help string
+}{
+	{"cpu", "waiting for a CPU while runnable"},
+	{"blkio", "waiting for synchronous block I/O to complete"},
+	{"swapin", "waiting for pages to be swapped in"},
+}
+
+// taskstatsDelays holds the number of delays and their total duration in
+// nanoseconds per kind of taskstatsDelayKinds.
+type taskstatsDelays [3]struct {
+	count, total uint64
+}
+
+func (d *taskstatsDelays) add(o taskstatsDelays) {
+	for i := range d {
+		d[i].count += o[i].count
+		d[i].total += o[i].total
+	}
+}
+
+// counters returns the counts and totals of d, alternating per kind, as
+// kept by exitedCounters.
+func (d taskstatsDelays) counters() []float64 {
+	counters := make([]float64, 0, 2*len(d))
+	for _, k := range d {
+		counters = append(counters, float64(k.count), float64(k.total))
+	}
+	return counters
+}
+
+type taskstatsCollector struct {
+	groups map[string]*regexp.Regexp
+
+	// mu guards the delays of the processes kept between updates, by group
+	// and "" for all processes.
+	mu     sync.Mutex
+	exited *exitedCounters
+
+	delays, delaySeconds           []typedDesc
+	groupDelays, groupDelaySeconds []typedDesc
+}
+
+func init() {
+	registerCollector("taskstats", defaultDisabled, NewTaskstatsCollector)
+}
+
+// NewTaskstatsCollector returns a new Collector exposing the delay accounting
+// of the taskstats netlink interface, summed up over all processes and over
+// the groups of --collector.taskstats.groups and taskstats_group_<name>
+// config entries.
+func NewTaskstatsCollector(config Config) (Collector, error) {
+	groups, err := parseProcessGroups(*taskstatsGroups, config, "taskstats_group_")
+	if err != nil {
+		return nil, err
+	}
+	c := &taskstatsCollector{groups: groups, exited: newExitedCounters(2 * len(taskstatsDelayKinds))}
+	for _, k := range taskstatsDelayKinds {
+		c.delays = append(c.delays, newTypedDesc(taskstatsSubsystem, k.name+"_delays_total",
+			fmt.Sprintf("Number of times processes were delayed %s.", k.help), prometheus.CounterValue))
+		c.delaySeconds = append(c.delaySeconds, newTypedDesc(taskstatsSubsystem, k.name+"_delay_seconds_total",
+			fmt.Sprintf("Seconds processes spent %s.", k.help), prometheus.CounterValue))
+		c.groupDelays = append(c.groupDelays, newTypedDesc(taskstatsSubsystem, "group_"+k.name+"_delays_total",
+			fmt.Sprintf("Number of times the processes of a group were delayed %s.", k.help), prometheus.CounterValue, "group"))
+		c.groupDelaySeconds = append(c.groupDelaySeconds, newTypedDesc(taskstatsSubsystem, "group_"+k.name+"_delay_seconds_total",
+			fmt.Sprintf("Seconds the processes of a group spent %s.", k.help), prometheus.CounterValue, "group"))
+	}
+	return c, nil
+}
+
+func (c *taskstatsCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	conn, err := newGenlConn()
+	if err != nil {
+		return fmt.Errorf("couldn't open generic netlink socket: %s", err)
+	}
+	defer conn.close()
+	family, err := conn.family(taskstatsFamilyName)
+	if err != nil {
+		return fmt.Errorf("couldn't resolve taskstats netlink family: %s", err)
+	}
+
+	pids, err := allProcesses(*procPath)
+	if err != nil {
+		return fmt.Errorf("couldn't list processes: %s", err)
+	}
+	// The delays of the processes by group, "" holding all processes.
+	current := map[string]map[int][]float64{"": {}}
+	for name := range c.groups {
+		// Export empty groups as well, so that absence can be alerted on.
+		current[name] = map[int][]float64{}
+	}
+	for _, pid := range pids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		d, err := conn.taskstats(family, pid)
+		if err == syscall.ESRCH { // exited
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't get taskstats of process %d: %s", pid, err)
+		}
+		delays := d.counters()
+		current[""][pid] = delays
+		if len(c.groups) == 0 {
+			continue
+		}
+		cmdline, err := procfs.ReadFile(path.Join(*procPath, strconv.Itoa(pid), "cmdline"))
+		if err != nil || len(cmdline) == 0 { // exited or kernel thread
+			continue
+		}
+		cmdline = bytes.TrimSpace(bytes.Replace(cmdline, []byte{0}, []byte{' '}, -1))
+		for name, pattern := range c.groups {
+			if pattern.Match(cmdline) {
+				current[name][pid] = delays
+			}
+		}
+	}
+
+	// Processes exit all the time, their delays are kept in the counters.
+	c.mu.Lock()
+	totals := c.exited.update(current)
+	c.mu.Unlock()
+	all := totals[""]
+	for i := range taskstatsDelayKinds {
+		ch <- c.delays[i].mustNewConstMetric(all[2*i])
+		ch <- c.delaySeconds[i].mustNewConstMetric(all[2*i+1] / float64(time.Second))
+		for name := range c.groups {
+			d := totals[name]
+			ch <- c.groupDelays[i].mustNewConstMetric(d[2*i], name)
+			ch <- c.groupDelaySeconds[i].mustNewConstMetric(d[2*i+1]/float64(time.Second), name)
+		}
+	}
+	return nil
+}
+
+// genlConn is a generic netlink socket sending one request at a time.
+type genlConn struct {
+	fd  int
+	seq uint32
+	buf []byte
+}
+
+func newGenlConn() (*genlConn, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_GENERIC)
+	if err != nil {
+		return nil, err
+	}
+	tv := syscall.NsecToTimeval(int64(taskstatsTimeout))
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return &genlConn{fd: fd, buf: make([]byte, 16*1024)}, nil
+}
+
+func (c *genlConn) close() error {
+	return syscall.Close(c.fd)
+}
+
+// request sends a generic netlink request and returns the attributes of the
+// response. They point into the buffer of c and are only valid until the
+// next request.
+func (c *genlConn) request(family uint16, cmd, version uint8, attrs ...[]byte) ([]syscall.NetlinkRouteAttr, error) {
+	c.seq++
+	payload := []byte{cmd, version, 0, 0}
+	for _, a := range attrs {
+		payload = append(payload, a...)
+	}
+	msg := newNetlinkMessage(family, syscall.NLM_F_REQUEST, c.seq, payload)
+	if err := syscall.Sendto(c.fd, msg, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, err
+	}
+	for {
+		n, _, err := syscall.Recvfrom(c.fd, c.buf, 0)
+		if err != nil {
+			return nil, err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(c.buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range msgs {
+			if m.Header.Seq != c.seq {
+				continue
+			}
+			return parseGenlResponse(m)
+		}
+	}
+}
+
+// parseGenlResponse returns the attributes of a generic netlink response, or
+// the error it carries.
+func parseGenlResponse(m syscall.NetlinkMessage) ([]syscall.NetlinkRouteAttr, error) {
+	if m.Header.Type == syscall.NLMSG_ERROR {
+		if len(m.Data) < 4 {
+			return nil, fmt.Errorf("truncated netlink error")
+		}
+		if errno := int32(nativeEndian().Uint32(m.Data)); errno != 0 {
+			return nil, syscall.Errno(-errno)
+		}
+		return nil, nil
+	}
+	if len(m.Data) < genlHdrLen {
+		return nil, fmt.Errorf("truncated generic netlink message")
+	}
+	return parseNetlinkAttrs(m.Data[genlHdrLen:])
+}
+
+// family returns the id of the named generic netlink family.
+func (c *genlConn) family(name string) (uint16, error) {
+	attrs, err := c.request(genlIDCtrl, ctrlCmdGetFamily, ctrlVersion, netlinkAttr(ctrlAttrFamilyName, append([]byte(name), 0)))
+	if err != nil {
+		return 0, err
+	}
+	for _, a := range attrs {
+		if a.Attr.Type == ctrlAttrFamilyID && len(a.Value) >= 2 {
+			return nativeEndian().Uint16(a.Value), nil
+		}
+	}
+	return 0, fmt.Errorf("no id in response for family %s", name)
+}
+
+// taskstats returns the delays of all threads of the process pid, including
+// those that exited.
+func (c *genlConn) taskstats(family uint16, pid int) (taskstatsDelays, error) {
+	tgid := make([]byte, 4)
+	nativeEndian().PutUint32(tgid, uint32(pid))
+	attrs, err := c.request(family, taskstatsCmdGet, taskstatsVersion, netlinkAttr(taskstatsCmdAttrTGID, tgid))
+	if err != nil {
+		return taskstatsDelays{}, err
+	}
+	return parseTaskstats(attrs)
+}
+
+// parseTaskstats returns the delays of the struct taskstats nested in the
+// attributes of a TASKSTATS_CMD_GET response.
+func parseTaskstats(attrs []syscall.NetlinkRouteAttr) (taskstatsDelays, error) {
+	var d taskstatsDelays
+	for _, a := range attrs {
+		if a.Attr.Type != taskstatsTypeAggrTGID {
+			continue
+		}
+		nested, err := parseNetlinkAttrs(a.Value)
+		if err != nil {
+			return d, err
+		}
+		for _, n := range nested {
+			if n.Attr.Type != taskstatsTypeStats {
+				continue
+			}
+			if len(n.Value) < taskstatsDelaysOffset+taskstatsDelaysLen {
+				return d, fmt.Errorf("truncated taskstats of %d bytes", len(n.Value))
+			}
+			b := n.Value[taskstatsDelaysOffset:]
+			for i := range d {
+				d[i].count = nativeEndian().Uint64(b[16*i:])
+				d[i].total = nativeEndian().Uint64(b[16*i+8:])
+			}
+			return d, nil
+		}
+	}
+	return d, fmt.Errorf("no taskstats in response")
+}
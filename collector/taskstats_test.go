@@ -0,0 +1,58 @@
+// +build linux
+
+package collector
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParseTaskstats(t *testing.T) {
+	stats := make([]byte, 328) // struct taskstats of version 8
+	nativeEndian().PutUint16(stats, 8)
+	for i, v := range []uint64{3, 1500000000, 2, 250000000, 1, 1000} {
+		nativeEndian().PutUint64(stats[taskstatsDelaysOffset+8*i:], v)
+	}
+	tgid := make([]byte, 4)
+	nativeEndian().PutUint32(tgid, 42)
+	aggr := append(netlinkAttr(taskstatsCmdAttrTGID, tgid), netlinkAttr(taskstatsTypeStats, stats)...)
+	payload := append([]byte{taskstatsCmdGet + 1, taskstatsVersion, 0, 0}, netlinkAttr(taskstatsTypeAggrTGID, aggr)...)
+
+	msgs, err := syscall.ParseNetlinkMessage(newNetlinkMessage(27, 0, 1, payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs, err := parseGenlResponse(msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := parseTaskstats(attrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []struct{ count, total uint64 }{{3, 1500000000}, {2, 250000000}, {1, 1000}} {
+		if d[i].count != want.count || d[i].total != want.total {
+			t.Errorf("want %s delays %d/%d, got %d/%d", taskstatsDelayKinds[i].name, want.count, want.total, d[i].count, d[i].total)
+		}
+	}
+
+	var sum taskstatsDelays
+	sum.add(d)
+	sum.add(d)
+	if want, got := uint64(6), sum[0].count; want != got {
+		t.Errorf("want summed cpu delays %d, got %d", want, got)
+	}
+}
+
+func TestParseGenlResponseError(t *testing.T) {
+	data := make([]byte, syscall.SizeofNlMsgerr)
+	errno := -int32(syscall.ESRCH)
+	nativeEndian().PutUint32(data, uint32(errno))
+	msgs, err := syscall.ParseNetlinkMessage(newNetlinkMessage(syscall.NLMSG_ERROR, 0, 1, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseGenlResponse(msgs[0]); err != syscall.ESRCH {
+		t.Errorf("want ESRCH, got %v", err)
+	}
+}
@@ -9,69 +9,81 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	dto "github.com/prometheus/client_model/go"
 
 	"code.google.com/p/goprotobuf/proto"
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/text"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
 )
 
 var (
 	textFileDirectory = flag.String("collector.textfile.directory", "", "Directory to read text files with metrics from.")
+
+	// Number of failed attempts to read or parse each file, over all scrapes.
+	textFileParseErrors    = map[string]float64{}
+	textFileParseErrorsMtx sync.Mutex
 )
 
 type textFileCollector struct {
 }
 
 func init() {
-	Factories["textfile"] = NewTextFileCollector
+	registerCollector("textfile", defaultEnabled, NewTextFileCollector)
 }
 
 // Takes a config struct and registers a
 // SetMetricFamilyInjectionHook.
 func NewTextFileCollector(config Config) (Collector, error) {
-	if *textFileDirectory == "" {
+	directory := config.Options("textfile").String("directory")
+	if directory == "" {
 		// This collector is enabled by default, so do not fail if
 		// the flag is not passed.
-		glog.Infof("No directory specified, see --textfile.directory")
+		log.Infof("No directory specified, see --collector.textfile.directory")
 	} else {
-		prometheus.SetMetricFamilyInjectionHook(parseTextFiles)
+		prometheus.SetMetricFamilyInjectionHook(func() []*dto.MetricFamily {
+			return parseTextFiles(directory)
+		})
 	}
 
 	return &textFileCollector{}, nil
 }
 
 // textFile collector works via SetMetricFamilyInjectionHook in parseTextFiles.
-func (c *textFileCollector) Update(ch chan<- prometheus.Metric) (err error) {
+func (c *textFileCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
 	return nil
 }
 
-func parseTextFiles() []*dto.MetricFamily {
+func parseTextFiles(directory string) []*dto.MetricFamily {
 	var parser text.Parser
 	error := 0.0
 	metricFamilies := make([]*dto.MetricFamily, 0)
 	mtimes := map[string]time.Time{}
 
 	// Iterate over files and accumulate their metrics.
-	files, _ := ioutil.ReadDir(*textFileDirectory)
+	files, _ := ioutil.ReadDir(directory)
 	for _, f := range files {
 		if !strings.HasSuffix(f.Name(), ".prom") {
 			continue
 		}
-		path := filepath.Join(*textFileDirectory, f.Name())
+		path := filepath.Join(directory, f.Name())
 		file, err := os.Open(path)
 		if err != nil {
-			glog.Errorf("Error opening %s: %v", path, err)
+			log.Errorf("Error opening %s: %v", path, err)
 			error = 1.0
+			countTextFileParseError(f.Name())
 			continue
 		}
 		parsedFamilies, err := parser.TextToMetricFamilies(file)
+		file.Close()
 		if err != nil {
-			glog.Errorf("Error parsing %s: %v", path, err)
+			log.Errorf("Error parsing %s: %v", path, err)
 			error = 1.0
+			countTextFileParseError(f.Name())
 			continue
 		}
 		// Only set this once it has been parsed, so that
@@ -89,7 +101,7 @@ func parseTextFiles() []*dto.MetricFamily {
 	// Export the mtimes of the successful files.
 	if len(mtimes) > 0 {
 		mtimeMetricFamily := dto.MetricFamily{
-			Name:   proto.String("node_textfile_mtime"),
+			Name:   proto.String(Namespace + "_textfile_mtime"),
 			Help:   proto.String("Unixtime mtime of textfiles successfully read."),
 			Type:   dto.MetricType_GAUGE.Enum(),
 			Metric: []*dto.Metric{},
@@ -109,9 +121,34 @@ func parseTextFiles() []*dto.MetricFamily {
 		}
 		metricFamilies = append(metricFamilies, &mtimeMetricFamily)
 	}
+	// Export the error counts of all files that ever failed.
+	textFileParseErrorsMtx.Lock()
+	if len(textFileParseErrors) > 0 {
+		parseErrorsMetricFamily := dto.MetricFamily{
+			Name:   proto.String(Namespace + "_textfile_parse_errors"),
+			Help:   proto.String("Number of times opening or parsing a textfile failed."),
+			Type:   dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{},
+		}
+		for name, count := range textFileParseErrors {
+			parseErrorsMetricFamily.Metric = append(parseErrorsMetricFamily.Metric,
+				&dto.Metric{
+					Label: []*dto.LabelPair{
+						&dto.LabelPair{
+							Name:  proto.String("file"),
+							Value: proto.String(name),
+						},
+					},
+					Counter: &dto.Counter{Value: proto.Float64(count)},
+				},
+			)
+		}
+		metricFamilies = append(metricFamilies, &parseErrorsMetricFamily)
+	}
+	textFileParseErrorsMtx.Unlock()
 	// Export if there were errors.
 	metricFamilies = append(metricFamilies, &dto.MetricFamily{
-		Name: proto.String("node_textfile_scrape_error"),
+		Name: proto.String(Namespace + "_textfile_scrape_error"),
 		Help: proto.String("1 if there was an error opening or reading a file, 0 otherwise"),
 		Type: dto.MetricType_GAUGE.Enum(),
 		Metric: []*dto.Metric{
@@ -123,3 +160,9 @@ func parseTextFiles() []*dto.MetricFamily {
 
 	return metricFamilies
 }
+
+func countTextFileParseError(name string) {
+	textFileParseErrorsMtx.Lock()
+	defer textFileParseErrorsMtx.Unlock()
+	textFileParseErrors[name]++
+}
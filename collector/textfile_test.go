@@ -0,0 +1,36 @@
+package collector
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestTextFiles(t *testing.T) {
+	for scrape := 1; scrape <= 2; scrape++ {
+		families := map[string]*dto.MetricFamily{}
+		for _, mf := range parseTextFiles("fixtures/textfile") {
+			families[mf.GetName()] = mf
+		}
+
+		if _, ok := families["role"]; !ok {
+			t.Error("want metric role from role.prom")
+		}
+		if _, ok := families["ignored"]; ok {
+			t.Error("want files without .prom suffix to be ignored")
+		}
+		if want, got := 1, len(families["node_textfile_mtime"].GetMetric()); want != got {
+			t.Errorf("want %d mtimes, got %d", want, got)
+		}
+		if want, got := 1.0, families["node_textfile_scrape_error"].GetMetric()[0].GetGauge().GetValue(); want != got {
+			t.Errorf("want scrape error %f, got %f", want, got)
+		}
+		parseErrors := families["node_textfile_parse_errors"].GetMetric()
+		if want, got := 1, len(parseErrors); want != got {
+			t.Fatalf("want parse errors for %d file, got %d", want, got)
+		}
+		if want, got := float64(scrape), parseErrors[0].GetCounter().GetValue(); want != got {
+			t.Errorf("want %f parse errors, got %f", want, got)
+		}
+	}
+}
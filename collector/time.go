@@ -5,8 +5,9 @@ package collector
 import (
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
 )
 
 type timeCollector struct {
@@ -15,7 +16,7 @@ type timeCollector struct {
 }
 
 func init() {
-	Factories["time"] = NewTimeCollector
+	registerCollector("time", defaultEnabled, NewTimeCollector)
 }
 
 // Takes a config struct and prometheus registry and returns a new Collector exposing
@@ -31,9 +32,9 @@ func NewTimeCollector(config Config) (Collector, error) {
 	}, nil
 }
 
-func (c *timeCollector) Update(ch chan<- prometheus.Metric) (err error) {
+func (c *timeCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
 	now := time.Now()
-	glog.V(1).Infof("Set time: %f", now.Unix())
+	log.Debugf("Set time: %d", now.Unix())
 	c.metric.Set(float64(now.Unix()))
 	c.metric.Collect(ch)
 	return err
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+// Update runs c.Update, passing its metrics on to ch, so that a failing
+// collector can't break the whole scrape: a panic is returned as error, and
+// metrics that can't be written, which would make the client library reject
+// the scrape, are dropped and reported as error once the update is done.
+//
+// The update is given up once the timeout, if not 0, expires or ctx is done.
+// The collector gets a context ending at the timeout; one ignoring it is left
+// running and its remaining metrics are discarded.
+func Update(ctx context.Context, c Collector, ch chan<- prometheus.Metric, timeout time.Duration) error {
+	if timeout <= 0 && ctx.Done() == nil {
+		return isolatedUpdate(ctx, c, ch)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	metrics := make(chan prometheus.Metric)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- isolatedUpdate(ctx, c, metrics)
+		close(metrics)
+	}()
+
+	for {
+		select {
+		case m, ok := <-metrics:
+			if !ok {
+				return <-errc
+			}
+			ch <- m
+		case <-ctx.Done():
+			go func() {
+				for range metrics {
+				}
+			}()
+			if ctx.Err() == context.DeadlineExceeded && timeout > 0 {
+				return fmt.Errorf("timed out after %s", timeout)
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+func isolatedUpdate(ctx context.Context, c Collector, ch chan<- prometheus.Metric) error {
+	metrics := make(chan prometheus.Metric)
+	invalid := make(chan error, 1)
+	go func() {
+		var dropped int
+		var first error
+		for m := range metrics {
+			err := fmt.Errorf("nil metric")
+			if m != nil {
+				err = m.Write(&dto.Metric{})
+			}
+			if err != nil {
+				if first == nil {
+					first = err
+				}
+				dropped++
+				continue
+			}
+			ch <- m
+		}
+		if dropped > 0 {
+			invalid <- fmt.Errorf("dropped %d invalid metrics: %s", dropped, first)
+		}
+		close(invalid)
+	}()
+
+	err := recoverUpdate(ctx, c, metrics)
+	close(metrics)
+	if e := <-invalid; e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+func recoverUpdate(ctx context.Context, c Collector, ch chan<- prometheus.Metric) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return c.Update(ctx, ch)
+}
@@ -0,0 +1,113 @@
+package collector
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+type brokenMetric struct {
+	prometheus.Metric
+}
+
+func (brokenMetric) Write(*dto.Metric) error {
+	return errors.New("broken")
+}
+
+type funcCollector func(ch chan<- prometheus.Metric) error
+
+func (f funcCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	return f(ch)
+}
+
+func TestIsolatedUpdate(t *testing.T) {
+	good := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"})
+
+	for i, test := range []struct {
+		update  func(ch chan<- prometheus.Metric) error
+		err     string
+		metrics int
+	}{
+		{
+			update: func(ch chan<- prometheus.Metric) error {
+				ch <- good
+				return nil
+			},
+			metrics: 1,
+		},
+		{
+			update: func(ch chan<- prometheus.Metric) error {
+				ch <- good
+				panic("index out of range")
+			},
+			err:     "panic: index out of range",
+			metrics: 1,
+		},
+		{
+			update: func(ch chan<- prometheus.Metric) error {
+				ch <- good
+				ch <- brokenMetric{}
+				ch <- nil
+				return nil
+			},
+			err:     "dropped 2 invalid metrics: broken",
+			metrics: 1,
+		},
+		{
+			update: func(ch chan<- prometheus.Metric) error {
+				ch <- brokenMetric{}
+				return errors.New("read failed")
+			},
+			err: "read failed",
+		},
+	} {
+		ch := make(chan prometheus.Metric, 3)
+		err := Update(context.Background(), funcCollector(test.update), ch, 0)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%d. want error %q, got %v", i, test.err, err)
+		}
+		if want, got := test.metrics, len(ch); want != got {
+			t.Errorf("%d. want %d metrics, got %d", i, want, got)
+		}
+	}
+}
+
+type sleepCollector struct {
+	metric prometheus.Gauge
+	sleep  time.Duration
+}
+
+func (c sleepCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	ch <- c.metric
+	time.Sleep(c.sleep)
+	ch <- c.metric
+	return nil
+}
+
+func TestUpdateTimeout(t *testing.T) {
+	c := sleepCollector{
+		metric: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"}),
+		sleep:  time.Second,
+	}
+	ch := make(chan prometheus.Metric, 2)
+	if err := Update(context.Background(), c, ch, 10*time.Millisecond); err == nil {
+		t.Error("expected timeout error")
+	}
+	if want, got := 1, len(ch); want != got {
+		t.Errorf("want %d metrics before the timeout, got %d", want, got)
+	}
+
+	c.sleep = 0
+	ch = make(chan prometheus.Metric, 2)
+	if err := Update(context.Background(), c, ch, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 2, len(ch); want != got {
+		t.Errorf("want %d metrics, got %d", want, got)
+	}
+}
@@ -0,0 +1,145 @@
+// +build !nousers
+
+package collector
+
+import (
+	"flag"
+	"fmt"
+	"os/user"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const usersSubsystem = "user"
+
+var (
+	usersMinRss = flag.Float64("collector.users.min-rss-bytes", 0, "Only export users whose processes use at least this much resident memory.")
+)
+
+type usersCollector struct {
+	config Config
+
+	// mu guards the state reused by updates, which run concurrently when a
+	// timed out update is still running: the cached user names, the CPU
+	// times of the processes and the metric vectors.
+	mu             sync.Mutex
+	names          map[uint32]string
+	cpuTimes       *exitedCounters
+	cpu            *prometheus.CounterVec
+	rss, processes *prometheus.GaugeVec
+}
+
+type userStats struct {
+	rss   float64
+	procs int
+}
+
+func init() {
+	registerCollector("users", defaultDisabled, NewUsersCollector)
+}
+
+// NewUsersCollector returns a new Collector exposing CPU, memory and process
+// counts summed up per user.
+func NewUsersCollector(config Config) (Collector, error) {
+	var userLabelNames = []string{"uid", "user"}
+
+	return &usersCollector{
+		config:   config,
+		names:    map[uint32]string{},
+		cpuTimes: newExitedCounters(2),
+		cpu: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: usersSubsystem,
+				Name:      "cpu_seconds",
+				Help:      "Seconds the processes of a user spent in each mode, including processes that exited since the exporter started.",
+			},
+			[]string{"uid", "user", "mode"},
+		),
+		rss: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: usersSubsystem,
+				Name:      "memory_rss_bytes",
+				Help:      "Resident memory of the processes of a user in bytes.",
+			},
+			userLabelNames,
+		),
+		processes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: usersSubsystem,
+				Name:      "processes",
+				Help:      "Number of processes of a user.",
+			},
+			userLabelNames,
+		),
+	}, nil
+}
+
+func (c *usersCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	pids, err := allProcesses(*procPath)
+	if err != nil {
+		return fmt.Errorf("couldn't list processes: %s", err)
+	}
+
+	stats := map[uint32]*userStats{}
+	cpuTimes := map[string]map[int][]float64{}
+	for _, pid := range pids {
+		p, err := readProcess(*procPath, pid)
+		if err != nil {
+			continue
+		}
+		s, ok := stats[p.uid]
+		if !ok {
+			s = &userStats{}
+			stats[p.uid] = s
+		}
+		id := strconv.FormatUint(uint64(p.uid), 10)
+		if cpuTimes[id] == nil {
+			cpuTimes[id] = map[int][]float64{}
+		}
+		cpuTimes[id][pid] = []float64{p.utime, p.stime}
+		s.rss += p.rssBytes
+		s.procs++
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cpu := c.cpuTimes.update(cpuTimes)
+	// Users log in and out, only export those currently running processes.
+	c.cpu.Reset()
+	c.rss.Reset()
+	c.processes.Reset()
+	for uid, s := range stats {
+		if s.rss < *usersMinRss {
+			continue
+		}
+		id, name := strconv.FormatUint(uint64(uid), 10), c.userName(uid)
+		c.cpu.WithLabelValues(id, name, "user").Set(cpu[id][0])
+		c.cpu.WithLabelValues(id, name, "system").Set(cpu[id][1])
+		c.rss.WithLabelValues(id, name).Set(s.rss)
+		c.processes.WithLabelValues(id, name).Set(float64(s.procs))
+	}
+	c.cpu.Collect(ch)
+	c.rss.Collect(ch)
+	c.processes.Collect(ch)
+	return nil
+}
+
+// userName resolves and caches the name of uid, which is empty for users
+// unknown to the system. It is called with mu held.
+func (c *usersCollector) userName(uid uint32) string {
+	if name, ok := c.names[uid]; ok {
+		return name
+	}
+	name := ""
+	if u, err := user.LookupId(strconv.FormatUint(uint64(uid), 10)); err == nil {
+		name = u.Username
+	}
+	c.names[uid] = name
+	return name
+}
@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/context"
+)
+
+func TestUsersProcPath(t *testing.T) {
+	defer func(old string) { *procPath = old }(*procPath)
+	*procPath = "fixtures/proc"
+
+	c, err := NewUsersCollector(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan prometheus.Metric, 100)
+	if err := c.Update(context.Background(), ch); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	// Both fixture processes belong to the owner of the fixtures.
+	got := map[string]float64{}
+	for m := range ch {
+		pb := &dto.Metric{}
+		if err := m.Write(pb); err != nil {
+			t.Fatal(err)
+		}
+		key := ""
+		for _, l := range pb.Label {
+			if l.GetName() == "mode" {
+				key = l.GetValue()
+			}
+		}
+		switch desc := m.Desc().String(); {
+		case pb.Counter != nil:
+			got["cpu_"+key] = pb.Counter.GetValue()
+		case strings.Contains(desc, "user_processes"):
+			got["processes"] = pb.Gauge.GetValue()
+		case strings.Contains(desc, "user_memory_rss_bytes"):
+			got["rss"] = pb.Gauge.GetValue()
+		}
+	}
+	for key, want := range map[string]float64{
+		"cpu_user":   1500 / userHZ,
+		"cpu_system": 1000 / userHZ,
+		"processes":  2,
+		"rss":        3072 * float64(os.Getpagesize()),
+	} {
+		if got[key] != want {
+			t.Errorf("want %s %f, got %f", key, want, got[key])
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// +build !novirtualization
+
+package collector
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
+)
+
+// DMI values identifying hypervisors, matched exactly: substrings like
+// "Virtual" or a vendor alone, e.g. Microsoft, also match physical machines.
+// All fields of an entry have to match.
+var dmiHypervisors = []struct {
+	fields     map[string]string
+	hypervisor string
+}{
+	{map[string]string{"sys_vendor": "QEMU"}, "kvm"},
+	{map[string]string{"product_name": "KVM"}, "kvm"},
+	{map[string]string{"sys_vendor": "Amazon EC2"}, "kvm"},
+	{map[string]string{"product_name": "Google Compute Engine"}, "kvm"},
+	{map[string]string{"sys_vendor": "VMware, Inc."}, "vmware"},
+	{map[string]string{"sys_vendor": "innotek GmbH"}, "virtualbox"},
+	{map[string]string{"product_name": "VirtualBox"}, "virtualbox"},
+	{map[string]string{"sys_vendor": "Xen"}, "xen"},
+	{map[string]string{"bios_vendor": "Xen"}, "xen"},
+	{map[string]string{"sys_vendor": "Microsoft Corporation", "product_name": "Virtual Machine"}, "hyperv"},
+	{map[string]string{"sys_vendor": "Parallels Software International Inc."}, "parallels"},
+	{map[string]string{"product_name": "BHYVE"}, "bhyve"},
+}
+
+type virtualizationCollector struct {
+	config Config
+	metric *prometheus.GaugeVec
+}
+
+func init() {
+	registerCollector("virtualization", defaultDisabled, NewVirtualizationCollector)
+}
+
+// NewVirtualizationCollector returns a new Collector exposing whether the
+// node runs inside a container and/or a virtual machine.
+func NewVirtualizationCollector(config Config) (Collector, error) {
+	return &virtualizationCollector{
+		config: config,
+		metric: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Name:      "virtualization_info",
+				Help:      "Detected container runtime and hypervisor, \"none\" on bare metal.",
+			},
+			[]string{"container", "hypervisor"},
+		),
+	}, nil
+}
+
+func (c *virtualizationCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	container, hypervisor := detectContainer(), detectHypervisor()
+	log.Debugf("Set node_virtualization_info{container=%q,hypervisor=%q}: 1", container, hypervisor)
+	c.metric.Reset()
+	c.metric.WithLabelValues(container, hypervisor).Set(1)
+	c.metric.Collect(ch)
+	return nil
+}
+
+func detectContainer() string {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return "docker"
+	}
+	// Set by lxc, systemd-nspawn, podman and others for the init process.
+	if data, err := ioutil.ReadFile(procFilePath("1/environ")); err == nil {
+		if container := environContainer(string(data)); container != "" {
+			return container
+		}
+	}
+	if data, err := ioutil.ReadFile(procFilePath("1/cgroup")); err == nil {
+		return cgroupContainer(string(data))
+	}
+	return "none"
+}
+
+// environContainer returns the value of the container variable in the NUL
+// separated environment of a process.
+func environContainer(environ string) string {
+	for _, v := range strings.Split(environ, "\x00") {
+		if strings.HasPrefix(v, "container=") {
+			return strings.TrimPrefix(v, "container=")
+		}
+	}
+	return ""
+}
+
+// cgroupContainer guesses the container runtime from /proc/1/cgroup, which
+// names the container's cgroup on hosts not using cgroup namespaces.
+func cgroupContainer(cgroup string) string {
+	scanner := bufio.NewScanner(strings.NewReader(cgroup))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, "/docker"):
+			return "docker"
+		case strings.Contains(line, "/kubepods"):
+			return "kubernetes"
+		case strings.Contains(line, "/lxc"):
+			return "lxc"
+		}
+	}
+	return "none"
+}
+
+func detectHypervisor() string {
+	if data, err := ioutil.ReadFile(sysFilePath("hypervisor/type")); err == nil {
+		if t := strings.TrimSpace(string(data)); t != "" {
+			return t
+		}
+	}
+	dmi := map[string]string{}
+	for _, name := range []string{"sys_vendor", "product_name", "bios_vendor"} {
+		if data, err := ioutil.ReadFile(sysFilePath(path.Join("class/dmi/id", name))); err == nil {
+			dmi[name] = strings.TrimSpace(string(data))
+		}
+	}
+	if hypervisor := dmiHypervisor(dmi); hypervisor != "" {
+		return hypervisor
+	}
+	// Without DMI (e.g. on ARM) the CPU flags still tell there is some
+	// hypervisor.
+	if data, err := ioutil.ReadFile(procFilePath("cpuinfo")); err == nil {
+		if cpuinfoHypervisor(string(data)) {
+			return "unknown"
+		}
+	}
+	return "none"
+}
+
+// dmiHypervisor returns the hypervisor of the first entry of dmiHypervisors
+// matching the DMI values by field name.
+func dmiHypervisor(dmi map[string]string) string {
+	for _, h := range dmiHypervisors {
+		match := true
+		for field, value := range h.fields {
+			if v, ok := dmi[field]; !ok || v != value {
+				match = false
+				break
+			}
+		}
+		if match {
+			return h.hypervisor
+		}
+	}
+	return ""
+}
+
+// cpuinfoHypervisor reports whether the hypervisor CPU flag is set.
+func cpuinfoHypervisor(cpuinfo string) bool {
+	scanner := bufio.NewScanner(strings.NewReader(cpuinfo))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) != "flags" {
+			continue
+		}
+		for _, flag := range strings.Fields(parts[1]) {
+			if flag == "hypervisor" {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
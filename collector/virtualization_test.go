@@ -0,0 +1,48 @@
+package collector
+
+import "testing"
+
+func TestEnvironContainer(t *testing.T) {
+	if want, got := "systemd-nspawn", environContainer("TERM=vt220\x00container=systemd-nspawn\x00container_uuid=e2a1\x00"); want != got {
+		t.Errorf("want container %s, got %s", want, got)
+	}
+	if want, got := "", environContainer("HOME=/\x00TERM=linux\x00"); want != got {
+		t.Errorf("want no container, got %s", got)
+	}
+}
+
+func TestCgroupContainer(t *testing.T) {
+	cgroup := "11:memory:/docker/3f0d3d2fa6\n10:cpu,cpuacct:/docker/3f0d3d2fa6\n"
+	if want, got := "docker", cgroupContainer(cgroup); want != got {
+		t.Errorf("want container %s, got %s", want, got)
+	}
+	if want, got := "none", cgroupContainer("0::/init.scope\n"); want != got {
+		t.Errorf("want container %s, got %s", want, got)
+	}
+}
+
+func TestHypervisor(t *testing.T) {
+	for _, test := range []struct {
+		dmi        map[string]string
+		hypervisor string
+	}{
+		{map[string]string{"sys_vendor": "QEMU", "product_name": "Standard PC (i440FX + PIIX, 1996)", "bios_vendor": "SeaBIOS"}, "kvm"},
+		{map[string]string{"sys_vendor": "VMware, Inc.", "product_name": "VMware Virtual Platform"}, "vmware"},
+		{map[string]string{"sys_vendor": "Microsoft Corporation", "product_name": "Virtual Machine"}, "hyperv"},
+		{map[string]string{"sys_vendor": "Dell Inc.", "product_name": "PowerEdge R630"}, ""},
+		// Physical machines whose DMI values contain those of hypervisors.
+		{map[string]string{"sys_vendor": "Microsoft Corporation", "product_name": "Surface Pro 7"}, ""},
+		{map[string]string{"sys_vendor": "Supermicro", "product_name": "VirtualBoxNAS", "bios_vendor": "Xenon Technologies"}, ""},
+	} {
+		if want, got := test.hypervisor, dmiHypervisor(test.dmi); want != got {
+			t.Errorf("want hypervisor %q for %v, got %q", want, test.dmi, got)
+		}
+	}
+
+	if !cpuinfoHypervisor("processor\t: 0\nflags\t\t: fpu vme de pse tsc msr hypervisor lahf_lm\n") {
+		t.Error("want hypervisor flag to be detected")
+	}
+	if cpuinfoHypervisor("processor\t: 0\nflags\t\t: fpu vme de pse tsc msr lahf_lm\n") {
+		t.Error("want no hypervisor flag")
+	}
+}
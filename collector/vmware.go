@@ -0,0 +1,112 @@
+// +build !novmware
+
+package collector
+
+import (
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultVMwareToolbox = "vmware-toolbox-cmd"
+	vmwareSubsystem      = "vmware"
+
+	// Limits are reported as the largest 32 bit value when unset.
+	vmwareUnlimited = 4294967295
+)
+
+// Statistics queried with "vmware-toolbox-cmd stat <name>".
+var vmwareStats = []struct {
+	stat, name, help string
+}{
+	{"balloon", "memory_ballooned_bytes", "Guest memory reclaimed by the VMware balloon driver in bytes."},
+	{"swap", "memory_swapped_bytes", "Guest memory swapped out by the hypervisor in bytes."},
+	{"memlimit", "memory_limit_bytes", "Memory limit of the virtual machine in bytes, +Inf if unlimited."},
+	{"memres", "memory_reservation_bytes", "Memory reservation of the virtual machine in bytes."},
+	{"cpulimit", "cpu_limit_hertz", "CPU limit of the virtual machine in hertz, +Inf if unlimited."},
+	{"cpures", "cpu_reservation_hertz", "CPU reservation of the virtual machine in hertz."},
+	{"speed", "cpu_speed_hertz", "Host CPU speed in hertz."},
+}
+
+type vmwareCollector struct {
+	config  Config
+	cli     string
+	metrics map[string]prometheus.Gauge
+}
+
+func init() {
+	registerCollector("vmware", defaultDisabled, NewVMwareCollector)
+}
+
+// NewVMwareCollector returns a new Collector exposing ballooned and swapped
+// memory and resource limits of VMware guests through vmware-toolbox-cmd.
+// CPU steal time is exported by the stat collector.
+func NewVMwareCollector(config Config) (Collector, error) {
+	cli := defaultVMwareToolbox
+	if config.Config["vmware_toolbox_command"] != "" {
+		cli = config.Config["vmware_toolbox_command"]
+	}
+	if _, err := exec.LookPath(cli); err != nil {
+		return nil, NotApplicable("no %s: %s", cli, err)
+	}
+
+	metrics := map[string]prometheus.Gauge{}
+	for _, s := range vmwareStats {
+		metrics[s.stat] = prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: vmwareSubsystem,
+			Name:      s.name,
+			Help:      s.help,
+		})
+	}
+	return &vmwareCollector{
+		config:  config,
+		cli:     cli,
+		metrics: metrics,
+	}, nil
+}
+
+func (c *vmwareCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	for _, s := range vmwareStats {
+		out, err := commandOutput(ctx, c.cli, "stat", s.stat)
+		if err != nil {
+			return fmt.Errorf("couldn't get vmware stat %s: %s", s.stat, err)
+		}
+		value, err := parseVMwareStat(string(out))
+		if err != nil {
+			return fmt.Errorf("couldn't parse vmware stat %s: %s", s.stat, err)
+		}
+		c.metrics[s.stat].Set(value)
+		c.metrics[s.stat].Collect(ch)
+	}
+	return nil
+}
+
+// parseVMwareStat converts output like "512 MB" or "2600 MHz" to bytes and
+// hertz respectively.
+func parseVMwareStat(out string) (float64, error) {
+	parts := strings.Fields(out)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("unexpected output %q", out)
+	}
+	value, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	if value == vmwareUnlimited {
+		return math.Inf(1), nil
+	}
+	switch parts[1] {
+	case "MB":
+		return value * 1024 * 1024, nil
+	case "MHz":
+		return value * 1e6, nil
+	}
+	return 0, fmt.Errorf("unknown unit %s", parts[1])
+}
@@ -0,0 +1,36 @@
+package collector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVMwareStat(t *testing.T) {
+	value, err := parseVMwareStat("512 MB\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 536870912.0; want != value {
+		t.Errorf("want %f bytes, got %f", want, value)
+	}
+
+	value, err = parseVMwareStat("2600 MHz\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 2.6e9; want != value {
+		t.Errorf("want %f hertz, got %f", want, value)
+	}
+
+	value, err = parseVMwareStat("4294967295 MB\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(value, 1) {
+		t.Errorf("want unlimited, got %f", value)
+	}
+
+	if _, err := parseVMwareStat("Unable to get balloon size\n"); err == nil {
+		t.Error("want error for unexpected output")
+	}
+}
@@ -0,0 +1,197 @@
+// +build xen
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultXentop = "xentop"
+	xenSubsystem  = "xen_domain"
+)
+
+// Columns of xentop's batch output and how they are exported. Values in
+// kilobytes are converted to bytes.
+var xenColumns = []struct {
+	column, name, help string
+	scale              float64
+	counter            bool
+}{
+	{"CPU(sec)", "cpu_seconds", "CPU time consumed by the domain in seconds.", 1, true},
+	{"MEM(k)", "memory_bytes", "Memory assigned to the domain in bytes.", 1024, false},
+	{"MAXMEM(k)", "memory_max_bytes", "Maximum memory of the domain in bytes, +Inf if unlimited.", 1024, false},
+	{"VCPUS", "vcpus", "Number of virtual CPUs of the domain.", 1, false},
+	{"NETTX(k)", "network_transmit_bytes", "Bytes transmitted over all virtual interfaces of the domain.", 1024, true},
+	{"NETRX(k)", "network_receive_bytes", "Bytes received over all virtual interfaces of the domain.", 1024, true},
+	{"VBD_OO", "vbd_out_of_requests", "Number of times the domain's virtual block devices ran out of requests.", 1, true},
+	{"VBD_RD", "vbd_reads", "Read requests of the domain's virtual block devices.", 1, true},
+	{"VBD_WR", "vbd_writes", "Write requests of the domain's virtual block devices.", 1, true},
+	{"VBD_RSECT", "vbd_read_sectors", "Sectors read by the domain's virtual block devices.", 1, true},
+	{"VBD_WSECT", "vbd_written_sectors", "Sectors written by the domain's virtual block devices.", 1, true},
+}
+
+type xenCollector struct {
+	config Config
+	xentop string
+
+	// mu keeps overlapping updates from resetting each other's domains.
+	mu      sync.Mutex
+	metrics map[string]*prometheus.GaugeVec
+	counts  map[string]*prometheus.CounterVec
+}
+
+func init() {
+	registerCollector("xen", defaultDisabled, NewXenCollector)
+}
+
+// NewXenCollector returns a new Collector exposing per-domain statistics of
+// a Xen dom0 through xentop. Only built with the xen build tag.
+func NewXenCollector(config Config) (Collector, error) {
+	xentop := defaultXentop
+	if config.Config["xentop_command"] != "" {
+		xentop = config.Config["xentop_command"]
+	}
+	if _, err := exec.LookPath(xentop); err != nil {
+		return nil, NotApplicable("no %s: %s", xentop, err)
+	}
+
+	c := &xenCollector{
+		config:  config,
+		xentop:  xentop,
+		metrics: map[string]*prometheus.GaugeVec{},
+		counts:  map[string]*prometheus.CounterVec{},
+	}
+	for _, col := range xenColumns {
+		if col.counter {
+			c.counts[col.column] = prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Namespace: Namespace,
+					Subsystem: xenSubsystem,
+					Name:      col.name,
+					Help:      col.help,
+				},
+				[]string{"domain"},
+			)
+		} else {
+			c.metrics[col.column] = prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Namespace: Namespace,
+					Subsystem: xenSubsystem,
+					Name:      col.name,
+					Help:      col.help,
+				},
+				[]string{"domain"},
+			)
+		}
+	}
+	return c, nil
+}
+
+func (c *xenCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) (err error) {
+	cmd := exec.Command(c.xentop, "-b", "-i", "1")
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	wait, err := startCommand(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	domains, err := parseXentop(pipe)
+	if err != nil {
+		// Wait doesn't return before the command exits, which it may not
+		// do while its output is left unread.
+		cmd.Process.Kill()
+		if werr := wait(); ctx.Err() != nil {
+			return werr
+		}
+		return err
+	}
+	if err := wait(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Domains get created, migrated and destroyed, only export current ones.
+	for _, m := range c.metrics {
+		m.Reset()
+	}
+	for _, m := range c.counts {
+		m.Reset()
+	}
+	for domain, stats := range domains {
+		for _, col := range xenColumns {
+			value, ok := stats[col.column]
+			if !ok {
+				continue
+			}
+			if col.counter {
+				c.counts[col.column].WithLabelValues(domain).Set(value * col.scale)
+			} else {
+				c.metrics[col.column].WithLabelValues(domain).Set(value * col.scale)
+			}
+		}
+	}
+	for _, m := range c.metrics {
+		m.Collect(ch)
+	}
+	for _, m := range c.counts {
+		m.Collect(ch)
+	}
+	return nil
+}
+
+// parseXentop parses the batch output of xentop into per-domain values by
+// column header. Non-numeric values like "n/a" are skipped.
+func parseXentop(r io.Reader) (map[string]map[string]float64, error) {
+	var (
+		domains = map[string]map[string]float64{}
+		scanner = bufio.NewScanner(r)
+		header  []string
+	)
+
+	for scanner.Scan() {
+		// "no limit" is the only value containing a space.
+		parts := strings.Fields(strings.Replace(scanner.Text(), "no limit", "nolimit", -1))
+		if len(parts) == 0 {
+			continue
+		}
+		if parts[0] == "NAME" {
+			header = parts
+			continue
+		}
+		if header == nil {
+			continue
+		}
+		if len(parts) != len(header) {
+			return nil, fmt.Errorf("invalid xentop line: %s", scanner.Text())
+		}
+		stats := map[string]float64{}
+		for i, v := range parts[1:] {
+			if v == "nolimit" {
+				stats[header[i+1]] = math.Inf(1)
+				continue
+			}
+			if value, err := strconv.ParseFloat(v, 64); err == nil {
+				stats[header[i+1]] = value
+			}
+		}
+		domains[parts[0]] = stats
+	}
+	if header == nil {
+		return nil, fmt.Errorf("no header found in xentop output")
+	}
+	return domains, scanner.Err()
+}
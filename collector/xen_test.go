@@ -0,0 +1,68 @@
+// +build xen
+
+package collector
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+func TestXentop(t *testing.T) {
+	file, err := os.Open("fixtures/xentop.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	domains, err := parseXentop(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := 2, len(domains); want != got {
+		t.Fatalf("want %d domains, got %d", want, got)
+	}
+	if !math.IsInf(domains["Domain-0"]["MAXMEM(k)"], 1) {
+		t.Errorf("want unlimited Domain-0 memory, got %f", domains["Domain-0"]["MAXMEM(k)"])
+	}
+	if want, got := 52223.0, domains["web01"]["CPU(sec)"]; want != got {
+		t.Errorf("want web01 cpu %f, got %f", want, got)
+	}
+	if want, got := 61824246.0, domains["web01"]["VBD_WSECT"]; want != got {
+		t.Errorf("want web01 written sectors %f, got %f", want, got)
+	}
+}
+
+func TestXenCancelled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// A hung toolstack.
+	xentop := filepath.Join(dir, "xentop")
+	if err := ioutil.WriteFile(xentop, []byte("#!/bin/sh\nexec sleep 10\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewXenCollector(Config{Config: map[string]string{"xentop_command": xentop}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	if err := c.Update(ctx, make(chan prometheus.Metric, 100)); err != context.DeadlineExceeded {
+		t.Errorf("want %v, got %v", context.DeadlineExceeded, err)
+	}
+	if d := time.Since(begin); d > 5*time.Second {
+		t.Errorf("want xentop killed at the deadline, took %s", d)
+	}
+}
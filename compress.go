@@ -0,0 +1,98 @@
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var disableCompression = flag.Bool("web.disable-compression", false, "Never gzip the metrics response, even if the client accepts it.")
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+// WriteHeader drops the Content-Length set by the wrapped handler, which is
+// that of the uncompressed body.
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.gz.Write(b)
+}
+
+// Flush sends the data compressed so far on to the client.
+func (w *gzipResponseWriter) Flush() {
+	w.gz.Flush()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify passes on the notifications of the wrapped writer, which
+// scrapes cancel their collectors on.
+func (w *gzipResponseWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
+
+// gzipHandler compresses responses for clients sending Accept-Encoding: gzip.
+// The header is removed before calling the wrapped handler, so the output is
+// compressed at most once and never if compression is disabled.
+type gzipHandler struct {
+	handler  http.Handler
+	disabled bool
+}
+
+func (h gzipHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	accepted := acceptsGzip(r.Header.Get("Accept-Encoding"))
+	r.Header.Del("Accept-Encoding")
+	if h.disabled || !accepted {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Add("Vary", "Accept-Encoding")
+	gz := gzip.NewWriter(w)
+	defer gz.Close()
+	gw := &gzipResponseWriter{ResponseWriter: w, gz: gz}
+	h.handler.ServeHTTP(gw, r)
+	if !gw.wroteHeader {
+		w.Header().Del("Content-Length")
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip, i.e.
+// lists it without a quality value of zero.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		if strings.TrimSpace(params[0]) != "gzip" {
+			continue
+		}
+		for _, param := range params[1:] {
+			param = strings.Replace(param, " ", "", -1)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				return err == nil && q > 0
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestGzipHandler(t *testing.T) {
+	body := "node_load1 0.21\n"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "" {
+			t.Error("Accept-Encoding passed to wrapped handler")
+		}
+		if _, ok := w.(http.CloseNotifier); !ok {
+			t.Error("CloseNotifier not passed to wrapped handler")
+		}
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("Flusher not passed to wrapped handler")
+		}
+		// Like the client library, which buffers the uncompressed body.
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write([]byte(body))
+	})
+	server := httptest.NewServer(gzipHandler{handler: handler})
+	defer server.Close()
+
+	for _, c := range []struct {
+		accept   string
+		disabled bool
+		gzipped  bool
+	}{
+		{"gzip, deflate", false, true},
+		{"deflate, gzip;q=0.5", false, true},
+		{"gzip;q=0", false, false},
+		{"gzip; q=0.0, identity", false, false},
+		{"", false, false},
+		{"gzip", true, false},
+	} {
+		r, _ := http.NewRequest("GET", "/metrics", nil)
+		r.Header.Set("Accept-Encoding", c.accept)
+		w := closeNotifyRecorder{httptest.NewRecorder(), make(chan bool)}
+		gzipHandler{handler: handler, disabled: c.disabled}.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Content-Encoding") == "gzip"; got != c.gzipped {
+			t.Errorf("%q (disabled %t): want gzipped %t, got %t", c.accept, c.disabled, c.gzipped, got)
+			continue
+		}
+		if cl := w.Header().Get("Content-Length"); c.gzipped && cl != "" {
+			t.Errorf("%q: want no Content-Length for gzipped body, got %s", c.accept, cl)
+		}
+		got := w.Body.String()
+		if c.gzipped {
+			gz, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadAll(gz)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = string(b)
+		}
+		if got != body {
+			t.Errorf("%q: want body %q, got %q", c.accept, body, got)
+		}
+	}
+
+	// A client reading the body up to the announced length.
+	r, _ := http.NewRequest("GET", server.URL+"/metrics", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	resp, err := (&http.Transport{DisableCompression: true}).RoundTrip(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("couldn't read gzipped body: %s", err)
+	}
+	if string(b) != body {
+		t.Errorf("want body %q, got %q", body, b)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/node_exporter/collector"
+	"github.com/prometheus/node_exporter/log"
+	"gopkg.in/yaml.v2"
+)
+
+// fileConfig is the structure of the YAML file given by -config.file. As YAML
+// is a superset of JSON, the older JSON files with just the config and
+// attributes sections are still accepted.
+type fileConfig struct {
+	Web struct {
+		ListenAddresses    []string `yaml:"listen_addresses"`
+		ListenSocket       string   `yaml:"listen_socket"`
+		TelemetryPath      string   `yaml:"telemetry_path"`
+		TLSCertFile        string   `yaml:"tls_cert_file"`
+		TLSKeyFile         string   `yaml:"tls_key_file"`
+		TLSClientCAFile    string   `yaml:"tls_client_ca_file"`
+		TLSAllowedCNs      []string `yaml:"tls_allowed_cns"`
+		AllowedNetworks    []string `yaml:"allowed_networks"`
+		ScrapeMinInterval  string   `yaml:"scrape_min_interval"`
+		AuthFile           string   `yaml:"auth_file"`
+		DisableCompression *bool    `yaml:"disable_compression"`
+	} `yaml:"web"`
+	Collectors struct {
+		Enabled   []string `yaml:"enabled"`
+		Timeout   string   `yaml:"timeout"`
+		CacheTTL  string   `yaml:"cache_ttl"`
+		Namespace string   `yaml:"namespace"`
+	} `yaml:"collectors"`
+	// Collector holds the options of each collector. Options named like a
+	// flag of the collector, e.g. ignored-mount-points of filesystem, set
+	// that flag, and enabled sets -collector.<name>. All options are passed
+	// to the collectors as <name>_<option>.
+	Collector map[string]map[string]interface{} `yaml:"collector"`
+
+	Config     map[string]string `yaml:"config"`
+	Attributes map[string]string `yaml:"attributes"`
+}
+
+func parseConfig(data []byte) (*fileConfig, error) {
+	config := &fileConfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// flags returns the values of the flags set by the config, in the order to
+// set them.
+func (c *fileConfig) flags() map[string][]string {
+	flags := map[string][]string{}
+	set := func(name string, values ...string) {
+		if len(values) > 0 && (len(values) > 1 || values[0] != "") {
+			flags[name] = values
+		}
+	}
+	set("web.listen-address", c.Web.ListenAddresses...)
+	set("web.listen-socket", c.Web.ListenSocket)
+	set("web.telemetry-path", c.Web.TelemetryPath)
+	set("web.tls-cert-file", c.Web.TLSCertFile)
+	set("web.tls-key-file", c.Web.TLSKeyFile)
+	set("web.tls-client-ca-file", c.Web.TLSClientCAFile)
+	set("web.tls-allowed-cns", strings.Join(c.Web.TLSAllowedCNs, ","))
+	set("web.allowed-networks", strings.Join(c.Web.AllowedNetworks, ","))
+	set("web.scrape-min-interval", c.Web.ScrapeMinInterval)
+	set("auth.file", c.Web.AuthFile)
+	if c.Web.DisableCompression != nil {
+		set("web.disable-compression", fmt.Sprint(*c.Web.DisableCompression))
+	}
+	set("collectors.enabled", strings.Join(c.Collectors.Enabled, ","))
+	set("collectors.timeout", c.Collectors.Timeout)
+	set("collector.cache-ttl", c.Collectors.CacheTTL)
+	set("collector.namespace", c.Collectors.Namespace)
+
+	for name, options := range c.Collector {
+		for option, value := range options {
+			if f := "collector." + name; option == "enabled" && flag.Lookup(f) != nil {
+				set(f, optionString(value))
+			} else if f := "collector." + name + "." + option; flag.Lookup(f) != nil {
+				set(f, optionString(value))
+			}
+		}
+	}
+	return flags
+}
+
+// collectorConfig returns the config passed to the collectors.
+func (c *fileConfig) collectorConfig() collector.Config {
+	config := collector.Config{
+		Config:     map[string]string{},
+		Collectors: map[string]collector.Options{},
+		Attributes: c.Attributes,
+	}
+	for k, v := range c.Config {
+		config.Config[k] = v
+	}
+	for name, options := range c.Collector {
+		config.Collectors[name] = collector.Options{}
+		for option, value := range options {
+			config.Collectors[name][option] = optionString(value)
+			config.Config[name+"_"+option] = optionString(value)
+		}
+	}
+	return config
+}
+
+// optionString turns an option value into the string a flag would hold,
+// joining lists with commas.
+func optionString(value interface{}) string {
+	if list, ok := value.([]interface{}); ok {
+		s := make([]string, 0, len(list))
+		for _, v := range list {
+			s = append(s, fmt.Sprint(v))
+		}
+		return strings.Join(s, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+var (
+	// commandLineFlags holds the names of the flags given on the command
+	// line, which take precedence over the config.
+	commandLineFlags map[string]bool
+	// configFlags holds the names of the flags set by the last applied config.
+	configFlags = map[string]bool{}
+
+	// flagsMu guards the flags set by the config, and the maps above:
+	// applyConfig holds it for writing, running collectors for reading.
+	flagsMu sync.RWMutex
+)
+
+// restartFlag reports whether the flag only takes effect on restart, as it
+// configures the web server or the metric names.
+func restartFlag(name string) bool {
+	return strings.HasPrefix(name, "web.") || strings.HasPrefix(name, "auth.") || name == "collector.namespace"
+}
+
+// applyConfig loads the config file and sets the flags it configures, except
+// those given on the command line. On reload, flags no longer set by the
+// config are reset to their defaults and web server flags are left alone.
+func applyConfig(file string, reload bool) (collector.Config, error) {
+	if file == "" {
+		return collector.Config{}, nil
+	}
+	log.Infof("Reading config %s", file)
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return collector.Config{}, err
+	}
+	config, err := parseConfig(data)
+	if err != nil {
+		return collector.Config{}, err
+	}
+
+	flagsMu.Lock()
+	defer flagsMu.Unlock()
+	if commandLineFlags == nil {
+		commandLineFlags = map[string]bool{}
+		flag.Visit(func(f *flag.Flag) { commandLineFlags[f.Name] = true })
+	}
+	flags := config.flags()
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if reload {
+		for name := range configFlags {
+			if _, ok := flags[name]; !ok && !restartFlag(name) {
+				f := flag.Lookup(name)
+				if err := f.Value.Set(f.DefValue); err != nil {
+					return collector.Config{}, fmt.Errorf("couldn't reset %s: %s", name, err)
+				}
+				delete(configFlags, name)
+			}
+		}
+	}
+	for _, name := range names {
+		if commandLineFlags[name] {
+			continue
+		}
+		if reload && restartFlag(name) {
+			if values := flags[name]; flag.Lookup(name).Value.String() != strings.Join(values, ",") {
+				log.Warnf("Changing %s requires a restart", name)
+			}
+			continue
+		}
+		for _, value := range flags[name] {
+			if err := flag.Set(name, value); err != nil {
+				return collector.Config{}, fmt.Errorf("invalid value %q for %s: %s", value, name, err)
+			}
+		}
+		configFlags[name] = true
+	}
+	return config.collectorConfig(), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	data, err := ioutil.ReadFile("node_exporter.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := parseConfig(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flags := config.flags()
+	for name, want := range map[string][]string{
+		"web.listen-address":                        {"127.0.0.1:9100"},
+		"collectors.timeout":                        {"10s"},
+		"collector.filesystem.ignored-mount-points": {"^/(sys|proc|dev)($|/)"},
+		"collector.textfile.directory":              {"/var/lib/node_exporter/textfile"},
+		"collector.interrupts":                      {"true"},
+	} {
+		if got := flags[name]; !reflect.DeepEqual(want, got) {
+			t.Errorf("flag %s: want %v, got %v", name, want, got)
+		}
+	}
+	if _, ok := flags["web.listen-socket"]; ok {
+		t.Error("unset option web.listen-socket set as flag")
+	}
+
+	c := config.collectorConfig()
+	for key, want := range map[string]string{
+		"megacli_command":    "megacli.sh",
+		"filesystem_timeout": "30s",
+	} {
+		if got := c.Config[key]; want != got {
+			t.Errorf("config %s: want %q, got %q", key, want, got)
+		}
+	}
+	if want, got := "/var/lib/node_exporter/textfile", c.Options("textfile").String("directory"); want != got {
+		t.Errorf("want textfile directory %q, got %q", want, got)
+	}
+	if want, got := "a", c.Attributes["zone"]; want != got {
+		t.Errorf("want attribute zone %q, got %q", want, got)
+	}
+}
+
+func TestParseJSONConfig(t *testing.T) {
+	data, err := ioutil.ReadFile("node_exporter.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := parseConfig(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := config.collectorConfig()
+	if want, got := "megacli.sh", c.Config["megacli_command"]; want != got {
+		t.Errorf("want megacli_command %q, got %q", want, got)
+	}
+	if want, got := 3, len(c.Attributes); want != got {
+		t.Errorf("want %d attributes, got %d", want, got)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+)
+
+var (
+	dryRun       = flag.Bool("dry-run", false, "If true, collect the metrics once, write them in the text format and exit, with a non-zero status if a collector failed.")
+	dryRunOutput = flag.String("dry-run.output", "", "File to write the metrics of -dry-run to, instead of standard output.")
+)
+
+// runDryRun scrapes handler once, writing the metrics to out and the errors
+// of failing collectors to errOut. It returns an error if the scrape or any
+// collector failed.
+func runDryRun(n *NodeCollector, handler http.Handler, out, errOut io.Writer) error {
+	r, err := http.NewRequest("GET", *metricsPath, nil)
+	if err != nil {
+		return err
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		return fmt.Errorf("scrape failed with status %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := rec.Body.WriteTo(out); err != nil {
+		return err
+	}
+
+	failures := n.lastFailures()
+	names := make([]string, 0, len(failures))
+	for name := range failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(errOut, "Collector %s failed: %s\n", name, failures[name])
+	}
+	if len(names) > 0 {
+		return fmt.Errorf("%d of %d collectors failed", len(names), len(n.names()))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
+)
+
+func TestRunDryRun(t *testing.T) {
+	scrapeDurations = newScrapeDurations()
+	n := &NodeCollector{
+		node: collector.NewNodeCollectorWith(map[string]collector.Collector{
+			"good": errorCollector{},
+			"bad":  errorCollector{err: errors.New("no such file")},
+		}, nil),
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ch := make(chan prometheus.Metric)
+		go func() {
+			n.Collect(ch)
+			close(ch)
+		}()
+		for range ch {
+		}
+		io.WriteString(w, "node_test 1\n")
+	})
+
+	var out, errOut bytes.Buffer
+	err := runDryRun(n, handler, &out, &errOut)
+	if err == nil {
+		t.Fatal("want error for failed collector, got none")
+	}
+	if want, got := "node_test 1\n", out.String(); got != want {
+		t.Errorf("want output %q, got %q", want, got)
+	}
+	if want, got := "Collector bad failed: no such file\n", errOut.String(); got != want {
+		t.Errorf("want errors %q, got %q", want, got)
+	}
+
+	n.setCollectors(map[string]collector.Collector{"good": errorCollector{}}, nil)
+	out.Reset()
+	errOut.Reset()
+	if err := runDryRun(n, handler, &out, &errOut); err != nil {
+		t.Errorf("want no error, got %s", err)
+	}
+	if errOut.Len() != 0 {
+		t.Errorf("want no collector errors, got %q", errOut.String())
+	}
+}
+
+func TestRunDryRunStatus(t *testing.T) {
+	n := &NodeCollector{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad collect[]", http.StatusBadRequest)
+	})
+	var out, errOut bytes.Buffer
+	err := runDryRun(n, handler, &out, &errOut)
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Errorf("want error for status 400, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("want no output, got %q", out.String())
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/prometheus/node_exporter/collector"
+)
+
+// collectorSwitch is the value of the -collector.<name> and
+// -no-collector.<name> flags. Unless set, -collectors.enabled decides.
+type collectorSwitch struct {
+	set, enabled bool
+}
+
+func (s *collectorSwitch) String() string {
+	if s == nil || !s.set {
+		return ""
+	}
+	return strconv.FormatBool(s.enabled)
+}
+
+// Set parses a boolean. The empty default value unsets the switch, so that
+// config reloads can reset it.
+func (s *collectorSwitch) Set(value string) error {
+	if value == "" {
+		s.set = false
+		return nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	s.set, s.enabled = true, enabled
+	return nil
+}
+
+func (s *collectorSwitch) IsBoolFlag() bool { return true }
+
+// negatedSwitch sets its switch to the opposite of its value.
+type negatedSwitch struct {
+	*collectorSwitch
+}
+
+func (s negatedSwitch) String() string {
+	if s.collectorSwitch == nil || !s.set {
+		return ""
+	}
+	return strconv.FormatBool(!s.enabled)
+}
+
+func (s negatedSwitch) Set(value string) error {
+	if value == "" {
+		return s.collectorSwitch.Set(value)
+	}
+	disabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	s.set, s.enabled = true, !disabled
+	return nil
+}
+
+// collectorSwitches holds the switch of each collector compiled in.
+var collectorSwitches = map[string]*collectorSwitch{}
+
+func init() {
+	defaults := map[string]bool{}
+	for _, name := range collector.DefaultCollectors {
+		defaults[name] = true
+	}
+	for name := range collector.Factories {
+		s := &collectorSwitch{}
+		collectorSwitches[name] = s
+		state := "disabled"
+		if defaults[name] {
+			state = "enabled"
+		}
+		flag.Var(s, "collector."+name, fmt.Sprintf("Enable the %s collector, %s by default.", name, state))
+		flag.Var(negatedSwitch{s}, "no-collector."+name, fmt.Sprintf("Disable the %s collector.", name))
+	}
+}
+
+// enabledCollectorNames returns the sorted names of the collectors given by
+// -collectors.enabled, plus those enabled and minus those disabled by their
+// own flags.
+func enabledCollectorNames() []string {
+	enabled := map[string]bool{}
+	for _, name := range strings.Split(*enabledCollectors, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			enabled[name] = true
+		}
+	}
+	for name, s := range collectorSwitches {
+		if s.set {
+			enabled[name] = s.enabled
+		}
+	}
+	names := make([]string, 0, len(enabled))
+	for name, ok := range enabled {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeCollectorList writes a line per available collector, with whether it
+// is enabled by default and its description.
+func writeCollectorList(w io.Writer) {
+	defaults := map[string]bool{}
+	for _, name := range collector.DefaultCollectors {
+		defaults[name] = true
+	}
+	names := make([]string, 0, len(collector.Factories))
+	for name := range collector.Factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLLECTOR\tDEFAULT\tDESCRIPTION")
+	for _, name := range names {
+		state := "disabled"
+		if defaults[name] {
+			state = "enabled"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, state, collector.Descriptions[name])
+	}
+	tw.Flush()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/node_exporter/collector"
+)
+
+func TestEnabledCollectorNames(t *testing.T) {
+	defer func(old string) { *enabledCollectors = old }(*enabledCollectors)
+	defer func() {
+		for _, s := range collectorSwitches {
+			s.Set("")
+		}
+	}()
+
+	*enabledCollectors = "meminfo,stat"
+	for _, test := range []struct {
+		flag, value string
+		want        []string
+	}{
+		{"", "", []string{"meminfo", "stat"}},
+		{"collector.loadavg", "true", []string{"loadavg", "meminfo", "stat"}},
+		{"no-collector.stat", "true", []string{"loadavg", "meminfo"}},
+		{"collector.meminfo", "false", []string{"loadavg"}},
+		{"no-collector.loadavg", "false", []string{"loadavg"}},
+		{"collector.loadavg", "", []string{}},
+	} {
+		if test.flag != "" {
+			if err := flag.Set(test.flag, test.value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := enabledCollectorNames(); !reflect.DeepEqual(test.want, got) {
+			t.Errorf("-%s=%s: want %v, got %v", test.flag, test.value, test.want, got)
+		}
+	}
+}
+
+func TestWriteCollectorList(t *testing.T) {
+	var buf bytes.Buffer
+	writeCollectorList(&buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if want, got := len(collector.Factories)+1, len(lines); want != got {
+		t.Fatalf("want %d lines, got %d", want, got)
+	}
+	states := map[string]string{}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			t.Errorf("want name, default state and description, got %q", line)
+			continue
+		}
+		states[fields[0]] = fields[1]
+	}
+	if states["stat"] != "enabled" || states["interrupts"] != "disabled" {
+		t.Errorf("want stat enabled and interrupts disabled by default, got %v", states)
+	}
+}
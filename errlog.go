@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
+	"github.com/prometheus/node_exporter/log"
+)
+
+var errorLogInterval = flag.Duration("log.collector-error-interval", 10*time.Minute, "Log an error a collector keeps failing with at most once per this duration, counting the others in node_exporter_suppressed_collector_errors_total. 0 logs every error.")
+
+// collectorErrors logs the errors of the collectors run by NodeCollector.
+var collectorErrors = newErrorLog()
+
+// errorLog logs the errors of collectors, suppressing those repeating the
+// last logged error of the collector within an interval.
+type errorLog struct {
+	mu         sync.Mutex
+	last       map[string]*loggedError
+	suppressed map[string]float64
+}
+
+type loggedError struct {
+	msg     string
+	logged  time.Time
+	repeats int // suppressed since logged
+}
+
+func newErrorLog() *errorLog {
+	return &errorLog{last: map[string]*loggedError{}, suppressed: map[string]float64{}}
+}
+
+func newSuppressedErrorsDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(collector.Namespace, subsystem, "suppressed_collector_errors_total"),
+		"node_exporter: Number of collector errors not logged as they repeated the last logged one.",
+		[]string{"collector"}, nil,
+	)
+}
+
+// failure logs err of the named collector, unless it failed with the same
+// error within interval. It returns whether err was logged.
+func (l *errorLog) failure(name string, duration time.Duration, err error, interval time.Duration) bool {
+	now := time.Now()
+	msg := err.Error()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	last, ok := l.last[name]
+	if ok && last.msg == msg && now.Sub(last.logged) < interval {
+		last.repeats++
+		l.suppressed[name]++
+		return false
+	}
+	var repeats int
+	if ok && last.msg == msg {
+		repeats = last.repeats
+	}
+	log.With("collector", name, "duration_seconds", duration.Seconds(), "repeats", repeats, "err", msg).Errorf("Collector failed")
+	l.last[name] = &loggedError{msg: msg, logged: now}
+	return true
+}
+
+// success forgets the error of the named collector, logging that it
+// recovered if it failed before.
+func (l *errorLog) success(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	last, ok := l.last[name]
+	if !ok {
+		return
+	}
+	log.With("collector", name, "repeats", last.repeats).Infof("Collector recovered")
+	delete(l.last, name)
+}
+
+// collect sends the number of suppressed errors per collector.
+func (l *errorLog) collect(ch chan<- prometheus.Metric) {
+	desc := newSuppressedErrorsDesc()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	names := make([]string, 0, len(l.suppressed))
+	for name := range l.suppressed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, l.suppressed[name], name)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestErrorLog(t *testing.T) {
+	l := newErrorLog()
+	failed, other := errors.New("failed"), errors.New("other")
+
+	for i, test := range []struct {
+		name     string
+		err      error
+		interval time.Duration
+		logged   bool
+	}{
+		{"a", failed, time.Hour, true},
+		{"a", failed, time.Hour, false},
+		{"a", failed, time.Hour, false},
+		{"b", failed, time.Hour, true},
+		{"a", other, time.Hour, true},
+		{"a", other, 0, true},
+		{"a", nil, time.Hour, false},
+		{"a", other, time.Hour, true},
+	} {
+		if test.err == nil {
+			l.success(test.name)
+			continue
+		}
+		if want, got := test.logged, l.failure(test.name, time.Second, test.err, test.interval); want != got {
+			t.Errorf("%d: want logged %t, got %t", i, want, got)
+		}
+	}
+
+	ch := make(chan prometheus.Metric, 2)
+	l.collect(ch)
+	if want, got := 1, len(ch); want != got {
+		t.Fatalf("want %d metrics, got %d", want, got)
+	}
+	var m dto.Metric
+	if err := (<-ch).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "a", m.Label[0].GetValue(); want != got {
+		t.Errorf("want collector label %q, got %q", want, got)
+	}
+	if want, got := 2.0, m.GetCounter().GetValue(); want != got {
+		t.Errorf("want %f suppressed errors, got %f", want, got)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	goMetrics      = flag.Bool("web.go-metrics", true, "If true, export the go_* metrics of the Go runtime of the exporter.")
+	processMetrics = flag.Bool("web.process-metrics", true, "If true, export the process_* metrics of the exporter process.")
+)
+
+// unregisterExporterMetrics removes the collectors of the go_* and process_*
+// metrics, which the default registry starts with, unless enabled. The
+// registry unregisters the collector with the same descs as the one given.
+func unregisterExporterMetrics() {
+	if !*goMetrics {
+		prometheus.Unregister(prometheus.NewGoCollector())
+	}
+	if !*processMetrics {
+		prometheus.Unregister(prometheus.NewProcessCollector(os.Getpid(), ""))
+	}
+}
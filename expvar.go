@@ -0,0 +1,71 @@
+package main
+
+import (
+	"expvar"
+	"flag"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+var enableExpvar = flag.Bool("web.enable-expvar", false, "If true, serve the expvar variables at /debug/vars, leaving out the command line.")
+
+// These are served at /debug/vars with the memstats variable of the expvar
+// package.
+var (
+	collectorVars   = expvar.NewMap("collectors")
+	collectorVarsMu sync.Mutex
+)
+
+func init() {
+	expvar.Publish("build_info", expvar.Func(func() interface{} {
+		return map[string]string{
+			"version":   version,
+			"revision":  revision,
+			"branch":    branch,
+			"goversion": runtime.Version(),
+		}
+	}))
+}
+
+// recordCollectorVars counts a run of the named collector and sets its last
+// duration.
+func recordCollectorVars(name string, duration time.Duration, err error) {
+	collectorVarsMu.Lock()
+	m, ok := collectorVars.Get(name).(*expvar.Map)
+	if !ok {
+		m = new(expvar.Map).Init()
+		m.Set("last_duration_seconds", new(expvar.Float))
+		m.Add("scrapes", 0)
+		m.Add("errors", 0)
+		collectorVars.Set(name, m)
+	}
+	collectorVarsMu.Unlock()
+
+	m.Get("last_duration_seconds").(*expvar.Float).Set(duration.Seconds())
+	m.Add("scrapes", 1)
+	if err != nil {
+		m.Add("errors", 1)
+	}
+}
+
+// expvarHandler serves the expvar variables like the handler of the expvar
+// package, but without cmdline, which holds secrets like -auth.pass.
+func expvarHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprintf(w, "{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if kv.Key == "cmdline" {
+			return
+		}
+		if !first {
+			fmt.Fprintf(w, ",\n")
+		}
+		first = false
+		fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+	})
+	fmt.Fprintf(w, "\n}\n")
+}
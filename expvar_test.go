@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRecordCollectorVars(t *testing.T) {
+	recordCollectorVars("expvartest", 2*time.Second, nil)
+	recordCollectorVars("expvartest", 500*time.Millisecond, errors.New("failed"))
+
+	want := `{"errors": 1, "last_duration_seconds": 0.5, "scrapes": 2}`
+	if got := collectorVars.Get("expvartest").String(); got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	if expvar.Get("build_info") == nil {
+		t.Error("build_info not published")
+	}
+}
+
+func TestExpvarHandler(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/debug/vars", nil)
+	w := httptest.NewRecorder()
+	expvarHandler(w, r)
+	var vars map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("invalid JSON %q: %s", w.Body.String(), err)
+	}
+	if _, ok := vars["cmdline"]; ok {
+		t.Error("want cmdline left out")
+	}
+	if _, ok := vars["build_info"]; !ok {
+		t.Error("want build_info served")
+	}
+}
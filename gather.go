@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/text"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const textContentType = "text/plain; version=0.0.4"
+
+// gather returns the metric families served by handler for r, sorted by
+// name. The client library doesn't expose its registry, so the metrics are
+// read back from the text format.
+func gather(handler http.Handler, r *http.Request) ([]*dto.MetricFamily, error) {
+	req := *r
+	req.Header = http.Header{}
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", textContentType)
+	req.Header.Del("Accept-Encoding")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, &req)
+	if rec.Code != http.StatusOK {
+		return nil, gatherError{code: rec.Code, message: strings.TrimSpace(rec.Body.String())}
+	}
+
+	var parser text.Parser
+	families, err := parser.TextToMetricFamilies(rec.Body)
+	if err != nil {
+		return nil, err
+	}
+	return sortedFamilies(families), nil
+}
+
+// collectFamilies returns the metrics sent by collect, grouped into families
+// sorted by name. The type of a family is that of its first metric.
+func collectFamilies(collect func(chan<- prometheus.Metric)) ([]*dto.MetricFamily, error) {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collect(ch)
+		close(ch)
+	}()
+	families := map[string]*dto.MetricFamily{}
+	var first error
+	for m := range ch {
+		name, help, _, err := parseDesc(m.Desc())
+		if err == nil {
+			pb := &dto.Metric{}
+			if err = m.Write(pb); err == nil {
+				addMetric(families, name, help, pb)
+			}
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	if first != nil {
+		return nil, first
+	}
+	return sortedFamilies(families), nil
+}
+
+// addMetric adds m to the family of the given name, creating the family if
+// there is none yet.
+func addMetric(families map[string]*dto.MetricFamily, name, help string, m *dto.Metric) {
+	mf, ok := families[name]
+	if !ok {
+		typ := dto.MetricType_UNTYPED
+		switch {
+		case m.Counter != nil:
+			typ = dto.MetricType_COUNTER
+		case m.Gauge != nil:
+			typ = dto.MetricType_GAUGE
+		case m.Summary != nil:
+			typ = dto.MetricType_SUMMARY
+		case m.Histogram != nil:
+			typ = dto.MetricType_HISTOGRAM
+		}
+		mf = &dto.MetricFamily{Name: &name, Help: &help, Type: &typ}
+		families[name] = mf
+	}
+	mf.Metric = append(mf.Metric, m)
+}
+
+// mergeFamilies returns the families of a and b, sorted by name, merging
+// those of the same name.
+func mergeFamilies(a, b []*dto.MetricFamily) []*dto.MetricFamily {
+	families := map[string]*dto.MetricFamily{}
+	for _, list := range [][]*dto.MetricFamily{a, b} {
+		for _, mf := range list {
+			if merged, ok := families[mf.GetName()]; ok {
+				merged.Metric = append(merged.Metric, mf.Metric...)
+				continue
+			}
+			families[mf.GetName()] = mf
+		}
+	}
+	return sortedFamilies(families)
+}
+
+func sortedFamilies(families map[string]*dto.MetricFamily) []*dto.MetricFamily {
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]*dto.MetricFamily, 0, len(names))
+	for _, name := range names {
+		result = append(result, families[name])
+	}
+	return result
+}
+
+// gatherError is returned by gather when the handler doesn't answer 200.
+type gatherError struct {
+	code    int
+	message string
+}
+
+func (e gatherError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.code, e.message)
+}
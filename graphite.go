@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	graphiteAddress  = flag.String("graphite.address", "", "Address of a Graphite/Carbon plaintext listener to send all metrics to periodically, e.g. carbon:2003.")
+	graphiteInterval = flag.Duration("graphite.interval", time.Minute, "Interval between sends to Graphite.")
+	graphitePrefix   = flag.String("graphite.prefix", "", "Prefix of the Graphite paths, e.g. servers.web01.")
+
+	graphiteInvalidRE = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+)
+
+// graphiteSender sends samples in the Carbon plaintext protocol.
+type graphiteSender struct {
+	address string
+	prefix  string
+	timeout time.Duration
+}
+
+func (g graphiteSender) send(samples []sample, now time.Time) error {
+	conn, err := net.DialTimeout("tcp", g.address, g.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(now.Add(g.timeout))
+
+	w := bufio.NewWriter(conn)
+	writeGraphite(w, g.prefix, samples, now)
+	return w.Flush()
+}
+
+// writeGraphite writes a "path value timestamp" line per sample. The path is
+// the metric name followed by the sorted label names and values, so
+// node_cpu{cpu="cpu0",mode="idle"} becomes node_cpu.cpu.cpu0.mode.idle.
+func writeGraphite(w *bufio.Writer, prefix string, samples []sample, now time.Time) {
+	timestamp := " " + strconv.FormatInt(now.Unix(), 10) + "\n"
+	prefix = strings.TrimSuffix(prefix, ".")
+	if prefix != "" {
+		prefix += "."
+	}
+	for _, s := range samples {
+		w.WriteString(prefix + graphitePath(s.name, s.labels) + " " + strconv.FormatFloat(s.value, 'g', -1, 64) + timestamp)
+	}
+}
+
+func graphitePath(name string, labels []*dto.LabelPair) string {
+	parts := make([]string, 0, len(labels))
+	for _, l := range labels {
+		parts = append(parts, graphiteInvalidRE.ReplaceAllString(l.GetName(), "_")+"."+graphiteInvalidRE.ReplaceAllString(l.GetValue(), "_"))
+	}
+	sort.Strings(parts)
+	return strings.Join(append([]string{graphiteInvalidRE.ReplaceAllString(name, "_")}, parts...), ".")
+}
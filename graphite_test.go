@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/text"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestWriteGraphite(t *testing.T) {
+	var parser text.Parser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(`# TYPE node_cpu counter
+node_cpu{mode="idle",cpu="cpu0"} 362812.79
+# TYPE node_filesystem_free gauge
+node_filesystem_free{filesystem="/var/lib"} 1e+09
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	samples := flattenSamples([]*dto.MetricFamily{families["node_cpu"], families["node_filesystem_free"]})
+	writeGraphite(w, "servers.web01.", samples, time.Unix(1426899682, 0))
+	w.Flush()
+
+	want := `servers.web01.node_cpu.cpu.cpu0.mode.idle 362812.79 1426899682
+servers.web01.node_filesystem_free.filesystem._var_lib 1e+09 1426899682
+`
+	if got := buf.String(); want != got {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// ready is set to 1 once all collectors have been set up.
+var ready int32
+
+func setReady(r bool) {
+	if r {
+		atomic.StoreInt32(&ready, 1)
+	} else {
+		atomic.StoreInt32(&ready, 0)
+	}
+}
+
+// healthyHandler reports that the process is up, without collecting metrics.
+func healthyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("Node Exporter is Healthy.\n"))
+}
+
+// readyHandler reports whether the collectors have been set up.
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&ready) == 0 {
+		http.Error(w, "Node Exporter is not ready.", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("Node Exporter is Ready.\n"))
+}
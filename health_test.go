@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadyHandler(t *testing.T) {
+	for _, r := range []bool{false, true} {
+		setReady(r)
+		req, _ := http.NewRequest("GET", "/-/ready", nil)
+		w := httptest.NewRecorder()
+		readyHandler(w, req)
+
+		want := http.StatusServiceUnavailable
+		if r {
+			want = http.StatusOK
+		}
+		if w.Code != want {
+			t.Errorf("ready %t: want status %d, got %d", r, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	influxDBURL      = flag.String("influxdb.url", "", "InfluxDB endpoint to send all metrics to periodically in line protocol, e.g. http://influxdb:8086/write?db=node or udp://influxdb:8089.")
+	influxDBInterval = flag.Duration("influxdb.interval", time.Minute, "Interval between sends to InfluxDB.")
+)
+
+// influxDBSender sends samples in InfluxDB line protocol, over HTTP or UDP.
+type influxDBSender struct {
+	url     *url.URL
+	timeout time.Duration
+	client  *http.Client
+}
+
+func newInfluxDBSender(rawurl string, timeout time.Duration) (*influxDBSender, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "udp" {
+		return nil, fmt.Errorf("unsupported scheme %q, expected http, https or udp", u.Scheme)
+	}
+	return &influxDBSender{url: u, timeout: timeout, client: &http.Client{Timeout: timeout}}, nil
+}
+
+func (s *influxDBSender) send(samples []sample, now time.Time) error {
+	lines := influxDBLines(samples, now)
+	if s.url.Scheme == "udp" {
+		return s.sendUDP(lines)
+	}
+
+	resp, err := s.client.Post(s.url.String(), "text/plain; charset=utf-8", strings.NewReader(strings.Join(lines, "")))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// sendUDP sends the lines batched into packets.
+func (s *influxDBSender) sendUDP(lines []string) error {
+	conn, err := net.DialTimeout("udp", s.url.Host, s.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return writePackets(conn, lines, maxPacketSize)
+}
+
+// influxDBLines returns a line per sample, with the metric name as
+// measurement, the labels as tags and the value as field "value". InfluxDB
+// rejects NaN and infinite values, so those samples are skipped.
+func influxDBLines(samples []sample, now time.Time) []string {
+	timestamp := " " + strconv.FormatInt(now.UnixNano(), 10) + "\n"
+	lines := make([]string, 0, len(samples))
+	for _, s := range samples {
+		if math.IsNaN(s.value) || math.IsInf(s.value, 0) {
+			continue
+		}
+		tags := make([]string, 0, len(s.labels))
+		for _, l := range s.labels {
+			if l.GetValue() == "" {
+				// Empty tag values aren't allowed.
+				continue
+			}
+			tags = append(tags, influxDBTagEscaper.Replace(l.GetName())+"="+influxDBTagEscaper.Replace(l.GetValue()))
+		}
+		sort.Strings(tags)
+
+		line := influxDBMeasurementEscaper.Replace(s.name)
+		if len(tags) > 0 {
+			line += "," + strings.Join(tags, ",")
+		}
+		lines = append(lines, line+" value="+strconv.FormatFloat(s.value, 'g', -1, 64)+timestamp)
+	}
+	return lines
+}
+
+var (
+	influxDBMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxDBTagEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
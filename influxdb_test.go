@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/text"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const testInfluxDBLines = `node_cpu,cpu=cpu0,mode=idle value=362812.79 1426899682000000000
+node_filesystem_free,filesystem=/mnt/my\ disk value=1e+09 1426899682000000000
+`
+
+func testInfluxDBSamples(t *testing.T) []sample {
+	var parser text.Parser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(`# TYPE node_cpu counter
+node_cpu{mode="idle",cpu="cpu0"} 362812.79
+# TYPE node_filesystem_free gauge
+node_filesystem_free{filesystem="/mnt/my disk"} 1e+09
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return flattenSamples([]*dto.MetricFamily{families["node_cpu"], families["node_filesystem_free"]})
+}
+
+func TestInfluxDBHTTP(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s, err := newInfluxDBSender(server.URL+"/write?db=node", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.send(testInfluxDBSamples(t), time.Unix(1426899682, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if body != testInfluxDBLines {
+		t.Errorf("want:\n%s\ngot:\n%s", testInfluxDBLines, body)
+	}
+}
+
+func TestInfluxDBUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s, err := newInfluxDBSender("udp://"+conn.LocalAddr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.send(testInfluxDBSamples(t), time.Unix(1426899682, 0)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, maxPacketSize)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != testInfluxDBLines {
+		t.Errorf("want:\n%s\ngot:\n%s", testInfluxDBLines, got)
+	}
+}
+
+func TestInfluxDBLinesSkipsNaN(t *testing.T) {
+	samples := append(testInfluxDBSamples(t),
+		sample{name: "node_nan", value: math.NaN()},
+		sample{name: "node_inf", value: math.Inf(1)},
+		sample{name: "node_neg_inf", value: math.Inf(-1)},
+	)
+	got := strings.Join(influxDBLines(samples, time.Unix(1426899682, 0)), "")
+	if got != testInfluxDBLines {
+		t.Errorf("want:\n%s\ngot:\n%s", testInfluxDBLines, got)
+	}
+}
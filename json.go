@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// jsonHandler serves the metrics of handler as JSON, for consumers that
+// can't parse the text format.
+type jsonHandler struct {
+	handler http.Handler
+}
+
+type jsonFamily struct {
+	Name    string       `json:"name"`
+	Help    string       `json:"help,omitempty"`
+	Type    string       `json:"type"`
+	Metrics []jsonMetric `json:"metrics"`
+}
+
+type jsonMetric struct {
+	Labels    map[string]string    `json:"labels,omitempty"`
+	Value     *jsonFloat           `json:"value,omitempty"`
+	Quantiles map[string]jsonFloat `json:"quantiles,omitempty"`
+	Buckets   map[string]uint64    `json:"buckets,omitempty"`
+	Sum       *jsonFloat           `json:"sum,omitempty"`
+	Count     *uint64              `json:"count,omitempty"`
+}
+
+// jsonFloat is a float64 encoding NaN and infinities as the strings "NaN",
+// "+Inf" and "-Inf", which JSON numbers can't represent.
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return []byte(`"` + formatOpenMetricsFloat(v) + `"`), nil
+	}
+	return []byte(strconv.FormatFloat(v, 'g', -1, 64)), nil
+}
+
+func (h jsonHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	families, err := gather(h.handler, r)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if e, ok := err.(gatherError); ok {
+			code = e.code
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	result := make([]jsonFamily, 0, len(families))
+	for _, mf := range families {
+		result = append(result, newJSONFamily(mf))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func newJSONFamily(mf *dto.MetricFamily) jsonFamily {
+	family := jsonFamily{
+		Name:    mf.GetName(),
+		Help:    mf.GetHelp(),
+		Type:    strings.ToLower(mf.GetType().String()),
+		Metrics: make([]jsonMetric, 0, len(mf.Metric)),
+	}
+	for _, m := range mf.Metric {
+		var metric jsonMetric
+		if len(m.Label) > 0 {
+			metric.Labels = make(map[string]string, len(m.Label))
+			for _, l := range m.Label {
+				metric.Labels[l.GetName()] = l.GetValue()
+			}
+		}
+		value := func(v float64) *jsonFloat {
+			f := jsonFloat(v)
+			return &f
+		}
+		count := func(c uint64) *uint64 {
+			return &c
+		}
+		switch {
+		case m.Counter != nil:
+			metric.Value = value(m.Counter.GetValue())
+		case m.Gauge != nil:
+			metric.Value = value(m.Gauge.GetValue())
+		case m.Summary != nil:
+			metric.Quantiles = make(map[string]jsonFloat, len(m.Summary.GetQuantile()))
+			for _, q := range m.Summary.GetQuantile() {
+				metric.Quantiles[strconv.FormatFloat(q.GetQuantile(), 'g', -1, 64)] = jsonFloat(q.GetValue())
+			}
+			metric.Sum = value(m.Summary.GetSampleSum())
+			metric.Count = count(m.Summary.GetSampleCount())
+		case m.Histogram != nil:
+			metric.Buckets = make(map[string]uint64, len(m.Histogram.GetBucket()))
+			for _, b := range m.Histogram.GetBucket() {
+				metric.Buckets[formatOpenMetricsFloat(b.GetUpperBound())] = b.GetCumulativeCount()
+			}
+			metric.Sum = value(m.Histogram.GetSampleSum())
+			metric.Count = count(m.Histogram.GetSampleCount())
+		default:
+			metric.Value = value(m.Untyped.GetValue())
+		}
+		family.Metrics = append(family.Metrics, metric)
+	}
+	return family
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testJSON = `[{"name":"node_cpu","help":"Seconds the cpus spent in each mode.","type":"counter","metrics":[{"labels":{"cpu":"cpu0","mode":"idle"},"value":362812.7890625}]},` +
+	`{"name":"node_exporter_scrape_duration_seconds","help":"node_exporter: Duration of a scrape job.","type":"summary","metrics":[{"labels":{"collector":"stat","result":"success"},"quantiles":{"0.5":0.00023},"sum":0.0046,"count":20}]},` +
+	`{"name":"node_filesystem_size","help":"Filesystem size in bytes.","type":"gauge","metrics":[{"labels":{"filesystem":"/"},"value":1.0619203584e+10}]},` +
+	`{"name":"node_textfile_info","help":"A \"quoted\" help.","type":"untyped","metrics":[{"labels":{"role":"web\\server"},"value":1}]}]
+`
+
+func TestJSONHandler(t *testing.T) {
+	h := jsonHandler{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testExposition))
+	})}
+
+	r, _ := http.NewRequest("GET", "/metrics.json", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if want, got := "application/json", w.Header().Get("Content-Type"); want != got {
+		t.Errorf("want content type %q, got %q", want, got)
+	}
+	if want, got := testJSON, w.Body.String(); want != got {
+		t.Errorf("want JSON:\n%s\ngot:\n%s", want, got)
+	}
+
+	h = jsonHandler{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unknown collector", http.StatusBadRequest)
+	})}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("want status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestJSONFloat(t *testing.T) {
+	for in, want := range map[float64]string{
+		1.5:         "1.5",
+		1e+21:       "1e+21",
+		math.Inf(1): `"+Inf"`,
+	} {
+		got, err := jsonFloat(in).MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%v: want %s, got %s", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"runtime"
+
+	"github.com/prometheus/node_exporter/log"
+)
+
+var landingTemplate = template.Must(template.New("landing").Parse(`<html>
+<head><title>Node Exporter</title></head>
+<body>
+<h1>Node Exporter</h1>
+<p><a href="{{.MetricsPath}}">Metrics</a></p>
+<h2>Enabled collectors</h2>
+<ul>
+{{range .Collectors}}<li>{{.}}</li>
+{{end}}</ul>
+<p>Version {{.Version}}, built with {{.GoVersion}}</p>
+</body>
+</html>
+`))
+
+type landingPage struct {
+	// collectors returns the names of the enabled collectors.
+	collectors func() []string
+}
+
+func newLandingPage(collectors func() []string) *landingPage {
+	return &landingPage{collectors: collectors}
+}
+
+func (p *landingPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var buf bytes.Buffer
+	err := landingTemplate.Execute(&buf, struct {
+		MetricsPath string
+		Collectors  []string
+		Version     string
+		GoVersion   string
+	}{*metricsPath, p.collectors(), version, runtime.Version()})
+	if err != nil {
+		log.Errorf("Couldn't render landing page: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLandingPage(t *testing.T) {
+	p := newLandingPage(func() []string { return []string{"meminfo", "stat"} })
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	body := w.Body.String()
+	for _, want := range []string{`<a href="/metrics">`, "<li>meminfo</li>\n<li>stat</li>", "Version unknown"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("landing page doesn't contain %q:\n%s", want, body)
+		}
+	}
+
+	r, _ = http.NewRequest("GET", "/favicon.ico", nil)
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("want status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
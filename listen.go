@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var (
+	listenAddresses  = &addressList{addresses: []string{":9100"}}
+	listenSocket     = flag.String("web.listen-socket", "", "Path of a Unix socket on which to expose metrics and web interface.")
+	listenSocketMode = flag.String("web.listen-socket-mode", "0660", "Permissions of the Unix socket, in octal.")
+	listenSocketUID  = flag.Int("web.listen-socket-uid", -1, "Numeric user id to own the Unix socket. -1 keeps the current user.")
+	listenSocketGID  = flag.Int("web.listen-socket-gid", -1, "Numeric group id to own the Unix socket. -1 keeps the current group.")
+	systemdSocket    = flag.Bool("web.systemd-socket", false, "Use the sockets passed by systemd socket activation instead of -web.listen-address and -web.listen-socket.")
+)
+
+func init() {
+	flag.Var(listenAddresses, "web.listen-address", "Address on which to expose metrics and web interface. Repeat to listen on several addresses, set to empty to not listen on TCP.")
+}
+
+// addressList is a repeatable flag. The default is replaced by the first
+// address given on the command line.
+type addressList struct {
+	addresses []string
+	set       bool
+}
+
+func (a *addressList) String() string {
+	return strings.Join(a.addresses, ",")
+}
+
+func (a *addressList) Set(address string) error {
+	if !a.set {
+		a.addresses = nil
+		a.set = true
+	}
+	if address != "" {
+		a.addresses = append(a.addresses, address)
+	}
+	return nil
+}
+
+// First file descriptor passed by systemd, see sd_listen_fds(3).
+const listenFDsStart = 3
+
+// listen opens all configured listeners.
+func listen() ([]net.Listener, error) {
+	if *systemdSocket {
+		return systemdListeners()
+	}
+
+	listeners := []net.Listener{}
+	for _, address := range listenAddresses.addresses {
+		l, err := net.Listen("tcp", address)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	if *listenSocket != "" {
+		mode, err := strconv.ParseUint(*listenSocketMode, 8, 32)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, fmt.Errorf("invalid socket mode %q: %s", *listenSocketMode, err)
+		}
+		l, err := listenUnix(*listenSocket, os.FileMode(mode), *listenSocketUID, *listenSocketGID)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+// listenUnix listens on a Unix socket at path, replacing a stale socket left
+// behind by a previous run.
+func listenUnix(path string, mode os.FileMode, uid, gid int) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if uid != -1 || gid != -1 {
+		if err := os.Chown(path, uid, gid); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// systemdListeners returns the listeners passed by systemd socket activation.
+func systemdListeners() ([]net.Listener, error) {
+	files, err := activationFiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no sockets passed by systemd")
+	}
+	listeners := make([]net.Listener, 0, len(files))
+	for _, f := range files {
+		l, err := net.FileListener(f)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't use %s: %s", f.Name(), err)
+		}
+		// FileListener dups the descriptor.
+		f.Close()
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// activationFiles implements sd_listen_fds(3): it returns the file
+// descriptors passed by systemd and unsets the environment variables, so
+// they aren't inherited by child processes.
+func activationFiles() ([]*os.File, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LISTEN_FDS: %s", err)
+	}
+
+	files := make([]*os.File, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		syscall.CloseOnExec(fd)
+		files = append(files, os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd)))
+	}
+	return files, nil
+}
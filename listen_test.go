@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node_exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "node_exporter.sock")
+
+	l, err := listenUnix(path, 0600, -1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := os.FileMode(0600), fi.Mode().Perm(); want != got {
+		t.Errorf("want mode %s, got %s", want, got)
+	}
+}
+
+func TestActivationFiles(t *testing.T) {
+	os.Setenv("LISTEN_PID", "1")
+	os.Setenv("LISTEN_FDS", "1")
+	files, err := activationFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("want no files for a foreign LISTEN_PID, got %d", len(files))
+	}
+	if os.Getenv("LISTEN_FDS") != "" {
+		t.Error("LISTEN_FDS not unset")
+	}
+
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	os.Setenv("LISTEN_FDS", "invalid")
+	if _, err := activationFiles(); err == nil {
+		t.Error("expected error for invalid LISTEN_FDS")
+	}
+}
+
+func TestAddressList(t *testing.T) {
+	a := &addressList{addresses: []string{":9100"}}
+	if want, got := ":9100", a.String(); want != got {
+		t.Errorf("want default %s, got %s", want, got)
+	}
+	a.Set("127.0.0.1:9100")
+	a.Set("10.0.0.1:9100")
+	if want, got := "127.0.0.1:9100,10.0.0.1:9100", a.String(); want != got {
+		t.Errorf("want %s, got %s", want, got)
+	}
+
+	a = &addressList{addresses: []string{":9100"}}
+	a.Set("")
+	if len(a.addresses) != 0 {
+		t.Errorf("want no addresses, got %v", a.addresses)
+	}
+}
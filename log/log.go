@@ -0,0 +1,258 @@
+// Package log is the leveled, structured logger of the node exporter. Each
+// record is a line of key-value pairs, in logfmt or JSON, with the time,
+// level, caller and message followed by the fields added with With.
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a record. Records below the level set with
+// SetLevel are dropped.
+type Level int
+
+// The levels, from the most verbose.
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < DebugLevel || l > ErrorLevel {
+		return strconv.Itoa(int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level named debug, info, warn or error.
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if s == name {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q, want one of %s", s, strings.Join(levelNames, ", "))
+}
+
+// Formats of the records.
+const (
+	FormatLogfmt = "logfmt"
+	FormatJSON   = "json"
+)
+
+var (
+	mu     sync.Mutex
+	level         = InfoLevel
+	format        = FormatLogfmt
+	out    output = writerOutput{os.Stderr}
+)
+
+// SetLevel sets the least severe level logged.
+func SetLevel(l Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	level = l
+}
+
+// SetFormat sets the format of the records, logfmt or json.
+func SetFormat(f string) error {
+	if f != FormatLogfmt && f != FormatJSON {
+		return fmt.Errorf("unknown log format %q, want %s or %s", f, FormatLogfmt, FormatJSON)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	format = f
+	return nil
+}
+
+// SetOutput sets the writer the records are written to, stderr by default.
+func SetOutput(w io.Writer) {
+	setOutput(writerOutput{w})
+}
+
+func setOutput(o output) {
+	mu.Lock()
+	defer mu.Unlock()
+	if c, ok := out.(io.Closer); ok {
+		c.Close()
+	}
+	out = o
+}
+
+// Enabled returns whether records of level l are logged, to skip
+// building expensive debug messages.
+func Enabled(l Level) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return l >= level
+}
+
+// Logger logs records with a fixed set of fields.
+type Logger struct {
+	fields []interface{}
+}
+
+var std = &Logger{}
+
+// With returns a Logger adding the alternating keys and values to each
+// record, like With("collector", name).
+func With(keyvals ...interface{}) *Logger {
+	return std.With(keyvals...)
+}
+
+// With returns a Logger adding the keys and values to those of l.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "(MISSING)")
+	}
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	return &Logger{fields: append(append(fields, l.fields...), keyvals...)}
+}
+
+// Debugf logs at the debug level.
+func (l *Logger) Debugf(format string, args ...interface{}) { l.log(DebugLevel, format, args...) }
+
+// Infof logs at the info level.
+func (l *Logger) Infof(format string, args ...interface{}) { l.log(InfoLevel, format, args...) }
+
+// Warnf logs at the warn level.
+func (l *Logger) Warnf(format string, args ...interface{}) { l.log(WarnLevel, format, args...) }
+
+// Errorf logs at the error level.
+func (l *Logger) Errorf(format string, args ...interface{}) { l.log(ErrorLevel, format, args...) }
+
+// Fatalf logs at the error level and exits with status 1.
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.log(ErrorLevel, format, args...)
+	os.Exit(1)
+}
+
+// Debugf logs at the debug level.
+func Debugf(format string, args ...interface{}) { std.log(DebugLevel, format, args...) }
+
+// Infof logs at the info level.
+func Infof(format string, args ...interface{}) { std.log(InfoLevel, format, args...) }
+
+// Warnf logs at the warn level.
+func Warnf(format string, args ...interface{}) { std.log(WarnLevel, format, args...) }
+
+// Errorf logs at the error level.
+func Errorf(format string, args ...interface{}) { std.log(ErrorLevel, format, args...) }
+
+// Fatalf logs at the error level and exits with status 1.
+func Fatalf(format string, args ...interface{}) {
+	std.log(ErrorLevel, format, args...)
+	os.Exit(1)
+}
+
+// Fatal logs the args like fmt.Sprint at the error level and exits with
+// status 1.
+func Fatal(args ...interface{}) {
+	std.log(ErrorLevel, "%s", fmt.Sprint(args...))
+	os.Exit(1)
+}
+
+// log writes a record. The caller is that of the exported function, two
+// frames up.
+func (l *Logger) log(lvl Level, msg string, args ...interface{}) {
+	if !Enabled(lvl) {
+		return
+	}
+	caller := "???"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		caller = filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+	keyvals := append([]interface{}{
+		"time", time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		"level", lvl.String(),
+		"caller", caller,
+		"msg", fmt.Sprintf(msg, args...),
+	}, l.fields...)
+
+	mu.Lock()
+	defer mu.Unlock()
+	out.write(lvl, keyvals)
+}
+
+// formatRecord returns keyvals as a line in the format set with SetFormat.
+func formatRecord(keyvals []interface{}) []byte {
+	var buf bytes.Buffer
+	if format == FormatJSON {
+		writeJSON(&buf, keyvals)
+	} else {
+		writeLogfmt(&buf, keyvals)
+	}
+	return buf.Bytes()
+}
+
+// writeLogfmt writes keyvals as a line of key=value pairs, quoting values
+// with spaces, quotes or equal signs.
+func writeLogfmt(buf *bytes.Buffer, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(fmt.Sprint(keyvals[i]))
+		buf.WriteByte('=')
+		v := stringValue(keyvals[i+1])
+		if v == "" || strings.ContainsAny(v, " =\"\\\n\t") {
+			v = strconv.Quote(v)
+		}
+		buf.WriteString(v)
+	}
+	buf.WriteByte('\n')
+}
+
+// writeJSON writes keyvals as a JSON object on one line, keeping numbers
+// and booleans and writing other values as strings.
+func writeJSON(buf *bytes.Buffer, keyvals []interface{}) {
+	buf.WriteByte('{')
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(fmt.Sprint(keyvals[i]))
+		buf.Write(k)
+		buf.WriteByte(':')
+		var v interface{}
+		switch val := keyvals[i+1].(type) {
+		case bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+			v = val
+		default:
+			v = stringValue(val)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			// NaN and infinite floats.
+			b, _ = json.Marshal(fmt.Sprint(v))
+		}
+		buf.Write(b)
+	}
+	buf.WriteString("}\n")
+}
+
+func stringValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "<nil>"
+	case error:
+		return val.Error()
+	case fmt.Stringer:
+		return val.String()
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func capture(t *testing.T, f string, lvl Level) *bytes.Buffer {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetLevel(lvl)
+	if err := SetFormat(f); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func reset() {
+	SetOutput(os.Stderr)
+	SetLevel(InfoLevel)
+	SetFormat(FormatLogfmt)
+}
+
+func TestLogfmt(t *testing.T) {
+	defer reset()
+	buf := capture(t, FormatLogfmt, InfoLevel)
+
+	With("collector", "diskstats", "repeats", 2).Errorf("Collector failed: %s", "no such file")
+	line := buf.String()
+	for _, want := range []string{
+		" level=error caller=log_test.go:",
+		` msg="Collector failed: no such file" collector=diskstats repeats=2` + "\n",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("want %q in %q", want, line)
+		}
+	}
+	if !strings.HasPrefix(line, "time=") {
+		t.Errorf("want time first in %q", line)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	defer reset()
+	buf := capture(t, FormatJSON, InfoLevel)
+
+	With("duration_seconds", 1.5, "name", "a b").Warnf("Slow")
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON %q: %s", buf.String(), err)
+	}
+	for k, want := range map[string]interface{}{
+		"level":            "warn",
+		"msg":              "Slow",
+		"duration_seconds": 1.5,
+		"name":             "a b",
+	} {
+		if got := record[k]; want != got {
+			t.Errorf("want %s %v, got %v", k, want, got)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	defer reset()
+	buf := capture(t, FormatLogfmt, WarnLevel)
+
+	Debugf("debug")
+	Infof("info")
+	if buf.Len() != 0 {
+		t.Errorf("want records below warn dropped, got %q", buf.String())
+	}
+	Errorf("error")
+	if !strings.Contains(buf.String(), "msg=error") {
+		t.Errorf("want error logged, got %q", buf.String())
+	}
+
+	if l, err := ParseLevel("debug"); err != nil || l != DebugLevel {
+		t.Errorf("want debug level, got %s, %v", l, err)
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("want error for unknown level")
+	}
+	if err := SetFormat("xml"); err == nil {
+		t.Error("want error for unknown format")
+	}
+}
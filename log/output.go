@@ -0,0 +1,160 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net"
+	"os"
+	"strings"
+)
+
+// Outputs of the records.
+const (
+	OutputStderr   = "stderr"
+	OutputSyslog   = "syslog"
+	OutputJournald = "journald"
+)
+
+// identifier is the syslog tag and journal identifier of the records.
+const identifier = "node_exporter"
+
+// journalSocket is where journald receives records with its native
+// protocol.
+var journalSocket = "/run/systemd/journal/socket"
+
+// An output writes the keys and values of records, starting with the time,
+// level, caller and message. It is called with mu held.
+type output interface {
+	write(lvl Level, keyvals []interface{})
+}
+
+// Open sets the output of the records to stderr, the local syslog daemon
+// or the systemd journal, with the priorities of their levels.
+func Open(name string) error {
+	switch name {
+	case OutputStderr:
+		setOutput(writerOutput{os.Stderr})
+	case OutputSyslog:
+		w, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, identifier)
+		if err != nil {
+			return fmt.Errorf("couldn't connect to syslog: %s", err)
+		}
+		setOutput(syslogOutput{w})
+	case OutputJournald:
+		conn, err := net.Dial("unixgram", journalSocket)
+		if err != nil {
+			return fmt.Errorf("couldn't connect to journald: %s", err)
+		}
+		setOutput(journalOutput{conn})
+	default:
+		return fmt.Errorf("unknown log output %q, want %s, %s or %s", name, OutputStderr, OutputSyslog, OutputJournald)
+	}
+	return nil
+}
+
+// writerOutput writes formatted records to a writer.
+type writerOutput struct {
+	w io.Writer
+}
+
+func (o writerOutput) write(lvl Level, keyvals []interface{}) {
+	o.w.Write(formatRecord(keyvals))
+}
+
+// syslogOutput writes formatted records without the time, which syslog
+// adds, at the priority of their level.
+type syslogOutput struct {
+	w *syslog.Writer
+}
+
+func (o syslogOutput) write(lvl Level, keyvals []interface{}) {
+	msg := string(formatRecord(keyvals[2:]))
+	var err error
+	switch lvl {
+	case DebugLevel:
+		err = o.w.Debug(msg)
+	case InfoLevel:
+		err = o.w.Info(msg)
+	case WarnLevel:
+		err = o.w.Warning(msg)
+	default:
+		err = o.w.Err(msg)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't log to syslog: %s\n%s", err, formatRecord(keyvals))
+	}
+}
+
+func (o syslogOutput) Close() error {
+	return o.w.Close()
+}
+
+// journalOutput sends records to journald, with the fields of the records
+// as journal fields.
+type journalOutput struct {
+	conn net.Conn
+}
+
+func (o journalOutput) write(lvl Level, keyvals []interface{}) {
+	if _, err := o.conn.Write(journalRecord(lvl, keyvals)); err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't log to journald: %s\n%s", err, formatRecord(keyvals))
+	}
+}
+
+func (o journalOutput) Close() error {
+	return o.conn.Close()
+}
+
+// journalPriorities are the syslog priorities of the levels.
+var journalPriorities = map[Level]int{
+	DebugLevel: 7,
+	InfoLevel:  6,
+	WarnLevel:  4,
+	ErrorLevel: 3,
+}
+
+// journalRecord encodes a record in the native protocol of journald: the
+// message as MESSAGE, the level as PRIORITY and the other fields but the
+// time, which the journal adds, with their keys in upper case. Values with
+// newlines are sent as their length and bytes.
+func journalRecord(lvl Level, keyvals []interface{}) []byte {
+	var buf bytes.Buffer
+	field := func(key, value string) {
+		if !strings.Contains(value, "\n") {
+			fmt.Fprintf(&buf, "%s=%s\n", key, value)
+			return
+		}
+		buf.WriteString(key + "\n")
+		binary.Write(&buf, binary.LittleEndian, uint64(len(value)))
+		buf.WriteString(value + "\n")
+	}
+	field("PRIORITY", fmt.Sprint(journalPriorities[lvl]))
+	field("SYSLOG_IDENTIFIER", identifier)
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		switch key {
+		case "time", "level":
+			continue
+		case "msg":
+			key = "MESSAGE"
+		}
+		field(journalFieldName(key), stringValue(keyvals[i+1]))
+	}
+	return buf.Bytes()
+}
+
+// journalFieldName returns key as a journal field name, of upper case
+// letters, digits and underscores and not starting with an underscore,
+// which marks the fields journald adds.
+func journalFieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	return strings.TrimLeft(string(name), "_")
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJournalRecord(t *testing.T) {
+	record := journalRecord(WarnLevel, []interface{}{
+		"time", "2016-03-01T12:00:00.000Z",
+		"level", "warn",
+		"caller", "node.go:93",
+		"msg", "Collector failed",
+		"collector", "diskstats",
+		"err", "line one\nline two",
+	})
+	want := "PRIORITY=4\nSYSLOG_IDENTIFIER=node_exporter\nCALLER=node.go:93\nMESSAGE=Collector failed\nCOLLECTOR=diskstats\nERR\n\x11\x00\x00\x00\x00\x00\x00\x00line one\nline two\n"
+	if got := string(record); want != got {
+		t.Errorf("want record %q, got %q", want, got)
+	}
+
+	if want, got := "DURATION_SECONDS", journalFieldName("duration.seconds"); want != got {
+		t.Errorf("want field name %s, got %s", want, got)
+	}
+	if want, got := "KEY", journalFieldName("_key"); want != got {
+		t.Errorf("want field name %s, got %s", want, got)
+	}
+}
+
+func TestOpenJournald(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	defer func(s string) { journalSocket = s }(journalSocket)
+	journalSocket = socket
+	if err := Open(OutputJournald); err != nil {
+		t.Fatal(err)
+	}
+	defer reset()
+	Errorf("Couldn't listen")
+
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"PRIORITY=3\n", "\nMESSAGE=Couldn't listen\n", "\nCALLER=output_test.go:"} {
+		if !bytes.Contains(buf[:n], []byte(want)) {
+			t.Errorf("want %q in %q", want, buf[:n])
+		}
+	}
+
+	if err := Open("kafka"); err == nil || !strings.Contains(err.Error(), "unknown log output") {
+		t.Errorf("want error for unknown output, got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/prometheus/node_exporter/log"
+)
+
+var (
+	logLevel  = flag.String("log.level", "info", "Least severe level of the logged messages: debug, info, warn or error.")
+	logFormat = flag.String("log.format", log.FormatLogfmt, "Format of the log records: logfmt or json.")
+	logOutput = flag.String("log.output", log.OutputStderr, "Where to log: stderr, syslog for the local syslog daemon, or journald for the systemd journal.")
+)
+
+// setupLogging applies the log flags.
+func setupLogging() error {
+	level, err := log.ParseLevel(*logLevel)
+	if err != nil {
+		return err
+	}
+	if err := log.SetFormat(*logFormat); err != nil {
+		return err
+	}
+	log.SetLevel(level)
+	return log.Open(*logOutput)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
+)
+
+var checkNames = flag.Bool("collectors.check-names", false, "If true, update the collectors once when loading them and fail if two collectors export the same metric name, or a collector exports a name with different help strings or labels.")
+
+// nameCheckTimeout bounds the check update of collectors without a timeout.
+const nameCheckTimeout = 10 * time.Second
+
+// descPattern matches the String of a prometheus.Desc, the only way to get at
+// its name, help and label names.
+var descPattern = regexp.MustCompile(`^Desc\{fqName: ("(?:[^"\\]|\\.)*"), help: ("(?:[^"\\]|\\.)*"), .*variableLabels: \[([^\]]*)\]\}$`)
+
+// metricName is where a metric name was seen first.
+type metricName struct {
+	collector, help, labels string
+}
+
+// checkMetricNames updates the collectors once and returns an error listing
+// the metric names exported by several collectors, or with different help
+// strings or label names. Failing collectors are left out of the check, they
+// fail again on scrapes.
+func checkMetricNames(collectors map[string]collector.Collector, timeouts map[string]time.Duration) error {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		descs = map[string][]*prometheus.Desc{}
+	)
+	for name, c := range collectors {
+		wg.Add(1)
+		go func(name string, c collector.Collector) {
+			defer wg.Done()
+			timeout := timeouts[name]
+			if timeout == 0 {
+				timeout = nameCheckTimeout
+			}
+			ch := make(chan prometheus.Metric)
+			done := make(chan error, 1)
+			go func() {
+				done <- collector.Update(context.Background(), c, ch, timeout)
+				close(ch)
+			}()
+			seen := map[*prometheus.Desc]bool{}
+			for m := range ch {
+				seen[m.Desc()] = true
+			}
+			if err := <-done; err != nil {
+				log.Debugf("Collector %s failed, leaving it out of the metric name check: %s", name, err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for d := range seen {
+				descs[name] = append(descs[name], d)
+			}
+		}(name, c)
+	}
+	wg.Wait()
+
+	names := map[string]metricName{}
+	reserved := nodeCollectorDescs()
+	reserved = append(reserved, newBuildInfoDesc())
+	if *backgroundInterval > 0 {
+		reserved = append(reserved, newBackgroundUpdatedDesc())
+	}
+	for _, d := range reserved {
+		if err := addMetricName(names, "node_exporter", d); err != nil {
+			return err
+		}
+	}
+	var problems []string
+	for _, name := range sortedKeys(descs) {
+		for _, d := range descs[name] {
+			if err := addMetricName(names, name, d); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("inconsistent metrics: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// nodeCollectorDescs returns the descs of the duration and success metrics
+// a collector.NodeCollector adds to those of its collectors.
+func nodeCollectorDescs() []*prometheus.Desc {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		collector.NewNodeCollectorWith(nil, nil).Describe(ch)
+		close(ch)
+	}()
+	var descs []*prometheus.Desc
+	for d := range ch {
+		descs = append(descs, d)
+	}
+	return descs
+}
+
+// parseDesc returns the name, help string and variable label names of d.
+func parseDesc(d *prometheus.Desc) (fqName, help, labels string, err error) {
+	m := descPattern.FindStringSubmatch(d.String())
+	if m == nil {
+		return "", "", "", fmt.Errorf("couldn't parse %s", d)
+	}
+	if fqName, err = strconv.Unquote(m[1]); err != nil {
+		return "", "", "", fmt.Errorf("couldn't parse name of %s: %s", d, err)
+	}
+	if help, err = strconv.Unquote(m[2]); err != nil {
+		return "", "", "", fmt.Errorf("couldn't parse help of %s: %s", d, err)
+	}
+	return fqName, help, m[3], nil
+}
+
+// addMetricName records the name of d as exported by the named collector,
+// returning an error if it conflicts with what was recorded before.
+func addMetricName(names map[string]metricName, collector string, d *prometheus.Desc) error {
+	fqName, help, labels, err := parseDesc(d)
+	if err != nil {
+		return fmt.Errorf("%s: %s", collector, err)
+	}
+	current := metricName{collector: collector, help: help, labels: labels}
+	first, ok := names[fqName]
+	switch {
+	case !ok:
+		names[fqName] = current
+	case first.collector != collector:
+		return fmt.Errorf("%s exported by both %s and %s", fqName, first.collector, collector)
+	case first.help != help:
+		return fmt.Errorf("%s of %s has help strings %q and %q", fqName, collector, first.help, help)
+	case first.labels != current.labels:
+		return fmt.Errorf("%s of %s has labels [%s] and [%s]", fqName, collector, first.labels, current.labels)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string][]*prometheus.Desc) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
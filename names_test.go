@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
+	"golang.org/x/net/context"
+)
+
+// descCollector exports a metric of each desc.
+type descCollector []*prometheus.Desc
+
+func (c descCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	for _, d := range c {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, 1)
+	}
+	return nil
+}
+
+// badLabelCollector exports a metric whose desc doesn't parse.
+type badLabelCollector struct{}
+
+func (badLabelCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	ch <- prometheus.MustNewConstMetric(prometheus.NewDesc("node_a", "A.", []string{"bad]"}, nil), prometheus.GaugeValue, 1, "x")
+	return nil
+}
+
+func TestCheckMetricNames(t *testing.T) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(name, help, nil, nil)
+	}
+
+	for _, test := range []struct {
+		collectors map[string]collector.Collector
+		err        string
+	}{
+		{
+			collectors: map[string]collector.Collector{
+				"a": descCollector{desc("node_a", "A."), desc("node_a", "A.")},
+				"b": descCollector{desc("node_b", "B.")},
+			},
+		},
+		{
+			collectors: map[string]collector.Collector{
+				"a": descCollector{desc("node_x", "A.")},
+				"b": descCollector{desc("node_x", "B.")},
+			},
+			err: "node_x exported by both a and b",
+		},
+		{
+			collectors: map[string]collector.Collector{
+				"a": descCollector{desc("node_a", "A."), desc("node_a", "Other A.")},
+			},
+			err: `node_a of a has help strings`,
+		},
+		{
+			collectors: map[string]collector.Collector{
+				"a": descCollector{desc("node_scrape_collector_success", "A.")},
+			},
+			err: "node_scrape_collector_success exported by both node_exporter and a",
+		},
+		{
+			collectors: map[string]collector.Collector{
+				"a": badLabelCollector{},
+			},
+			err: "a: couldn't parse",
+		},
+	} {
+		err := checkMetricNames(test.collectors, nil)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("want no error, got %s", err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("want error %q, got %v", test.err, err)
+		}
+	}
+}
@@ -1,12 +1,11 @@
 package main
 
 import (
-	"encoding/json"
+	"crypto/tls"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"net"
 	"net/http"
-	_ "net/http/pprof"
 	"os"
 	"os/signal"
 	"strings"
@@ -14,9 +13,11 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/node_exporter/collector"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
 )
 
 const subsystem = "exporter"
@@ -24,16 +25,28 @@ const subsystem = "exporter"
 var (
 	configFile        = flag.String("config.file", "", "Path to config file.")
 	memProfile        = flag.String("debug.memprofile-file", "", "Write memory profile to this file upon receipt of SIGUSR1.")
-	listenAddress     = flag.String("web.listen-address", ":9100", "Address on which to expose metrics and web interface.")
 	metricsPath       = flag.String("web.telemetry-path", "/metrics", "Path under which to expose metrics.")
-	enabledCollectors = flag.String("collectors.enabled", "attributes,diskstats,filesystem,loadavg,meminfo,stat,textfile,time,netdev,netstat", "Comma-separated list of collectors to use.")
+	enabledCollectors = flag.String("collectors.enabled", strings.Join(collector.DefaultCollectors, ","), "Comma-separated list of collectors to use.")
 	printCollectors   = flag.Bool("collectors.print", false, "If true, print available collectors and exit.")
+	listCollectors    = flag.Bool("collectors.list", false, "If true, print all available collectors with their default state and description, and exit.")
+	maxConcurrency    = flag.Int("collectors.max-concurrency", 8, "Maximum number of collectors running at the same time during a scrape. 0 runs all at once.")
+	collectorsTimeout = flag.Duration("collectors.timeout", 0, "Time after which a collector is reported as failed and its metrics are dropped. Overridden per collector with the <name>_timeout config option. 0 disables the timeout.")
 	authUser          = flag.String("auth.user", "", "Username for basic auth.")
 	authPass          = flag.String("auth.pass", "", "Password for basic auth.")
+	authFile          = flag.String("auth.file", "", "Path to a file of user:bcrypt-hash lines for basic auth, as written by htpasswd -B.")
+	tlsCertFile       = flag.String("web.tls-cert-file", "", "Path to a PEM encoded server certificate. If set together with -web.tls-key-file, metrics are served over HTTPS.")
+	tlsKeyFile        = flag.String("web.tls-key-file", "", "Path to the PEM encoded private key of the server certificate.")
+	tlsClientCAFile   = flag.String("web.tls-client-ca-file", "", "Path to PEM encoded CA certificates. If set, clients must present a certificate signed by one of them.")
+	tlsAllowedCNs     = flag.String("web.tls-allowed-cns", "", "Comma-separated list of client certificate common names allowed to connect. Empty allows all verified clients.")
 
 	collectorLabelNames = []string{"collector", "result"}
 
-	scrapeDurations = prometheus.NewSummaryVec(
+	// scrapeDurations is set up by main, as the namespace is a flag.
+	scrapeDurations *prometheus.SummaryVec
+)
+
+func newScrapeDurations() *prometheus.SummaryVec {
+	return prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
 			Namespace: collector.Namespace,
 			Subsystem: subsystem,
@@ -42,101 +55,229 @@ var (
 		},
 		collectorLabelNames,
 	)
-)
+}
 
-// Implements Collector.
+// Implements Collector. It runs the collectors of a collector.NodeCollector
+// with the options of a scrape and the admin API switches, and keeps track
+// of their outcome.
 type NodeCollector struct {
-	collectors map[string]collector.Collector
+	// reloadMu serializes reloads.
+	reloadMu sync.Mutex
+	// mu is held for reading during a scrape, and for writing while the
+	// collectors are replaced.
+	mu   sync.RWMutex
+	node *collector.NodeCollector
+	// disabled holds the collectors turned off through the admin API.
+	disabled map[string]bool
+
+	// results holds the outcome of the last run of each collector.
+	resultsMu sync.Mutex
+	results   map[string]collectorResult
 }
 
 // Implements Collector.
-func (n NodeCollector) Describe(ch chan<- *prometheus.Desc) {
+func (n *NodeCollector) Describe(ch chan<- *prometheus.Desc) {
 	scrapeDurations.Describe(ch)
+	collector.NewNodeCollectorWith(nil, nil).Describe(ch)
+	ch <- newSuppressedErrorsDesc()
+	ch <- newBuildInfoDesc()
 }
 
-// Implements Collector.
-func (n NodeCollector) Collect(ch chan<- prometheus.Metric) {
+// Implements Collector, running all enabled collectors.
+func (n *NodeCollector) Collect(ch chan<- prometheus.Metric) {
+	n.collect(nil, ch)
+}
+
+// gather returns the metrics of a scrape with the options o.
+func (n *NodeCollector) gather(o *scrapeOptions) ([]*dto.MetricFamily, error) {
+	return collectFamilies(func(ch chan<- prometheus.Metric) { n.collect(o, ch) })
+}
+
+// collect runs the collectors included in the scrape with the options o.
+func (n *NodeCollector) collect(o *scrapeOptions, ch chan<- prometheus.Metric) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	flagsMu.RLock()
+	defer flagsMu.RUnlock()
+	// Running collectors hold a slot of workers, so that at most
+	// -collectors.max-concurrency of them run at once.
+	var workers chan struct{}
+	if *maxConcurrency > 0 {
+		workers = make(chan struct{}, *maxConcurrency)
+	}
+	// The collectors of a scrape share the /proc files they read.
+	ctx := collector.WithScrapeCache(o.scrapeContext())
 	wg := sync.WaitGroup{}
-	wg.Add(len(n.collectors))
-	for name, c := range n.collectors {
-		go func(name string, c collector.Collector) {
-			Execute(name, c, ch)
-			wg.Done()
-		}(name, c)
+	for _, name := range n.node.Names() {
+		if n.disabled[name] || !o.includes(name) {
+			continue
+		}
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if workers != nil {
+				workers <- struct{}{}
+				defer func() { <-workers }()
+			}
+			n.execute(ctx, name, o.timeout(n.node.Timeout(name)), ch)
+		}(name)
 	}
 	wg.Wait()
 	scrapeDurations.Collect(ch)
+	collectorErrors.collect(ch)
+	ch <- buildInfo()
 }
 
-type basicAuthHandler struct {
-	handler  http.HandlerFunc
-	user     string
-	password string
+// names returns the sorted names of the enabled collectors, leaving out
+// those disabled through the admin API.
+func (n *NodeCollector) names() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	names := []string{}
+	for _, name := range n.node.Names() {
+		if !n.disabled[name] {
+			names = append(names, name)
+		}
+	}
+	return names
 }
 
-func (h *basicAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	user, password, ok := r.BasicAuth()
-	if !ok || password != h.password || user != h.user {
-		w.Header().Set("WWW-Authenticate", "Basic realm=\"metrics\"")
-		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
-		return
+// setResult records a run of a collector started at begin, keeping the
+// time of its last success.
+func (n *NodeCollector) setResult(name string, begin time.Time, err error) {
+	n.resultsMu.Lock()
+	defer n.resultsMu.Unlock()
+	if n.results == nil {
+		n.results = map[string]collectorResult{}
 	}
-	h.handler(w, r)
-	return
+	r := n.results[name]
+	r.duration, r.err = time.Since(begin), err
+	if err == nil {
+		r.lastSuccess = begin
+	}
+	n.results[name] = r
 }
 
-func Execute(name string, c collector.Collector, ch chan<- prometheus.Metric) {
-	begin := time.Now()
-	err := c.Update(ch)
-	duration := time.Since(begin)
-	var result string
+// lastFailures returns the errors of the collectors that failed in their
+// last run.
+func (n *NodeCollector) lastFailures() map[string]error {
+	n.resultsMu.Lock()
+	defer n.resultsMu.Unlock()
+	failures := map[string]error{}
+	for name, r := range n.results {
+		if r.err != nil {
+			failures[name] = r.err
+		}
+	}
+	return failures
+}
 
-	if err != nil {
-		glog.Infof("ERROR: %s failed after %fs: %s", name, duration.Seconds(), err)
-		result = "error"
-	} else {
-		glog.Infof("OK: %s success after %fs.", name, duration.Seconds())
-		result = "success"
+// setCollectors replaces the collectors once running scrapes are done, and
+// returns the replaced ones.
+func (n *NodeCollector) setCollectors(collectors map[string]collector.Collector, timeouts map[string]time.Duration) map[string]collector.Collector {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var old map[string]collector.Collector
+	if n.node != nil {
+		old = n.node.Collectors()
 	}
-	scrapeDurations.WithLabelValues(name, result).Observe(duration.Seconds())
+	n.node = collector.NewNodeCollectorWith(collectors, timeouts)
+	n.resultsMu.Lock()
+	n.results = nil
+	n.resultsMu.Unlock()
+	return old
 }
 
-func getConfig(file string) (*collector.Config, error) {
-	config := &collector.Config{}
-	glog.Infof("Reading config %s", *configFile)
-	bytes, err := ioutil.ReadFile(*configFile)
+// execute runs the named collector within the timeout, and logs and records
+// its outcome.
+func (n *NodeCollector) execute(ctx context.Context, name string, timeout time.Duration, ch chan<- prometheus.Metric) error {
+	begin := time.Now()
+	duration, err := n.node.Execute(ctx, name, ch, timeout)
+	result := "success"
 	if err != nil {
-		return nil, err
+		collectorErrors.failure(name, duration, err, *errorLogInterval)
+		result = "error"
+	} else {
+		collectorErrors.success(name)
+		log.With("collector", name, "duration_seconds", duration.Seconds()).Debugf("Collector succeeded")
 	}
-	return config, json.Unmarshal(bytes, &config)
+	scrapeDurations.WithLabelValues(name, result).Observe(duration.Seconds())
+	recordCollectorVars(name, duration, err)
+	n.setResult(name, begin, err)
+	return err
 }
 
-func loadCollectors(file string) (map[string]collector.Collector, error) {
+// newCollectors sets up the enabled collectors and reads their timeouts,
+// without running or wrapping them.
+func newCollectors(config collector.Config) (map[string]collector.Collector, map[string]time.Duration, error) {
 	collectors := map[string]collector.Collector{}
-	config := &collector.Config{}
-	if file != "" {
-		var err error
-		config, err = getConfig(file)
-		if err != nil {
-			return nil, fmt.Errorf("couldn't read config %s: %s", file, err)
-		}
-	}
-	for _, name := range strings.Split(*enabledCollectors, ",") {
+	timeouts := map[string]time.Duration{}
+	for _, name := range enabledCollectorNames() {
 		fn, ok := collector.Factories[name]
 		if !ok {
-			return nil, fmt.Errorf("collector '%s' not available", name)
+			closeCollectors(collectors)
+			return nil, nil, fmt.Errorf("collector '%s' not available", name)
+		}
+		c, err := fn(config)
+		if collector.IsNotApplicable(err) {
+			log.Infof("Leaving out collector %s: %s", name, err)
+			continue
 		}
-		c, err := fn(*config)
 		if err != nil {
-			return nil, err
+			closeCollectors(collectors)
+			return nil, nil, err
 		}
 		collectors[name] = c
+		timeouts[name], err = config.Timeout(name, *collectorsTimeout)
+		if err != nil {
+			closeCollectors(collectors)
+			return nil, nil, err
+		}
+	}
+	return collectors, timeouts, nil
+}
+
+// loadCollectors sets up the enabled collectors for serving: cached, checked
+// for invalid metric names and run in the background as the flags ask.
+func loadCollectors(config collector.Config) (map[string]collector.Collector, map[string]time.Duration, error) {
+	collectors, timeouts, err := newCollectors(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if *cacheTTL > 0 {
+		for name, c := range collectors {
+			collectors[name] = newCachingCollector(c, *cacheTTL)
+		}
+	}
+	if *checkNames {
+		if err := checkMetricNames(collectors, timeouts); err != nil {
+			closeCollectors(collectors)
+			return nil, nil, err
+		}
 	}
-	return collectors, nil
+	if *backgroundInterval > 0 {
+		for name, c := range collectors {
+			collectors[name] = newBackgroundCollector(name, c, *backgroundInterval, timeouts[name])
+		}
+	}
+	return collectors, timeouts, nil
 }
 
 func main() {
 	flag.Parse()
+	if *printVersion {
+		writeVersion(os.Stdout)
+		return
+	}
+	if err := setupLogging(); err != nil {
+		log.Fatal(err)
+	}
+	if err := loadPlugins(); err != nil {
+		log.Fatal(err)
+	}
+	if flag.Arg(0) == "check-config" {
+		os.Exit(runCheckConfig(os.Stdout, os.Stderr))
+	}
 	if *printCollectors {
 		fmt.Printf("Available collectors:\n")
 		for n, _ := range collector.Factories {
@@ -144,46 +285,220 @@ func main() {
 		}
 		return
 	}
-	collectors, err := loadCollectors(*configFile)
+	if *listCollectors {
+		writeCollectorList(os.Stdout)
+		return
+	}
+	config, err := applyConfig(*configFile, false)
+	if err != nil {
+		log.Fatalf("Couldn't read config %s: %s", *configFile, err)
+	}
+	scrapeDurations = newScrapeDurations()
+	collectors, timeouts, err := loadCollectors(config)
 	if err != nil {
-		glog.Fatalf("Couldn't load config and collectors: %s", err)
+		log.Fatalf("Couldn't load config and collectors: %s", err)
 	}
 
-	glog.Infof("Enabled collectors:")
+	log.Infof("Enabled collectors:")
 	for n, _ := range collectors {
-		glog.Infof(" - %s", n)
+		log.Infof(" - %s", n)
+	}
+
+	nodeCollector := &NodeCollector{
+		node:     collector.NewNodeCollectorWith(collectors, timeouts),
+		disabled: map[string]bool{},
 	}
+	unregisterExporterMetrics()
+	setReady(true)
 
-	nodeCollector := NodeCollector{collectors: collectors}
-	prometheus.MustRegister(nodeCollector)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := nodeCollector.reload(); err != nil {
+				log.Errorf("Couldn't reload config: %s", err)
+			}
+		}
+	}()
 
 	sigUsr1 := make(chan os.Signal)
 	signal.Notify(sigUsr1, syscall.SIGUSR1)
 
-	handler := prometheus.Handler()
-	if *authUser != "" || *authPass != "" {
-		if *authUser == "" || *authPass == "" {
-			glog.Fatal("You need to specify -auth.user and -auth.pass to enable basic auth")
-		}
-		handler = &basicAuthHandler{
-			handler:  prometheus.Handler().ServeHTTP,
-			user:     *authUser,
-			password: *authPass,
-		}
-	}
-
-	http.Handle(*metricsPath, handler)
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`<html>
-			<head><title>Node Exporter</title></head>
-			<body>
-			<h1>Node Exporter</h1>
-			<p><a href="` + *metricsPath + `">Metrics</a></p>
-			</body>
-			</html>`))
-	})
-	err = http.ListenAndServe(*listenAddress, nil)
+	scrapes := &scrapeHandler{
+		handler:    prometheus.Handler(),
+		gather:     nodeCollector.gather,
+		collectors: nodeCollector.names,
+	}
+	if *dryRun {
+		out := os.Stdout
+		if *dryRunOutput != "" {
+			out, err = os.Create(*dryRunOutput)
+			if err != nil {
+				log.Fatalf("Couldn't create %s: %s", *dryRunOutput, err)
+			}
+		}
+		err := runDryRun(nodeCollector, scrapes, out, os.Stderr)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		closeCollectors(nodeCollector.setCollectors(nil, nil))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var handler http.Handler = openMetricsHandler{handler: scrapes}
+	handler = gzipHandler{handler: handler, disabled: *disableCompression}
+	withAuth, err := basicAuth()
 	if err != nil {
-		glog.Fatal(err)
+		log.Fatal(err)
+	}
+	handler = withAuth(handler)
+
+	jsonMetrics := withAuth(gzipHandler{handler: jsonHandler{handler: scrapes}, disabled: *disableCompression})
+	if *scrapeMinInterval > 0 {
+		// Both endpoints count towards the same limit.
+		limiter := newScrapeLimiter(*scrapeMinInterval)
+		handler = rateLimitHandler{handler: handler, limiter: limiter}
+		jsonMetrics = rateLimitHandler{handler: jsonMetrics, limiter: limiter}
+	}
+	if *accessLog {
+		handler = accessLogHandler{handler: handler}
+		jsonMetrics = accessLogHandler{handler: jsonMetrics}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(*metricsPath, handler)
+	mux.Handle(*metricsPath+".json", jsonMetrics)
+	if *enableLifecycle {
+		mux.Handle("/-/reload", withAuth(reloadHandler{reload: nodeCollector.reload}))
+	}
+	if *enableAdminAPI {
+		if err := checkAdminAPI(); err != nil {
+			log.Fatal(err)
+		}
+		admin := withAuth(adminHandler{collectors: nodeCollector})
+		mux.Handle(adminAPIPrefix, admin)
+		mux.Handle(adminAPIPrefix+"/", admin)
+	}
+	mux.Handle("/", newLandingPage(nodeCollector.names))
+	mux.Handle("/status", withAuth(statusHandler{collectors: nodeCollector}))
+	mux.HandleFunc("/-/healthy", healthyHandler)
+	mux.HandleFunc("/-/ready", readyHandler)
+	if *enableExpvar {
+		mux.Handle("/debug/vars", withAuth(http.HandlerFunc(expvarHandler)))
+	}
+	if *enablePprof {
+		registerPprof(mux, withAuth)
+	}
+	server := &http.Server{}
+	server.TLSConfig, err = serverTLSConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *tlsAllowedCNs != "" {
+		server.Handler = newCNAllowlistHandler(mux, strings.Split(*tlsAllowedCNs, ","))
+	}
+
+	if server.Handler == nil {
+		server.Handler = mux
+	}
+	if *allowedNetworks != "" {
+		server.Handler, err = newIPAllowlistHandler(server.Handler, *allowedNetworks)
+		if err != nil {
+			log.Fatalf("Couldn't parse -web.allowed-networks: %s", err)
+		}
+	}
+	inflight := &inflightHandler{handler: server.Handler}
+	server.Handler = inflight
+
+	stopSinks := make(chan struct{})
+	pushing := false
+	if *pushGateway != "" {
+		p, err := newPusher(*pushGateway, *pushJob, *pushInstance)
+		if err != nil {
+			log.Fatalf("Couldn't set up Pushgateway push: %s", err)
+		}
+		runSink("Pushgateway", *pushInterval, scrapes, stopSinks, p.push)
+		pushing = true
+	}
+	if *remoteWriteURL != "" {
+		w, err := newRemoteWriter(stopSinks)
+		if err != nil {
+			log.Fatalf("Couldn't set up remote_write: %s", err)
+		}
+		runSink("remote_write", *remoteWriteInterval, scrapes, stopSinks, func(families []*dto.MetricFamily) error {
+			return w.write(flattenSamples(families), time.Now())
+		})
+		pushing = true
+	}
+	if *graphiteAddress != "" {
+		g := graphiteSender{address: *graphiteAddress, prefix: *graphitePrefix, timeout: *graphiteInterval}
+		runSink("Graphite", *graphiteInterval, scrapes, stopSinks, func(families []*dto.MetricFamily) error {
+			return g.send(flattenSamples(families), time.Now())
+		})
+		pushing = true
+	}
+	if *influxDBURL != "" {
+		i, err := newInfluxDBSender(*influxDBURL, *influxDBInterval)
+		if err != nil {
+			log.Fatalf("Couldn't set up InfluxDB output: %s", err)
+		}
+		runSink("InfluxDB", *influxDBInterval, scrapes, stopSinks, func(families []*dto.MetricFamily) error {
+			return i.send(flattenSamples(families), time.Now())
+		})
+		pushing = true
+	}
+	if *statsdAddress != "" {
+		s, err := newStatsdSender(*statsdAddress, *statsdPrefix, *statsdLabelMapping, *statsdInterval)
+		if err != nil {
+			log.Fatalf("Couldn't set up StatsD output: %s", err)
+		}
+		runSink("StatsD", *statsdInterval, scrapes, stopSinks, func(families []*dto.MetricFamily) error {
+			return s.send(flattenSamples(families))
+		})
+		pushing = true
+	}
+
+	listeners, err := listen()
+	if err != nil {
+		log.Fatalf("Couldn't listen: %s", err)
+	}
+	if len(listeners) == 0 && !pushing {
+		log.Fatal("Neither -web.listen-address, -web.listen-socket nor a push mode set")
+	}
+	errc := make(chan error, len(listeners))
+	for i, l := range listeners {
+		if server.TLSConfig != nil {
+			l = tls.NewListener(l, server.TLSConfig)
+			listeners[i] = l
+		}
+		log.Infof("Listening on %s", l.Addr())
+		go func(l net.Listener) {
+			errc <- server.Serve(l)
+		}(l)
+	}
+
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, syscall.SIGTERM, syscall.SIGINT)
+	select {
+	case err := <-errc:
+		log.Fatal(err)
+	case sig := <-term:
+		log.Infof("Received %s, shutting down", sig)
+	}
+	setReady(false)
+	close(stopSinks)
+	server.SetKeepAlivesEnabled(false)
+	closeListeners(listeners)
+	if !inflight.wait(*shutdownTimeout) {
+		// The running scrapes still use the collectors, so they are left
+		// open.
+		log.Warnf("Scrapes still running after %s, exiting anyway", *shutdownTimeout)
+		return
 	}
+	sinks.Wait()
+	closeCollectors(nodeCollector.setCollectors(nil, nil))
 }
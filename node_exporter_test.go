@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
+	"golang.org/x/net/context"
+)
+
+type errorCollector struct {
+	err error
+}
+
+func (c errorCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	return c.err
+}
+
+type concurrencyCollector struct {
+	mu            *sync.Mutex
+	running, peak *int
+}
+
+func (c concurrencyCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	c.mu.Lock()
+	*c.running++
+	if *c.running > *c.peak {
+		*c.peak = *c.running
+	}
+	c.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	c.mu.Lock()
+	*c.running--
+	c.mu.Unlock()
+	return nil
+}
+
+func TestCollectMaxConcurrency(t *testing.T) {
+	scrapeDurations = newScrapeDurations()
+	defer func(old int) { *maxConcurrency = old }(*maxConcurrency)
+	*maxConcurrency = 2
+
+	var (
+		mu            sync.Mutex
+		running, peak int
+	)
+	collectors := map[string]collector.Collector{}
+	for i := 0; i < 6; i++ {
+		collectors[fmt.Sprintf("c%d", i)] = concurrencyCollector{mu: &mu, running: &running, peak: &peak}
+	}
+	n := &NodeCollector{node: collector.NewNodeCollectorWith(collectors, nil)}
+
+	ch := make(chan prometheus.Metric)
+	done := make(chan int)
+	go func() {
+		count := 0
+		for range ch {
+			count++
+		}
+		done <- count
+	}()
+	n.Collect(ch)
+	close(ch)
+
+	// The duration and success metrics of each collector.
+	if want, got := 2*len(collectors), <-done; got < want {
+		t.Errorf("want at least %d metrics, got %d", want, got)
+	}
+	if peak != 2 {
+		t.Errorf("want 2 collectors running at once, got %d", peak)
+	}
+}
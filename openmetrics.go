@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/node_exporter/log"
+)
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// Units exported as UNIT metadata of metric families ending in _<unit>.
+var openMetricsUnits = []string{"seconds", "bytes", "celsius", "ratio", "volts", "amperes", "joules", "hertz", "meters", "grams"}
+
+// openMetricsHandler serves the OpenMetrics format to clients asking for it
+// and passes all other requests on to the wrapped handler.
+type openMetricsHandler struct {
+	handler http.Handler
+}
+
+func (h openMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !acceptsOpenMetrics(r.Header.Get("Accept")) {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+	families, err := gather(h.handler, r)
+	if err != nil {
+		log.Errorf("Couldn't gather metrics: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", openMetricsContentType)
+	if err := writeOpenMetrics(w, families); err != nil {
+		log.Errorf("Couldn't write OpenMetrics: %s", err)
+	}
+}
+
+func acceptsOpenMetrics(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		if strings.TrimSpace(strings.Split(part, ";")[0]) == "application/openmetrics-text" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOpenMetrics writes the families in the OpenMetrics text format.
+// Counter families are named without the _total suffix carried by their
+// samples, and unknown replaces untyped.
+func writeOpenMetrics(out io.Writer, families []*dto.MetricFamily) error {
+	w := bufio.NewWriter(out)
+	for _, mf := range families {
+		name, typ := mf.GetName(), strings.ToLower(mf.GetType().String())
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			name = strings.TrimSuffix(name, "_total")
+		case dto.MetricType_UNTYPED:
+			typ = "unknown"
+		}
+
+		w.WriteString("# TYPE " + name + " " + typ + "\n")
+		if unit := openMetricsUnit(name); unit != "" {
+			w.WriteString("# UNIT " + name + " " + unit + "\n")
+		}
+		if mf.GetHelp() != "" {
+			w.WriteString("# HELP " + name + " " + escapeOpenMetrics(mf.GetHelp()) + "\n")
+		}
+		for _, m := range mf.Metric {
+			writeOpenMetricsSamples(w, name, m)
+		}
+	}
+	w.WriteString("# EOF\n")
+	return w.Flush()
+}
+
+func writeOpenMetricsSamples(w *bufio.Writer, name string, m *dto.Metric) {
+	switch {
+	case m.Counter != nil:
+		writeSample(w, name+"_total", m, "", "", m.Counter.GetValue())
+	case m.Gauge != nil:
+		writeSample(w, name, m, "", "", m.Gauge.GetValue())
+	case m.Summary != nil:
+		for _, q := range m.Summary.GetQuantile() {
+			writeSample(w, name, m, "quantile", formatOpenMetricsFloat(q.GetQuantile()), q.GetValue())
+		}
+		writeSample(w, name+"_sum", m, "", "", m.Summary.GetSampleSum())
+		writeSample(w, name+"_count", m, "", "", float64(m.Summary.GetSampleCount()))
+	case m.Histogram != nil:
+		inf := false
+		for _, b := range m.Histogram.GetBucket() {
+			inf = inf || math.IsInf(b.GetUpperBound(), 1)
+			writeSample(w, name+"_bucket", m, "le", formatOpenMetricsFloat(b.GetUpperBound()), float64(b.GetCumulativeCount()))
+		}
+		if !inf {
+			writeSample(w, name+"_bucket", m, "le", "+Inf", float64(m.Histogram.GetSampleCount()))
+		}
+		writeSample(w, name+"_sum", m, "", "", m.Histogram.GetSampleSum())
+		writeSample(w, name+"_count", m, "", "", float64(m.Histogram.GetSampleCount()))
+	default:
+		writeSample(w, name, m, "", "", m.Untyped.GetValue())
+	}
+}
+
+// writeSample writes one sample line of m, with an optional extra label like
+// quantile or le, and the timestamp of m in seconds if it has one. Exemplars
+// would be appended to the line here once the client library provides them.
+func writeSample(w *bufio.Writer, name string, m *dto.Metric, extraName, extraValue string, value float64) {
+	labels := m.Label
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l.GetName() + `="` + escapeOpenMetrics(l.GetValue()) + `"`)
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteString(" " + formatOpenMetricsFloat(value))
+	if m.TimestampMs != nil {
+		w.WriteString(" " + strconv.FormatFloat(float64(m.GetTimestampMs())/1000, 'f', -1, 64))
+	}
+	w.WriteByte('\n')
+}
+
+func openMetricsUnit(name string) string {
+	for _, unit := range openMetricsUnits {
+		if strings.HasSuffix(name, "_"+unit) {
+			return unit
+		}
+	}
+	return ""
+}
+
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeOpenMetrics(s string) string {
+	return openMetricsEscaper.Replace(s)
+}
+
+func formatOpenMetricsFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
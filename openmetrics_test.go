@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.google.com/p/goprotobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const testExposition = `# HELP node_cpu Seconds the cpus spent in each mode.
+# TYPE node_cpu counter
+node_cpu{cpu="cpu0",mode="idle"} 362812.7890625
+# HELP node_filesystem_size Filesystem size in bytes.
+# TYPE node_filesystem_size gauge
+node_filesystem_size{filesystem="/"} 1.0619203584e+10
+# HELP node_exporter_scrape_duration_seconds node_exporter: Duration of a scrape job.
+# TYPE node_exporter_scrape_duration_seconds summary
+node_exporter_scrape_duration_seconds{collector="stat",result="success",quantile="0.5"} 0.00023
+node_exporter_scrape_duration_seconds_sum{collector="stat",result="success"} 0.0046
+node_exporter_scrape_duration_seconds_count{collector="stat",result="success"} 20
+# HELP node_textfile_info A "quoted" help.
+node_textfile_info{role="web\\server"} 1
+`
+
+const testOpenMetrics = `# TYPE node_cpu counter
+# HELP node_cpu Seconds the cpus spent in each mode.
+node_cpu_total{cpu="cpu0",mode="idle"} 362812.7890625
+# TYPE node_exporter_scrape_duration_seconds summary
+# UNIT node_exporter_scrape_duration_seconds seconds
+# HELP node_exporter_scrape_duration_seconds node_exporter: Duration of a scrape job.
+node_exporter_scrape_duration_seconds{collector="stat",result="success",quantile="0.5"} 0.00023
+node_exporter_scrape_duration_seconds_sum{collector="stat",result="success"} 0.0046
+node_exporter_scrape_duration_seconds_count{collector="stat",result="success"} 20
+# TYPE node_filesystem_size gauge
+# HELP node_filesystem_size Filesystem size in bytes.
+node_filesystem_size{filesystem="/"} 1.0619203584e+10
+# TYPE node_textfile_info unknown
+# HELP node_textfile_info A \"quoted\" help.
+node_textfile_info{role="web\\server"} 1
+# EOF
+`
+
+func TestOpenMetricsHandler(t *testing.T) {
+	h := openMetricsHandler{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testExposition))
+	})}
+
+	r, _ := http.NewRequest("GET", "/metrics", nil)
+	r.Header.Set("Accept", "application/openmetrics-text; version=1.0.0,text/plain;version=0.0.4;q=0.5")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if want, got := openMetricsContentType, w.Header().Get("Content-Type"); want != got {
+		t.Errorf("want content type %q, got %q", want, got)
+	}
+	if want, got := testOpenMetrics, w.Body.String(); want != got {
+		t.Errorf("want OpenMetrics:\n%s\ngot:\n%s", want, got)
+	}
+
+	r, _ = http.NewRequest("GET", "/metrics", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if want, got := testExposition, w.Body.String(); want != got {
+		t.Errorf("want text format passed through, got:\n%s", got)
+	}
+}
+
+func TestOpenMetricsTimestamp(t *testing.T) {
+	families := []*dto.MetricFamily{{
+		Name: proto.String("node_textfile_info"),
+		Type: dto.MetricType_GAUGE.Enum(),
+		Metric: []*dto.Metric{
+			{Gauge: &dto.Gauge{Value: proto.Float64(1)}, TimestampMs: proto.Int64(1395066363123)},
+			{Gauge: &dto.Gauge{Value: proto.Float64(2)}, TimestampMs: proto.Int64(1395066363000)},
+			{Gauge: &dto.Gauge{Value: proto.Float64(3)}},
+		},
+	}}
+	var buf bytes.Buffer
+	if err := writeOpenMetrics(&buf, families); err != nil {
+		t.Fatal(err)
+	}
+	want := `# TYPE node_textfile_info gauge
+node_textfile_info 1 1395066363.123
+node_textfile_info 2 1395066363
+node_textfile_info 3
+# EOF
+`
+	if got := buf.String(); want != got {
+		t.Errorf("want OpenMetrics:\n%s\ngot:\n%s", want, got)
+	}
+}
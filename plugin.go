@@ -0,0 +1,69 @@
+// +build go1.8,linux,cgo
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"plugin"
+
+	"github.com/prometheus/node_exporter/collector"
+	"github.com/prometheus/node_exporter/log"
+)
+
+var pluginDir = flag.String("collector.plugin-dir", "", "Directory of Go plugins (.so files) adding collectors through an exported Register function.")
+
+// pluginRegisterFunc is the type of the Register function plugins export. It
+// adds the collectors of the plugin to the factories.
+type pluginRegisterFunc func(factories map[string]func(collector.Config) (collector.Collector, error))
+
+// loadPlugins opens all plugins in -collector.plugin-dir and registers their
+// collectors, to be enabled like built-in ones.
+func loadPlugins() error {
+	if *pluginDir == "" {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(*pluginDir, "*.so"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		p, err := plugin.Open(file)
+		if err != nil {
+			return fmt.Errorf("couldn't open plugin %s: %s", file, err)
+		}
+		sym, err := p.Lookup("Register")
+		if err != nil {
+			return fmt.Errorf("couldn't load plugin %s: %s", file, err)
+		}
+		if err := registerPlugin(sym, collector.Factories); err != nil {
+			return fmt.Errorf("couldn't load plugin %s: %s", file, err)
+		}
+		log.Infof("Loaded plugin %s", file)
+	}
+	return nil
+}
+
+func registerPlugin(sym interface{}, factories map[string]func(collector.Config) (collector.Collector, error)) error {
+	var register pluginRegisterFunc
+	switch fn := sym.(type) {
+	case func(map[string]func(collector.Config) (collector.Collector, error)):
+		register = fn
+	case *pluginRegisterFunc:
+		register = *fn
+	default:
+		return fmt.Errorf("Register is a %T, not a %T", sym, register)
+	}
+
+	// Keep plugins from replacing built-in or other plugins' collectors.
+	added := map[string]func(collector.Config) (collector.Collector, error){}
+	register(added)
+	for name, factory := range added {
+		if _, ok := factories[name]; ok {
+			return fmt.Errorf("collector %s already registered", name)
+		}
+		factories[name] = factory
+	}
+	return nil
+}
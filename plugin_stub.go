@@ -0,0 +1,9 @@
+// +build !go1.8 !linux !cgo
+
+package main
+
+// loadPlugins does nothing, as plugins need Go 1.8 or later on Linux with
+// cgo.
+func loadPlugins() error {
+	return nil
+}
@@ -0,0 +1,31 @@
+// +build go1.8,linux,cgo
+
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/node_exporter/collector"
+)
+
+func TestRegisterPlugin(t *testing.T) {
+	factories := map[string]func(collector.Config) (collector.Collector, error){
+		"stat": nil,
+	}
+	register := func(f map[string]func(collector.Config) (collector.Collector, error)) {
+		f["raid"] = func(collector.Config) (collector.Collector, error) { return nil, nil }
+	}
+	if err := registerPlugin(register, factories); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := factories["raid"]; !ok {
+		t.Error("plugin collector not registered")
+	}
+
+	if err := registerPlugin(register, factories); err == nil {
+		t.Error("want error for collector registered twice")
+	}
+	if err := registerPlugin(func() {}, factories); err == nil {
+		t.Error("want error for Register of the wrong type")
+	}
+}
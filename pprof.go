@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/http/pprof"
+)
+
+var enablePprof = flag.Bool("web.enable-pprof", false, "If true, serve the net/http/pprof profiling endpoints under /debug/pprof/.")
+
+// registerPprof adds the profiling endpoints to mux. Importing net/http/pprof
+// also registers them on http.DefaultServeMux, which is why the exporter
+// serves its own mux.
+func registerPprof(mux *http.ServeMux, withAuth func(http.Handler) http.Handler) {
+	mux.Handle("/debug/pprof/", withAuth(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", withAuth(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", withAuth(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", withAuth(http.HandlerFunc(pprof.Symbol)))
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterPprof(t *testing.T) {
+	mux := http.NewServeMux()
+	registerPprof(mux, func(h http.Handler) http.Handler { return h })
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/cmdline"} {
+		r, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: want status %d, got %d", path, http.StatusOK, w.Code)
+		}
+	}
+}
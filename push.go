@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/text"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	pushGateway  = flag.String("push.gateway-url", "", "URL of a Pushgateway to push all metrics to periodically, e.g. http://pushgateway:9091.")
+	pushInterval = flag.Duration("push.interval", time.Minute, "Interval between pushes to the Pushgateway.")
+	pushJob      = flag.String("push.job", "node", "Job label of the pushed metrics.")
+	pushInstance = flag.String("push.instance", "", "Instance label of the pushed metrics. Defaults to the hostname.")
+)
+
+// pusher replaces the metrics of a job and instance on a Pushgateway.
+type pusher struct {
+	url    string
+	client *http.Client
+}
+
+func newPusher(gateway, job, instance string) (*pusher, error) {
+	if instance == "" {
+		var err error
+		if instance, err = os.Hostname(); err != nil {
+			return nil, err
+		}
+	}
+	if !strings.Contains(gateway, "://") {
+		gateway = "http://" + gateway
+	}
+	return &pusher{
+		url:    strings.TrimRight(gateway, "/") + "/metrics/job/" + url.QueryEscape(job) + "/instance/" + url.QueryEscape(instance),
+		client: &http.Client{Timeout: *pushInterval},
+	}, nil
+}
+
+func (p *pusher) push(families []*dto.MetricFamily) error {
+	var buf bytes.Buffer
+	for _, mf := range families {
+		if _, err := text.MetricFamilyToText(&buf, mf); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest("PUT", p.url, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, body)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/text"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestPusher(t *testing.T) {
+	var method, path, body string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(b)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer gateway.Close()
+
+	var parser text.Parser
+	families, err := parser.TextToMetricFamilies(strings.NewReader("# TYPE node_load1 gauge\nnode_load1 0.21\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newPusher(gateway.URL+"/", "node", "edge-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.push([]*dto.MetricFamily{families["node_load1"]}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "PUT", method; want != got {
+		t.Errorf("want method %s, got %s", want, got)
+	}
+	if want, got := "/metrics/job/node/instance/edge-1", path; want != got {
+		t.Errorf("want path %s, got %s", want, got)
+	}
+	if !strings.Contains(body, "node_load1 0.21\n") {
+		t.Errorf("pushed body doesn't contain node_load1:\n%s", body)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/node_exporter/log"
+)
+
+// statusTooManyRequests is 429 Too Many Requests, which net/http only names
+// from Go 1.6 on.
+const statusTooManyRequests = 429
+
+var scrapeMinInterval = flag.Duration("web.scrape-min-interval", 0, "Minimum time between two scrapes of the metrics endpoint by the same client address. Clients scraping more often get 429 Too Many Requests. 0 turns the limit off.")
+
+// scrapeLimiter keeps track of the last scrape of each client address.
+type scrapeLimiter struct {
+	interval time.Duration
+
+	mu sync.Mutex
+	// last holds the time of the last scrape let through of each client.
+	last map[string]time.Time
+}
+
+func newScrapeLimiter(interval time.Duration) *scrapeLimiter {
+	return &scrapeLimiter{interval: interval, last: map[string]time.Time{}}
+}
+
+// allow records a scrape by client at now if its last one is at least the
+// interval ago, and otherwise returns how long the client has to wait.
+func (l *scrapeLimiter) allow(client string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if last, ok := l.last[client]; ok {
+		if wait := l.interval - now.Sub(last); wait > 0 {
+			return wait
+		}
+	}
+	l.last[client] = now
+	// Forget the clients free to scrape again, so that the map doesn't grow
+	// with every address seen.
+	if len(l.last) > 1024 {
+		for c, t := range l.last {
+			if now.Sub(t) >= l.interval {
+				delete(l.last, c)
+			}
+		}
+	}
+	return 0
+}
+
+// rateLimitHandler answers the requests of clients scraping more often than
+// the limiter allows with 429 and a Retry-After header.
+type rateLimitHandler struct {
+	handler http.Handler
+	limiter *scrapeLimiter
+}
+
+func (h rateLimitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client := clientIP(r)
+	if wait := h.limiter.allow(client, time.Now()); wait > 0 {
+		log.With("client", client, "retry_after_seconds", wait.Seconds()).Debugf("Scrape rate limited")
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many scrapes, at most one per "+h.limiter.interval.String()+" allowed", statusTooManyRequests)
+		return
+	}
+	h.handler.ServeHTTP(w, r)
+}
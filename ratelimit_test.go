@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScrapeLimiter(t *testing.T) {
+	l := newScrapeLimiter(10 * time.Second)
+	begin := time.Now()
+	for _, c := range []struct {
+		client string
+		after  time.Duration
+		wait   time.Duration
+	}{
+		{"192.0.2.1", 0, 0},
+		{"192.0.2.2", time.Second, 0},
+		{"192.0.2.1", 4 * time.Second, 6 * time.Second},
+		{"192.0.2.1", 10 * time.Second, 0},
+		{"192.0.2.2", 10 * time.Second, time.Second},
+	} {
+		if got := l.allow(c.client, begin.Add(c.after)); got != c.wait {
+			t.Errorf("%s after %s: want wait %s, got %s", c.client, c.after, c.wait, got)
+		}
+	}
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	h := rateLimitHandler{
+		handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		limiter: newScrapeLimiter(time.Minute),
+	}
+	for _, c := range []struct {
+		remoteAddr string
+		code       int
+	}{
+		{"192.0.2.1:40000", http.StatusOK},
+		{"192.0.2.1:40001", statusTooManyRequests},
+		{"192.0.2.2:40000", http.StatusOK},
+	} {
+		r, _ := http.NewRequest("GET", "/metrics", nil)
+		r.RemoteAddr = c.remoteAddr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != c.code {
+			t.Errorf("%s: want status %d, got %d", c.remoteAddr, c.code, w.Code)
+		}
+		if c.code == statusTooManyRequests && w.Header().Get("Retry-After") != "60" {
+			t.Errorf("%s: want Retry-After 60, got %q", c.remoteAddr, w.Header().Get("Retry-After"))
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/prometheus/node_exporter/log"
+)
+
+var enableLifecycle = flag.Bool("web.enable-lifecycle", false, "Reload the config file on POST requests to /-/reload.")
+
+// reload re-reads the config file and replaces the collectors with ones set
+// up from it. The old collectors are kept if that fails. The flags set by
+// the config change between collector runs, and the new collectors are
+// swapped in once the running scrapes are done.
+func (n *NodeCollector) reload() error {
+	n.reloadMu.Lock()
+	defer n.reloadMu.Unlock()
+	config, err := applyConfig(*configFile, true)
+	if err != nil {
+		return err
+	}
+	collectors, timeouts, err := loadCollectors(config)
+	if err != nil {
+		return err
+	}
+	closeCollectors(n.setCollectors(collectors, timeouts))
+	log.Infof("Reloaded config, enabled collectors: %v", n.names())
+	return nil
+}
+
+// reloadHandler reloads the config on POST requests.
+type reloadHandler struct {
+	reload func() error
+}
+
+func (h reloadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := h.reload(); err != nil {
+		log.Errorf("Couldn't reload config: %s", err)
+		http.Error(w, "Couldn't reload config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte("Config reloaded.\n"))
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
+	"golang.org/x/net/context"
+)
+
+func TestReloadHandler(t *testing.T) {
+	var reloadErr error
+	h := reloadHandler{reload: func() error { return reloadErr }}
+
+	for _, c := range []struct {
+		method string
+		err    error
+		code   int
+	}{
+		{"GET", nil, http.StatusMethodNotAllowed},
+		{"POST", nil, http.StatusOK},
+		{"POST", errors.New("broken config"), http.StatusInternalServerError},
+	} {
+		reloadErr = c.err
+		r, _ := http.NewRequest(c.method, "/-/reload", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != c.code {
+			t.Errorf("%s (%v): want status %d, got %d", c.method, c.err, c.code, w.Code)
+		}
+	}
+}
+
+func TestApplyConfigReload(t *testing.T) {
+	file, err := ioutil.TempFile("", "node_exporter.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer flag.Set("collectors.timeout", "0")
+
+	write := func(config string) {
+		if err := ioutil.WriteFile(file.Name(), []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("collectors: {timeout: 5s}\nweb: {telemetry_path: /metrics}\n")
+	if _, err := applyConfig(file.Name(), false); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "5s", flag.Lookup("collectors.timeout").Value.String(); want != got {
+		t.Errorf("want timeout %s, got %s", want, got)
+	}
+
+	// Removed options are reset, web options need a restart.
+	write("web: {telemetry_path: /other}\n")
+	if _, err := applyConfig(file.Name(), true); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "0s", flag.Lookup("collectors.timeout").Value.String(); want != got {
+		t.Errorf("want timeout %s after reload, got %s", want, got)
+	}
+	if want, got := "/metrics", *metricsPath; want != got {
+		t.Errorf("want telemetry path %s after reload, got %s", want, got)
+	}
+
+	write("collectors: {timeout: 7s}\n")
+	if _, err := applyConfig(file.Name(), true); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "7s", flag.Lookup("collectors.timeout").Value.String(); want != got {
+		t.Errorf("want timeout %s after second reload, got %s", want, got)
+	}
+}
+
+// flagCollector reads a flag set by the config on every update.
+type flagCollector struct{}
+
+func (flagCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	_ = flag.Lookup("collectors.timeout").Value.String()
+	return nil
+}
+
+func TestReloadConcurrent(t *testing.T) {
+	file, err := ioutil.TempFile("", "node_exporter.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString("collectors: {timeout: 5s}\n"); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer func(c, e string) {
+		*configFile, *enabledCollectors = c, e
+		flag.Set("collectors.timeout", "0")
+		delete(collector.Factories, "flag")
+	}(*configFile, *enabledCollectors)
+	collector.Factories["flag"] = func(collector.Config) (collector.Collector, error) {
+		return flagCollector{}, nil
+	}
+	*configFile, *enabledCollectors = file.Name(), "flag"
+	scrapeDurations = newScrapeDurations()
+
+	n := &NodeCollector{disabled: map[string]bool{}}
+	n.setCollectors(map[string]collector.Collector{"flag": flagCollector{}}, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := n.reload(); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := n.gather(nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if want, got := []string{"flag"}, n.names(); len(got) != 1 || got[0] != want[0] {
+		t.Errorf("want collectors %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+var (
+	remoteWriteURL        = flag.String("remote-write.url", "", "URL of a Prometheus remote_write endpoint to send all metrics to periodically.")
+	remoteWriteInterval   = flag.Duration("remote-write.interval", 15*time.Second, "Interval between collections sent to the remote_write endpoint.")
+	remoteWriteInstance   = flag.String("remote-write.instance", "", "Instance label of the sent samples. Defaults to the hostname.")
+	remoteWriteJob        = flag.String("remote-write.job", "node", "Job label of the sent samples.")
+	remoteWriteUser       = flag.String("remote-write.username", "", "Username for basic auth against the remote_write endpoint.")
+	remoteWritePassword   = flag.String("remote-write.password-file", "", "File containing the basic auth password for the remote_write endpoint.")
+	remoteWriteTokenFile  = flag.String("remote-write.bearer-token-file", "", "File containing a bearer token for the remote_write endpoint.")
+	remoteWriteMaxRetries = flag.Int("remote-write.max-retries", 5, "Number of times a failed send is retried, with exponential backoff.")
+)
+
+const (
+	remoteWriteMinBackoff = 500 * time.Millisecond
+	remoteWriteMaxBackoff = 30 * time.Second
+)
+
+// remoteWriter sends samples with the remote_write protocol: a snappy
+// compressed protobuf WriteRequest.
+type remoteWriter struct {
+	url        string
+	labels     []label
+	user       string
+	password   string
+	token      string
+	maxRetries int
+	client     *http.Client
+	// stop interrupts the backoff between retries on shutdown.
+	stop <-chan struct{}
+	// after is replaced in tests.
+	after func(time.Duration) <-chan time.Time
+}
+
+type label struct {
+	name, value string
+}
+
+func newRemoteWriter(stop <-chan struct{}) (*remoteWriter, error) {
+	instance := *remoteWriteInstance
+	if instance == "" {
+		var err error
+		if instance, err = os.Hostname(); err != nil {
+			return nil, err
+		}
+	}
+	w := &remoteWriter{
+		url:        *remoteWriteURL,
+		labels:     []label{{"instance", instance}, {"job", *remoteWriteJob}},
+		user:       *remoteWriteUser,
+		maxRetries: *remoteWriteMaxRetries,
+		client:     &http.Client{Timeout: *remoteWriteInterval},
+		stop:       stop,
+		after:      time.After,
+	}
+	var err error
+	if *remoteWritePassword != "" {
+		if w.password, err = readSecret(*remoteWritePassword); err != nil {
+			return nil, err
+		}
+	}
+	if *remoteWriteTokenFile != "" {
+		if w.token, err = readSecret(*remoteWriteTokenFile); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func readSecret(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (w *remoteWriter) write(samples []sample, timestamp time.Time) error {
+	body := snappy.Encode(nil, w.encode(samples, timestamp.UnixNano()/int64(time.Millisecond)))
+
+	backoff := remoteWriteMinBackoff
+	for try := 0; ; try++ {
+		retry, err := w.send(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || try >= w.maxRetries {
+			return err
+		}
+		select {
+		case <-w.after(backoff):
+		case <-w.stop:
+			return err
+		}
+		if backoff *= 2; backoff > remoteWriteMaxBackoff {
+			backoff = remoteWriteMaxBackoff
+		}
+	}
+}
+
+// send posts the request and reports whether a failure is worth retrying.
+func (w *remoteWriter) send(body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if w.user != "" {
+		req.SetBasicAuth(w.user, w.password)
+	}
+	if w.token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	msg, _ := ioutil.ReadAll(resp.Body)
+	retry := resp.StatusCode/100 == 5 || resp.StatusCode == statusTooManyRequests
+	return retry, fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+}
+
+// encode returns the WriteRequest protobuf of the samples:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func (w *remoteWriter) encode(samples []sample, timestampMs int64) []byte {
+	var req []byte
+	for _, s := range samples {
+		labels := make([]label, 0, len(s.labels)+len(w.labels)+1)
+		labels = append(labels, label{"__name__", s.name})
+		for _, l := range s.labels {
+			labels = append(labels, label{l.GetName(), l.GetValue()})
+		}
+		for _, l := range w.labels {
+			if !hasLabel(labels, l.name) {
+				labels = append(labels, l)
+			}
+		}
+		sort.Sort(labelsByName(labels))
+
+		var series []byte
+		for _, l := range labels {
+			var pb []byte
+			pb = appendString(pb, 1, l.name)
+			pb = appendString(pb, 2, l.value)
+			series = appendBytes(series, 1, pb)
+		}
+		var pb []byte
+		pb = appendVarint(pb, 1<<3|1)
+		pb = appendFixed64(pb, math.Float64bits(s.value))
+		pb = appendVarint(pb, 2<<3)
+		pb = appendVarint(pb, uint64(timestampMs))
+		series = appendBytes(series, 2, pb)
+
+		req = appendBytes(req, 1, series)
+	}
+	return req
+}
+
+func hasLabel(labels []label, name string) bool {
+	for _, l := range labels {
+		if l.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type labelsByName []label
+
+func (l labelsByName) Len() int           { return len(l) }
+func (l labelsByName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l labelsByName) Less(i, j int) bool { return l[i].name < l[j].name }
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// appendBytes appends a length delimited field.
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendVarint(b, uint64(field)<<3|2)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendString(b []byte, field int, v string) []byte {
+	return appendBytes(b, field, []byte(v))
+}
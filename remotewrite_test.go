@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/text"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestRemoteWriter(t *testing.T) {
+	var bodies [][]byte
+	statuses := []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusBadRequest}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want, got := "snappy", r.Header.Get("Content-Encoding"); want != got {
+			t.Errorf("want encoding %s, got %s", want, got)
+		}
+		if want, got := "Bearer secret", r.Header.Get("Authorization"); want != got {
+			t.Errorf("want authorization %q, got %q", want, got)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, b)
+		w.WriteHeader(statuses[0])
+		statuses = statuses[1:]
+	}))
+	defer server.Close()
+
+	var parser text.Parser
+	families, err := parser.TextToMetricFamilies(strings.NewReader("# TYPE node_load1 gauge\nnode_load1 0.21\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slept []time.Duration
+	w := &remoteWriter{
+		url:        server.URL,
+		labels:     []label{{"instance", "edge-1"}, {"job", "node"}},
+		token:      "secret",
+		maxRetries: 3,
+		client:     http.DefaultClient,
+		after: func(d time.Duration) <-chan time.Time {
+			slept = append(slept, d)
+			c := make(chan time.Time, 1)
+			c <- time.Time{}
+			return c
+		},
+	}
+	samples := flattenSamples([]*dto.MetricFamily{families["node_load1"]})
+
+	// The 503 is retried.
+	if err := w.write(samples, time.Unix(1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1, len(slept); want != got {
+		t.Errorf("want %d retries, got %d", want, got)
+	}
+	data, err := snappy.Decode(nil, bodies[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := w.encode(samples, 1000); !bytes.Equal(want, data) {
+		t.Errorf("want request %x, got %x", want, data)
+	}
+	for _, s := range []string{"__name__", "node_load1", "instance", "edge-1"} {
+		if !bytes.Contains(data, []byte(s)) {
+			t.Errorf("request doesn't contain %q", s)
+		}
+	}
+
+	// The 400 is not.
+	if err := w.write(samples, time.Unix(1, 0)); err == nil {
+		t.Error("expected error for status 400")
+	}
+	if want, got := 1, len(slept); want != got {
+		t.Errorf("want %d retries, got %d", want, got)
+	}
+
+	// Shutting down interrupts the backoff.
+	statuses = []int{http.StatusServiceUnavailable}
+	stop := make(chan struct{})
+	close(stop)
+	w.stop = stop
+	w.after = func(time.Duration) <-chan time.Time { return nil }
+	if err := w.write(samples, time.Unix(1, 0)); err == nil {
+		t.Error("expected error for status 503 on shutdown")
+	}
+}
+
+func TestRemoteWriteEncode(t *testing.T) {
+	w := &remoteWriter{labels: []label{{"job", "node"}}}
+	samples := []sample{{name: "up", value: 1}}
+	want := []byte{
+		0x0a, 0x2b, // timeseries, 43 bytes
+		0x0a, 0x0e, 0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_', 0x12, 0x02, 'u', 'p',
+		0x0a, 0x0b, 0x0a, 0x03, 'j', 'o', 'b', 0x12, 0x04, 'n', 'o', 'd', 'e',
+		0x12, 0x0c, 0x09, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, 0x10, 0xe8, 0x07,
+	}
+	if got := w.encode(samples, 1000); !bytes.Equal(want, got) {
+		t.Errorf("want %x, got %x", want, got)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/text"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/node_exporter/log"
+	"golang.org/x/net/context"
+)
+
+var scrapeTimeoutOffset = flag.Duration("web.scrape-timeout-offset", 500*time.Millisecond, "Offset subtracted from the X-Prometheus-Scrape-Timeout-Seconds header of a scrape to leave time for sending the response.")
+
+const scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// scrapeOptions holds the options of a scrape. Each request gets its own, so
+// scrapes run concurrently.
+type scrapeOptions struct {
+	// only is nil if all collectors are to be run.
+	only map[string]bool
+	// deadline is zero if the scraper didn't send a timeout.
+	deadline time.Time
+	// ctx is cancelled when the scraper goes away.
+	ctx context.Context
+}
+
+// includes reports whether the collector name is to be run in the scrape.
+func (o *scrapeOptions) includes(name string) bool {
+	return o == nil || o.only == nil || o.only[name]
+}
+
+// scrapeContext returns the context of the scrape, or the background
+// context outside of scrapes.
+func (o *scrapeOptions) scrapeContext() context.Context {
+	if o == nil || o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
+
+// timeout shortens the timeout of a collector to the time left until the
+// deadline of the scrape.
+func (o *scrapeOptions) timeout(timeout time.Duration) time.Duration {
+	if o == nil || o.deadline.IsZero() {
+		return timeout
+	}
+	left := o.deadline.Sub(time.Now())
+	if left <= 0 {
+		// Zero would disable the timeout.
+		left = time.Nanosecond
+	}
+	if timeout == 0 || left < timeout {
+		return left
+	}
+	return timeout
+}
+
+// scrapeHandler serves the metrics of the collectors given by the collect[]
+// query parameters, or all enabled ones, minus the collectors given by
+// exclude[], together with those served by the wrapped handler. Collectors
+// still running when the scrape timeout sent by Prometheus expires are
+// reported as failed, and the context of the collectors is cancelled when
+// the client disconnects.
+type scrapeHandler struct {
+	// handler serves the metrics of the default registry, like those of the
+	// Go runtime and the textfile collector.
+	handler http.Handler
+	// gather returns the metrics of the collectors for a scrape.
+	gather func(*scrapeOptions) ([]*dto.MetricFamily, error)
+	// collectors returns the names of the enabled collectors.
+	collectors func() []string
+}
+
+func (h *scrapeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The scraper's timeout runs from when it sent the request.
+	begin := time.Now()
+	only, err := h.parse(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var deadline time.Time
+	if s := r.Header.Get(scrapeTimeoutHeader); s != "" {
+		seconds, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s: %s", scrapeTimeoutHeader, err), http.StatusBadRequest)
+			return
+		}
+		deadline = begin.Add(time.Duration(seconds*float64(time.Second)) - *scrapeTimeoutOffset)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if cn, ok := w.(http.CloseNotifier); ok {
+		closed := cn.CloseNotify()
+		go func() {
+			select {
+			case <-closed:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	families, err := h.gather(&scrapeOptions{only: only, deadline: deadline, ctx: ctx})
+	if err != nil {
+		log.Errorf("Couldn't gather metrics: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if h.handler != nil {
+		registered, err := gather(h.handler, r)
+		if err != nil {
+			log.Errorf("Couldn't gather metrics: %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		families = mergeFamilies(registered, families)
+	}
+	w.Header().Set("Content-Type", textContentType)
+	for _, mf := range families {
+		if _, err := text.MetricFamilyToText(w, mf); err != nil {
+			log.Errorf("Couldn't write metrics: %s", err)
+			return
+		}
+	}
+}
+
+func (h *scrapeHandler) parse(r *http.Request) (map[string]bool, error) {
+	query := r.URL.Query()
+	names, excluded := query["collect[]"], query["exclude[]"]
+	if len(names) == 0 && len(excluded) == 0 {
+		return nil, nil
+	}
+
+	enabled := map[string]bool{}
+	for _, name := range h.collectors() {
+		enabled[name] = true
+	}
+	only := map[string]bool{}
+	for _, name := range names {
+		if !enabled[name] {
+			return nil, fmt.Errorf("collector %q not enabled", name)
+		}
+		only[name] = true
+	}
+	if len(names) == 0 {
+		only = enabled
+	}
+	for _, name := range excluded {
+		if !enabled[name] {
+			return nil, fmt.Errorf("collector %q not enabled", name)
+		}
+		delete(only, name)
+	}
+	return only, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/node_exporter/collector"
+	"golang.org/x/net/context"
+)
+
+func TestScrapeHandler(t *testing.T) {
+	enabled := []string{"meminfo", "netdev", "stat"}
+	var ran []string
+	h := &scrapeHandler{
+		gather: func(o *scrapeOptions) ([]*dto.MetricFamily, error) {
+			for _, name := range enabled {
+				if o.includes(name) {
+					ran = append(ran, name)
+				}
+			}
+			return nil, nil
+		},
+		collectors: func() []string { return enabled },
+	}
+
+	for _, c := range []struct {
+		query string
+		code  int
+		ran   string
+	}{
+		{"", http.StatusOK, "meminfo,netdev,stat"},
+		{"?collect[]=stat&collect[]=meminfo", http.StatusOK, "meminfo,stat"},
+		{"?collect[]=hwmon", http.StatusBadRequest, ""},
+		{"?exclude[]=netdev", http.StatusOK, "meminfo,stat"},
+		{"?collect[]=stat&collect[]=netdev&exclude[]=netdev", http.StatusOK, "stat"},
+		{"?exclude[]=hwmon", http.StatusBadRequest, ""},
+	} {
+		ran = nil
+		r, _ := http.NewRequest("GET", "/metrics"+c.query, nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != c.code {
+			t.Errorf("%s: want status %d, got %d", c.query, c.code, w.Code)
+		}
+		sort.Strings(ran)
+		if got := strings.Join(ran, ","); got != c.ran {
+			t.Errorf("%s: want collectors %s, got %s", c.query, c.ran, got)
+		}
+	}
+}
+
+func TestScrapeTimeout(t *testing.T) {
+	var timeout time.Duration
+	h := &scrapeHandler{
+		gather: func(o *scrapeOptions) ([]*dto.MetricFamily, error) {
+			timeout = o.timeout(10 * time.Second)
+			return nil, nil
+		},
+	}
+
+	for _, c := range []struct {
+		header   string
+		min, max time.Duration
+	}{
+		{"", 10 * time.Second, 10 * time.Second},
+		{"5", 4 * time.Second, 5*time.Second - *scrapeTimeoutOffset},
+		{"20", 10 * time.Second, 10 * time.Second},
+		{"0.1", time.Nanosecond, time.Nanosecond},
+	} {
+		r, _ := http.NewRequest("GET", "/metrics", nil)
+		if c.header != "" {
+			r.Header.Set(scrapeTimeoutHeader, c.header)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		if timeout < c.min || timeout > c.max {
+			t.Errorf("%q: want timeout between %s and %s, got %s", c.header, c.min, c.max, timeout)
+		}
+	}
+}
+
+func TestScrapeTimeoutConcurrent(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	timeouts := make(chan time.Duration, 1)
+	h := &scrapeHandler{
+		gather: func(o *scrapeOptions) ([]*dto.MetricFamily, error) {
+			if o.deadline.IsZero() {
+				close(started)
+				<-release
+				return nil, nil
+			}
+			timeouts <- o.timeout(10 * time.Second)
+			return nil, nil
+		},
+	}
+
+	// A running scrape doesn't use up the timeout of the next one.
+	go func() {
+		r, _ := http.NewRequest("GET", "/metrics", nil)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}()
+	<-started
+	defer close(release)
+	r, _ := http.NewRequest("GET", "/metrics", nil)
+	r.Header.Set(scrapeTimeoutHeader, "5")
+	go h.ServeHTTP(httptest.NewRecorder(), r)
+	select {
+	case timeout := <-timeouts:
+		if min := 4 * time.Second; timeout < min {
+			t.Errorf("want timeout of at least %s, got %s", min, timeout)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("scrape blocked by another one")
+	}
+}
+
+type closeNotifyRecorder struct {
+	*httptest.ResponseRecorder
+	closed chan bool
+}
+
+func (r closeNotifyRecorder) CloseNotify() <-chan bool {
+	return r.closed
+}
+
+func TestScrapeContext(t *testing.T) {
+	var err error
+	w := closeNotifyRecorder{httptest.NewRecorder(), make(chan bool, 1)}
+	h := &scrapeHandler{
+		gather: func(o *scrapeOptions) ([]*dto.MetricFamily, error) {
+			ctx := o.scrapeContext()
+			w.closed <- true
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-time.After(time.Second):
+			}
+			return nil, nil
+		},
+	}
+
+	r, _ := http.NewRequest("GET", "/metrics", nil)
+	h.ServeHTTP(w, r)
+	if err == nil {
+		t.Error("want scrape context cancelled when the client goes away")
+	}
+}
+
+// stuckCollector blocks its updates until release is closed.
+type stuckCollector struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c stuckCollector) Update(ctx context.Context, ch chan<- prometheus.Metric) error {
+	c.started <- struct{}{}
+	<-c.release
+	return nil
+}
+
+func TestScrapesConcurrent(t *testing.T) {
+	scrapeDurations = newScrapeDurations()
+	blocking := stuckCollector{started: make(chan struct{}, 1), release: make(chan struct{})}
+	n := &NodeCollector{node: collector.NewNodeCollectorWith(map[string]collector.Collector{
+		"blocking": blocking,
+		"good":     errorCollector{},
+	}, nil)}
+	h := &scrapeHandler{gather: n.gather, collectors: n.names}
+
+	// A scrape stuck in a collector doesn't hold up the others.
+	done := make(chan struct{})
+	go func() {
+		r, _ := http.NewRequest("GET", "/metrics?collect[]=blocking", nil)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		close(done)
+	}()
+	<-blocking.started
+
+	r, _ := http.NewRequest("GET", "/metrics?collect[]=good", nil)
+	w := httptest.NewRecorder()
+	served := make(chan struct{})
+	go func() {
+		h.ServeHTTP(w, r)
+		close(served)
+	}()
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("scrape blocked by another one")
+	}
+	close(blocking.release)
+	<-done
+
+	if want, got := textContentType, w.Header().Get("Content-Type"); want != got {
+		t.Errorf("want content type %q, got %q", want, got)
+	}
+	want := `node_scrape_collector_success{collector="good"} 1`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("want %q in %q", want, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), `collector="blocking"`) {
+		t.Errorf("want no metrics of blocking collector, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/node_exporter/collector"
+	"github.com/prometheus/node_exporter/log"
+)
+
+var shutdownTimeout = flag.Duration("web.shutdown-timeout", 10*time.Second, "Time to wait for running scrapes to finish on SIGTERM or SIGINT.")
+
+// inflightHandler keeps track of the requests being served, so shutdown can
+// wait for them. Once shutdown has started, new requests are rejected.
+type inflightHandler struct {
+	handler http.Handler
+
+	mu      sync.Mutex
+	running int
+	closed  bool
+	// idle is closed when the last running request finishes after shutdown
+	// has started.
+	idle chan struct{}
+}
+
+func (h *inflightHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		http.Error(w, "Node Exporter is shutting down.", http.StatusServiceUnavailable)
+		return
+	}
+	h.running++
+	h.mu.Unlock()
+	defer h.finish()
+	h.handler.ServeHTTP(w, r)
+}
+
+func (h *inflightHandler) finish() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.running--
+	if h.running == 0 && h.idle != nil {
+		close(h.idle)
+		h.idle = nil
+	}
+}
+
+// wait rejects new requests, waits for the running ones to finish and
+// reports whether they did within the timeout.
+func (h *inflightHandler) wait(timeout time.Duration) bool {
+	h.mu.Lock()
+	h.closed = true
+	if h.running == 0 {
+		h.mu.Unlock()
+		return true
+	}
+	if h.idle == nil {
+		h.idle = make(chan struct{})
+	}
+	idle := h.idle
+	h.mu.Unlock()
+	select {
+	case <-idle:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// closeCollectors releases the resources of collectors implementing
+// io.Closer.
+func closeCollectors(collectors map[string]collector.Collector) {
+	for name, c := range collectors {
+		if closer, ok := c.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.Errorf("Couldn't close collector %s: %s", name, err)
+			}
+		}
+	}
+}
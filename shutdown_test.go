@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestInflightHandler(t *testing.T) {
+	release := make(chan struct{})
+	h := &inflightHandler{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})}
+
+	r, _ := http.NewRequest("GET", "/metrics", nil)
+	go h.ServeHTTP(httptest.NewRecorder(), r)
+	// Give the request time to start.
+	time.Sleep(10 * time.Millisecond)
+	if h.wait(10 * time.Millisecond) {
+		t.Error("wait returned before the request finished")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("want status %d for a request during shutdown, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	close(release)
+	if !h.wait(time.Second) {
+		t.Error("wait timed out after the request finished")
+	}
+}
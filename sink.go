@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/node_exporter/log"
+)
+
+// Maximum size of the packets sent by the UDP sinks, to avoid fragmentation.
+const maxPacketSize = 1400
+
+// sinks are the push modes sending the metrics out periodically.
+var sinks sync.WaitGroup
+
+// runSink calls push with the gathered metrics every interval until stop is
+// closed.
+func runSink(name string, interval time.Duration, handler http.Handler, stop <-chan struct{}, push func([]*dto.MetricFamily) error) {
+	sinks.Add(1)
+	go func() {
+		defer sinks.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+			r, _ := http.NewRequest("GET", "/metrics", nil)
+			families, err := gather(handler, r)
+			if err != nil {
+				log.Errorf("Couldn't gather metrics for %s: %s", name, err)
+				continue
+			}
+			if err := push(families); err != nil {
+				log.Errorf("Couldn't push metrics to %s: %s", name, err)
+			}
+		}
+	}()
+}
+
+// sample is a single value of a metric family, as ingested by most systems
+// the push modes send to.
+type sample struct {
+	name   string
+	labels []*dto.LabelPair
+	value  float64
+	// typ is the type of the family the sample belongs to.
+	typ dto.MetricType
+}
+
+// flattenSamples turns the families into samples, expanding summaries and
+// histograms into their quantile or bucket, _sum and _count samples like
+// the text format does.
+func flattenSamples(families []*dto.MetricFamily) []sample {
+	var samples []sample
+	for _, mf := range families {
+		name, typ := mf.GetName(), mf.GetType()
+		for _, m := range mf.Metric {
+			add := func(name string, labels []*dto.LabelPair, value float64) {
+				samples = append(samples, sample{name: name, labels: labels, value: value, typ: typ})
+			}
+			switch {
+			case m.Counter != nil:
+				add(name, m.Label, m.Counter.GetValue())
+			case m.Gauge != nil:
+				add(name, m.Label, m.Gauge.GetValue())
+			case m.Summary != nil:
+				for _, q := range m.Summary.GetQuantile() {
+					add(name, withLabel(m.Label, "quantile", strconv.FormatFloat(q.GetQuantile(), 'g', -1, 64)), q.GetValue())
+				}
+				add(name+"_sum", m.Label, m.Summary.GetSampleSum())
+				add(name+"_count", m.Label, float64(m.Summary.GetSampleCount()))
+			case m.Histogram != nil:
+				for _, b := range m.Histogram.GetBucket() {
+					add(name+"_bucket", withLabel(m.Label, "le", formatOpenMetricsFloat(b.GetUpperBound())), float64(b.GetCumulativeCount()))
+				}
+				add(name+"_sum", m.Label, m.Histogram.GetSampleSum())
+				add(name+"_count", m.Label, float64(m.Histogram.GetSampleCount()))
+			default:
+				add(name, m.Label, m.Untyped.GetValue())
+			}
+		}
+	}
+	return samples
+}
+
+func withLabel(labels []*dto.LabelPair, name, value string) []*dto.LabelPair {
+	result := make([]*dto.LabelPair, 0, len(labels)+1)
+	result = append(result, labels...)
+	return append(result, &dto.LabelPair{Name: &name, Value: &value})
+}
+
+// writePackets writes the lines batched into packets of at most size bytes,
+// for datagram protocols. Lines longer than size get a packet of their own.
+func writePackets(w io.Writer, lines []string, size int) error {
+	var packet bytes.Buffer
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+len(line) > size {
+			if _, err := w.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() == 0 {
+		return nil
+	}
+	_, err := w.Write(packet.Bytes())
+	return err
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	statsdAddress      = flag.String("statsd.address", "", "Address of a StatsD server to send counters and gauges to periodically over UDP, e.g. statsd:8125.")
+	statsdInterval     = flag.Duration("statsd.interval", time.Minute, "Interval between sends to StatsD.")
+	statsdPrefix       = flag.String("statsd.prefix", "", "Prefix of the StatsD metric names, e.g. servers.web01.")
+	statsdLabelMapping = flag.String("statsd.label-mapping", "path", "How labels are mapped into StatsD metrics: path (node_cpu.cpu.cpu0.mode.idle), values (node_cpu.cpu0.idle) or tags (node_cpu|#cpu:cpu0,mode:idle, for DogStatsD).")
+
+	statsdTagEscaper = strings.NewReplacer(",", "_", "|", "_", ":", "_", "#", "_", "\n", "_")
+)
+
+// statsdSender sends counters as increments since the previous send and
+// gauges as values to a StatsD server.
+type statsdSender struct {
+	address string
+	prefix  string
+	mapping string
+	timeout time.Duration
+
+	// counters are the counter values of the previous send.
+	counters map[string]float64
+}
+
+func newStatsdSender(address, prefix, mapping string, timeout time.Duration) (*statsdSender, error) {
+	switch mapping {
+	case "path", "values", "tags":
+	default:
+		return nil, fmt.Errorf("unknown label mapping %q, expected path, values or tags", mapping)
+	}
+	prefix = strings.TrimSuffix(prefix, ".")
+	if prefix != "" {
+		prefix += "."
+	}
+	return &statsdSender{
+		address:  address,
+		prefix:   prefix,
+		mapping:  mapping,
+		timeout:  timeout,
+		counters: map[string]float64{},
+	}, nil
+}
+
+func (s *statsdSender) send(samples []sample) error {
+	conn, err := net.DialTimeout("udp", s.address, s.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return writePackets(conn, s.lines(samples), maxPacketSize)
+}
+
+// lines returns the StatsD lines for the samples. Counters are only sent
+// once they have a previous value to compute the increment from, summaries
+// and histograms aren't sent at all.
+func (s *statsdSender) lines(samples []sample) []string {
+	var lines []string
+	counters := make(map[string]float64, len(s.counters))
+	for _, sm := range samples {
+		if math.IsNaN(sm.value) || math.IsInf(sm.value, 0) {
+			continue
+		}
+		name, suffix := s.name(sm.name, sm.labels)
+		switch sm.typ {
+		case dto.MetricType_COUNTER:
+			// The tags of the suffix tell the series of a name apart.
+			series := name + suffix
+			counters[series] = sm.value
+			last, ok := s.counters[series]
+			if !ok {
+				continue
+			}
+			delta := sm.value - last
+			if delta < 0 {
+				// The counter was reset.
+				delta = sm.value
+			}
+			if delta == 0 {
+				continue
+			}
+			lines = append(lines, name+":"+strconv.FormatFloat(delta, 'g', -1, 64)+"|c"+suffix+"\n")
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			line := name + ":" + strconv.FormatFloat(sm.value, 'g', -1, 64) + "|g" + suffix + "\n"
+			if sm.value < 0 {
+				// A signed value modifies the gauge instead of setting it,
+				// so reset it to zero first.
+				line = name + ":0|g" + suffix + "\n" + line
+			}
+			lines = append(lines, line)
+		}
+	}
+	s.counters = counters
+	return lines
+}
+
+// name returns the StatsD name of a sample and, with the tags mapping, the
+// suffix with its tags.
+func (s *statsdSender) name(name string, labels []*dto.LabelPair) (string, string) {
+	switch s.mapping {
+	case "values":
+		sorted := make([]*dto.LabelPair, len(labels))
+		copy(sorted, labels)
+		sort.Sort(labelPairsByName(sorted))
+		parts := []string{graphiteInvalidRE.ReplaceAllString(name, "_")}
+		for _, l := range sorted {
+			parts = append(parts, graphiteInvalidRE.ReplaceAllString(l.GetValue(), "_"))
+		}
+		return s.prefix + strings.Join(parts, "."), ""
+	case "tags":
+		tags := make([]string, 0, len(labels))
+		for _, l := range labels {
+			tags = append(tags, statsdTagEscaper.Replace(l.GetName())+":"+statsdTagEscaper.Replace(l.GetValue()))
+		}
+		sort.Strings(tags)
+		suffix := ""
+		if len(tags) > 0 {
+			suffix = "|#" + strings.Join(tags, ",")
+		}
+		return s.prefix + graphiteInvalidRE.ReplaceAllString(name, "_"), suffix
+	default:
+		return s.prefix + graphitePath(name, labels), ""
+	}
+}
+
+type labelPairsByName []*dto.LabelPair
+
+func (l labelPairsByName) Len() int           { return len(l) }
+func (l labelPairsByName) Less(i, j int) bool { return l[i].GetName() < l[j].GetName() }
+func (l labelPairsByName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/text"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func testStatsdSamples(t *testing.T, in string) []sample {
+	var parser text.Parser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []*dto.MetricFamily
+	for _, name := range []string{"node_cpu", "node_load1", "node_temperature", "node_scrape_duration_seconds"} {
+		if mf, ok := families[name]; ok {
+			list = append(list, mf)
+		}
+	}
+	return flattenSamples(list)
+}
+
+func TestStatsdLines(t *testing.T) {
+	s, err := newStatsdSender("localhost:8125", "web01", "path", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		in   string
+		want []string
+	}{
+		{
+			in: `# TYPE node_cpu counter
+node_cpu{mode="idle",cpu="cpu0"} 100
+# TYPE node_load1 gauge
+node_load1 0.5
+node_temperature{sensor="outside"} -3
+# TYPE node_scrape_duration_seconds summary
+node_scrape_duration_seconds_sum 1
+node_scrape_duration_seconds_count 1
+`,
+			want: []string{
+				"web01.node_load1:0.5|g\n",
+				"web01.node_temperature.sensor.outside:0|g\nweb01.node_temperature.sensor.outside:-3|g\n",
+			},
+		},
+		{
+			in: `# TYPE node_cpu counter
+node_cpu{mode="idle",cpu="cpu0"} 150
+`,
+			want: []string{"web01.node_cpu.cpu.cpu0.mode.idle:50|c\n"},
+		},
+		{
+			in: `# TYPE node_cpu counter
+node_cpu{mode="idle",cpu="cpu0"} 20
+`,
+			want: []string{"web01.node_cpu.cpu.cpu0.mode.idle:20|c\n"},
+		},
+	} {
+		if got := s.lines(testStatsdSamples(t, test.in)); !reflect.DeepEqual(test.want, got) {
+			t.Errorf("%d. want %q, got %q", i, test.want, got)
+		}
+	}
+}
+
+func TestStatsdLabelMapping(t *testing.T) {
+	in := `node_temperature{sensor="outside",chip="a:b"} 3
+`
+	for mapping, want := range map[string]string{
+		"path":   "node_temperature.chip.a_b.sensor.outside:3|g\n",
+		"values": "node_temperature.a_b.outside:3|g\n",
+		"tags":   "node_temperature:3|g|#chip:a_b,sensor:outside\n",
+	} {
+		s, err := newStatsdSender("localhost:8125", "", mapping, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.lines(testStatsdSamples(t, in)); len(got) != 1 || got[0] != want {
+			t.Errorf("%s: want %q, got %q", mapping, want, got)
+		}
+	}
+
+	// The counters of the series of a name are kept apart.
+	s, err := newStatsdSender("localhost:8125", "", "tags", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.lines(testStatsdSamples(t, `# TYPE node_cpu counter
+node_cpu{cpu="cpu0"} 100
+node_cpu{cpu="cpu1"} 200
+`))
+	want := []string{"node_cpu:5|c|#cpu:cpu0\n", "node_cpu:10|c|#cpu:cpu1\n"}
+	if got := s.lines(testStatsdSamples(t, `# TYPE node_cpu counter
+node_cpu{cpu="cpu0"} 105
+node_cpu{cpu="cpu1"} 210
+`)); !reflect.DeepEqual(want, got) {
+		t.Errorf("tags: want %q, got %q", want, got)
+	}
+
+	if _, err := newStatsdSender("localhost:8125", "", "labels", time.Second); err == nil {
+		t.Error("expected an error for an unknown mapping")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/node_exporter/log"
+)
+
+// collectorResult is the outcome of the last run of a collector.
+type collectorResult struct {
+	duration    time.Duration
+	err         error
+	lastSuccess time.Time
+}
+
+// collectorStatus is a collector as listed on the status page. The run
+// fields are empty until the collector first runs.
+type collectorStatus struct {
+	Name                string     `json:"name"`
+	Enabled             bool       `json:"enabled"`
+	LastDurationSeconds float64    `json:"last_duration_seconds"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+}
+
+type byName []collectorStatus
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+// statuses returns the collectors sorted by name, with whether they are on
+// and the outcome of their last run.
+func (n *NodeCollector) statuses() []collectorStatus {
+	states := n.states()
+	n.resultsMu.Lock()
+	defer n.resultsMu.Unlock()
+	statuses := make([]collectorStatus, 0, len(states))
+	for name, enabled := range states {
+		s := collectorStatus{Name: name, Enabled: enabled}
+		if r, ok := n.results[name]; ok {
+			s.LastDurationSeconds = r.duration.Seconds()
+			if r.err != nil {
+				s.LastError = r.err.Error()
+			}
+			if !r.lastSuccess.IsZero() {
+				t := r.lastSuccess.UTC()
+				s.LastSuccess = &t
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Sort(byName(statuses))
+	return statuses
+}
+
+var statusTemplate = template.Must(template.New("status").Parse(`<html>
+<head><title>Node Exporter Status</title></head>
+<body>
+<h1>Collectors</h1>
+<table>
+<tr><th>Name</th><th>Enabled</th><th>Last duration</th><th>Last error</th><th>Last success</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td>{{.Enabled}}</td><td>{{printf "%.3fs" .LastDurationSeconds}}</td><td>{{.LastError}}</td><td>{{with .LastSuccess}}{{.Format "2006-01-02T15:04:05Z07:00"}}{{else}}never{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// statusHandler serves the status of the collectors as an HTML table, or
+// as JSON with ?format=json or an Accept header asking for JSON.
+type statusHandler struct {
+	collectors *NodeCollector
+}
+
+func (h statusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	statuses := h.collectors.statuses()
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(statuses); err != nil {
+			log.Errorf("Couldn't encode collector statuses: %s", err)
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := statusTemplate.Execute(&buf, statuses); err != nil {
+		log.Errorf("Couldn't render status page: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/node_exporter/collector"
+)
+
+func TestStatusHandler(t *testing.T) {
+	n := &NodeCollector{
+		node:     collector.NewNodeCollectorWith(map[string]collector.Collector{"stat": nil, "meminfo": nil, "hwmon": nil}, nil),
+		disabled: map[string]bool{"hwmon": true},
+	}
+	begin := time.Now()
+	n.setResult("stat", begin, nil)
+	n.setResult("stat", begin.Add(time.Second), errors.New("no such file"))
+	n.setResult("meminfo", begin, nil)
+	h := statusHandler{collectors: n}
+
+	r, _ := http.NewRequest("GET", "/status?format=json", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	var statuses []collectorStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("invalid JSON %q: %s", w.Body.String(), err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("want 3 collectors, got %v", statuses)
+	}
+	hwmon, meminfo, stat := statuses[0], statuses[1], statuses[2]
+	if hwmon.Name != "hwmon" || hwmon.Enabled || hwmon.LastSuccess != nil {
+		t.Errorf("want hwmon disabled and never run, got %+v", hwmon)
+	}
+	if !meminfo.Enabled || meminfo.LastError != "" || meminfo.LastSuccess == nil {
+		t.Errorf("want meminfo succeeding, got %+v", meminfo)
+	}
+	if stat.LastError != "no such file" || stat.LastSuccess == nil || !stat.LastSuccess.Equal(begin) {
+		t.Errorf("want stat failing after a success at %s, got %+v", begin, stat)
+	}
+
+	r, _ = http.NewRequest("GET", "/status", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if want, got := "text/html; charset=utf-8", w.Header().Get("Content-Type"); want != got {
+		t.Errorf("want content type %s, got %s", want, got)
+	}
+	for _, want := range []string{"<td>hwmon</td><td>false</td>", "<td>no such file</td>", "never"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("want %q in status page, got %s", want, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// newTLSConfig returns a TLS config serving the given certificate. If caFile
+// is set, clients must present a certificate signed by one of its CAs.
+func newTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if caFile == "" {
+		return config, nil
+	}
+
+	data, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	config.ClientCAs = pool
+	return config, nil
+}
+
+// serverTLSConfig returns the TLS config set up by the -web.tls-* flags, or
+// nil if TLS is off.
+func serverTLSConfig() (*tls.Config, error) {
+	var config *tls.Config
+	if *tlsCertFile != "" || *tlsKeyFile != "" {
+		if *tlsCertFile == "" || *tlsKeyFile == "" {
+			return nil, fmt.Errorf("You need to specify -web.tls-cert-file and -web.tls-key-file to enable TLS")
+		}
+		var err error
+		config, err = newTLSConfig(*tlsCertFile, *tlsKeyFile, *tlsClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't set up TLS: %s", err)
+		}
+	}
+	if *tlsAllowedCNs != "" && (*tlsClientCAFile == "" || config == nil) {
+		return nil, fmt.Errorf("You need to specify -web.tls-client-ca-file to restrict client common names")
+	}
+	return config, nil
+}
+
+// cnAllowlistHandler rejects requests whose verified client certificate
+// doesn't carry one of the allowed common names.
+type cnAllowlistHandler struct {
+	handler http.Handler
+	allowed map[string]bool
+}
+
+func newCNAllowlistHandler(handler http.Handler, cns []string) *cnAllowlistHandler {
+	allowed := map[string]bool{}
+	for _, cn := range cns {
+		if cn = strings.TrimSpace(cn); cn != "" {
+			allowed[cn] = true
+		}
+	}
+	return &cnAllowlistHandler{handler: handler, allowed: allowed}
+}
+
+func (h *cnAllowlistHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 ||
+		!h.allowed[r.TLS.VerifiedChains[0][0].Subject.CommonName] {
+		http.Error(w, "Client certificate not allowed", http.StatusForbidden)
+		return
+	}
+	h.handler.ServeHTTP(w, r)
+}
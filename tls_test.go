@@ -0,0 +1,37 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCNAllowlistHandler(t *testing.T) {
+	h := newCNAllowlistHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), []string{" prometheus", "backup"})
+
+	for _, c := range []struct {
+		state *tls.ConnectionState
+		code  int
+	}{
+		{nil, http.StatusForbidden},
+		{&tls.ConnectionState{}, http.StatusForbidden},
+		{clientState("prometheus"), http.StatusOK},
+		{clientState("intruder"), http.StatusForbidden},
+	} {
+		r, _ := http.NewRequest("GET", "/metrics", nil)
+		r.TLS = c.state
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != c.code {
+			t.Errorf("%v: want status %d, got %d", c.state, c.code, w.Code)
+		}
+	}
+}
+
+func clientState(cn string) *tls.ConnectionState {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+	return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+}
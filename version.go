@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
+)
+
+// The build information, set at build time by the Makefile.
+var (
+	version   = "unknown"
+	revision  = "unknown"
+	branch    = "unknown"
+	buildDate = "unknown"
+)
+
+var printVersion = flag.Bool("version", false, "Print the version, revision, build date, Go version and compiled-in collectors, and exit.")
+
+func newBuildInfoDesc() *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(collector.Namespace, subsystem, "build_info"),
+		"node_exporter: A metric with a constant '1' value labeled by the version, revision, branch and Go version node_exporter was built from.",
+		[]string{"version", "revision", "branch", "goversion"}, nil,
+	)
+}
+
+// buildInfo returns the build_info metric of the running binary.
+func buildInfo() prometheus.Metric {
+	return prometheus.MustNewConstMetric(newBuildInfoDesc(), prometheus.GaugeValue, 1, version, revision, branch, runtime.Version())
+}
+
+// writeVersion writes the build information of the binary and the
+// collectors compiled into it, which tells which build tags it was built
+// with.
+func writeVersion(w io.Writer) {
+	names := make([]string, 0, len(collector.Factories))
+	for name := range collector.Factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "node_exporter, version %s (branch: %s, revision: %s)\n", version, branch, revision)
+	fmt.Fprintf(w, "  build date:  %s\n", buildDate)
+	fmt.Fprintf(w, "  go version:  %s\n", runtime.Version())
+	fmt.Fprintf(w, "  platform:    %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(w, "  collectors:  %s\n", strings.Join(names, ", "))
+}
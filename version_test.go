@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestBuildInfo(t *testing.T) {
+	defer func(v, r, b string) { version, revision, branch = v, r, b }(version, revision, branch)
+	version, revision, branch = "0.8.0", "abc1234", "master"
+
+	var m dto.Metric
+	if err := buildInfo().Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1.0, m.GetGauge().GetValue(); want != got {
+		t.Errorf("want value %f, got %f", want, got)
+	}
+	want := map[string]string{"version": "0.8.0", "revision": "abc1234", "branch": "master", "goversion": runtime.Version()}
+	for _, l := range m.GetLabel() {
+		if want[l.GetName()] != l.GetValue() {
+			t.Errorf("want label %s=%q, got %q", l.GetName(), want[l.GetName()], l.GetValue())
+		}
+		delete(want, l.GetName())
+	}
+	if len(want) > 0 {
+		t.Errorf("missing labels %v", want)
+	}
+}
+
+func TestWriteVersion(t *testing.T) {
+	defer func(v, r, b, d string) { version, revision, branch, buildDate = v, r, b, d }(version, revision, branch, buildDate)
+	version, revision, branch, buildDate = "0.8.0", "abc1234", "master", "20161014-09:00:00"
+
+	var buf bytes.Buffer
+	writeVersion(&buf)
+	for _, want := range []string{
+		"node_exporter, version 0.8.0 (branch: master, revision: abc1234)\n",
+		"  build date:  20161014-09:00:00\n",
+		"  go version:  " + runtime.Version() + "\n",
+		"loadavg",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("want %q in %q", want, buf.String())
+		}
+	}
+}